@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"github.com/cheracc/fortress-grpc"
+)
+
+// JoinCommand joins a chat channel and makes it the one that say sends to
+type JoinCommand struct {
+	JoinFunc func(string) (string, error)
+}
+
+func (c JoinCommand) Execute(player *fortress.Player, args string) (string, error) {
+	return c.JoinFunc(args)
+}
+
+func (c JoinCommand) GetName() string {
+	return "join"
+}
+
+// LeaveCommand leaves a chat channel, or the one say sends to if none is given
+type LeaveCommand struct {
+	LeaveFunc func(string) (string, error)
+}
+
+func (c LeaveCommand) Execute(player *fortress.Player, args string) (string, error) {
+	return c.LeaveFunc(args)
+}
+
+func (c LeaveCommand) GetName() string {
+	return "leave"
+}
+
+// ChannelsCommand lists the server's chat channels
+type ChannelsCommand struct {
+	ListFunc func() (string, error)
+}
+
+func (c ChannelsCommand) Execute(player *fortress.Player, args string) (string, error) {
+	return c.ListFunc()
+}
+
+func (c ChannelsCommand) GetName() string {
+	return "channels"
+}
+
+// HistoryCommand shows the messages that were sent to a chat channel before
+type HistoryCommand struct {
+	HistoryFunc func(string) (string, error)
+}
+
+func (c HistoryCommand) Execute(player *fortress.Player, args string) (string, error) {
+	return c.HistoryFunc(args)
+}
+
+func (c HistoryCommand) GetName() string {
+	return "history"
+}
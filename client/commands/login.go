@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cheracc/fortress-grpc"
+)
+
+// LoginCommand logs in with a local account on the server
+type LoginCommand struct {
+	LoginFunc func(string, string) error
+}
+
+func (c LoginCommand) Execute(player *fortress.Player, args string) (string, error) {
+	username, password, ok := strings.Cut(args, " ")
+	if !ok || username == "" || password == "" {
+		return "", fmt.Errorf("syntax: login <username> <password>")
+	}
+	if err := c.LoginFunc(username, password); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Logged in as %s", player.GetName()), nil
+}
+
+func (c LoginCommand) GetName() string {
+	return "login"
+}
+
+// RegisterCommand creates a new local account on the server and logs in with it
+type RegisterCommand struct {
+	RegisterFunc func(string, string) error
+}
+
+func (c RegisterCommand) Execute(player *fortress.Player, args string) (string, error) {
+	username, password, ok := strings.Cut(args, " ")
+	if !ok || username == "" || password == "" {
+		return "", fmt.Errorf("syntax: register <username> <password>")
+	}
+	if err := c.RegisterFunc(username, password); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Registered and logged in as %s", player.GetName()), nil
+}
+
+func (c RegisterCommand) GetName() string {
+	return "register"
+}
+
+// VerifyCommand finishes logging in with a code from the player's authenticator app, for accounts with two-factor authentication on
+type VerifyCommand struct {
+	VerifyFunc func(string) error
+}
+
+func (c VerifyCommand) Execute(player *fortress.Player, args string) (string, error) {
+	code := strings.TrimSpace(args)
+	if code == "" {
+		return "", fmt.Errorf("syntax: verify <code>")
+	}
+	if err := c.VerifyFunc(code); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Logged in as %s", player.GetName()), nil
+}
+
+func (c VerifyCommand) GetName() string {
+	return "verify"
+}
+
+// RecoverCommand gets back into an account with one of its recovery codes, by linking a new identity to it
+type RecoverCommand struct {
+	RecoverFunc func(string, string, string, string) error
+	// whether the client logs in with local accounts, which need a username and password for the new account
+	Local bool
+}
+
+func (c RecoverCommand) Execute(player *fortress.Player, args string) (string, error) {
+	fields := strings.Fields(args)
+	if c.Local && len(fields) != 4 {
+		return "", fmt.Errorf("syntax: recover <player name> <recovery code> <new username> <new password>")
+	}
+	if !c.Local && len(fields) != 2 {
+		return "", fmt.Errorf("syntax: recover <player name> <recovery code>")
+	}
+	fields = append(fields, "", "")
+	if err := c.RecoverFunc(fields[0], fields[1], fields[2], fields[3]); err != nil {
+		return "", err
+	}
+	if !c.Local {
+		return "", nil // the login link was shown, the player is logged in once they sign in
+	}
+	return fmt.Sprintf("Recovered and logged in as %s", player.GetName()), nil
+}
+
+func (c RecoverCommand) GetName() string {
+	return "recover"
+}
@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/cheracc/fortress-grpc"
+)
+
+// TermsCommand shows the server's terms of service
+type TermsCommand struct {
+	ShowTermsFunc func() (string, error)
+}
+
+func (c TermsCommand) Execute(player *fortress.Player, args string) (string, error) {
+	return c.ShowTermsFunc()
+}
+
+func (c TermsCommand) GetName() string {
+	return "terms"
+}
+
+// AcceptCommand accepts the terms of service that were last shown with the terms command
+type AcceptCommand struct {
+	AcceptFunc func() error
+}
+
+func (c AcceptCommand) Execute(player *fortress.Player, args string) (string, error) {
+	if err := c.AcceptFunc(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Thank you %s, you have accepted the terms of service.", player.GetName()), nil
+}
+
+func (c AcceptCommand) GetName() string {
+	return "accept"
+}
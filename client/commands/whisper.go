@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cheracc/fortress-grpc"
+)
+
+// WhisperCommand sends a message that only one player sees
+type WhisperCommand struct {
+	WhisperFunc func(string, string) error
+}
+
+func (c WhisperCommand) Execute(player *fortress.Player, args string) (string, error) {
+	recipient, message, ok := strings.Cut(strings.TrimSpace(args), " ")
+	if !ok || strings.TrimSpace(message) == "" {
+		return "", fmt.Errorf("wrong arguments. Syntax: whisper <player> <message>")
+	}
+	return "", c.WhisperFunc(recipient, strings.TrimSpace(message))
+}
+
+func (c WhisperCommand) GetName() string {
+	return "whisper"
+}
+
+// ReplyCommand whispers to the last player the player whispered with
+type ReplyCommand struct {
+	ReplyFunc func(string) error
+}
+
+func (c ReplyCommand) Execute(player *fortress.Player, args string) (string, error) {
+	if strings.TrimSpace(args) == "" {
+		return "", fmt.Errorf("wrong arguments. Syntax: reply <message>")
+	}
+	return "", c.ReplyFunc(strings.TrimSpace(args))
+}
+
+func (c ReplyCommand) GetName() string {
+	return "reply"
+}
@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"strings"
+	"sync"
 	"time"
 
 	fgrpc "github.com/cheracc/fortress-grpc/grpc"
@@ -10,11 +13,17 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-const chatMonitorInterval = time.Second / 2
+const chatTimeFormat = "Jan 2 15:04"
 
 type Chat struct {
 	*Remote
 	*ChatStream
+	channel string // the channel that say sends to, "" for the server's first default channel
+	// the player we last whispered with, either way, who reply sends to. It is set from the chat stream's goroutine
+	whisperLock    *sync.Mutex
+	whisperPartner string
+	// the oldest message of each channel that the history command has shown, where 'history more' carries on from
+	historyBefore map[string]int64
 }
 
 type ChatStream struct {
@@ -24,9 +33,13 @@ type ChatStream struct {
 }
 
 func (c *Chat) SendChatMessageToServer(playerName string, message string) {
-	chatMessage := &fgrpc.ChatMessage{SendingPlayerName: playerName, Message: message, SessionToken: c.GetSessionToken()}
+	chatMessage := &fgrpc.ChatMessage{SendingPlayerName: playerName, Message: message, ChannelName: c.channel}
 
 	_, err := c.SendMessage(context.Background(), chatMessage)
+	if code := status.Code(err); code == codes.PermissionDenied || code == codes.FailedPrecondition || code == codes.InvalidArgument { // muted, the terms need accepting, or a chat filter rejected it
+		c.ToConsole(status.Convert(err).Message())
+		return
+	}
 	if err != nil {
 		c.Error(err.Error())
 	}
@@ -41,8 +54,156 @@ func (c *Chat) PostMessageToConsole(message *fgrpc.ChatMessage) {
 			return
 		}
 
-		c.ToConsolef("[CHAT] %s: %s", sender, msg)
+		if message.GetRecipientId() != "" {
+			c.postWhisperToConsole(message)
+			return
+		}
+		channel := message.GetChannelName()
+		if channel == "" {
+			channel = "CHAT"
+		}
+		if message.GetHistory() {
+			c.ToConsolef("[%s %s] %s: %s", channel, time.Unix(message.GetSentAt(), 0).Format(chatTimeFormat), sender, msg)
+			return
+		}
+		c.ToConsolef("[%s] %s: %s", channel, sender, msg)
+	}
+}
+
+// ShowChatHistory shows the newest messages of a channel (the one say sends to if none is given), or with 'more' the ones before
+// those it showed last
+func (c *Chat) ShowChatHistory(args string) (string, error) {
+	fields := strings.Fields(strings.ToLower(args))
+	more := len(fields) > 0 && fields[len(fields)-1] == "more"
+	if more {
+		fields = fields[:len(fields)-1]
+	}
+	if len(fields) > 1 {
+		return "", fmt.Errorf("wrong arguments. Syntax: history [channel] [more]")
+	}
+	channel := c.channel
+	if len(fields) == 1 {
+		channel = fields[0]
+	}
+
+	request := &fgrpc.HistoryRequest{ChannelName: channel}
+	if more {
+		request.BeforeId = c.historyBefore[channel]
+	}
+	history, err := c.ChatClient.History(context.Background(), request)
+	if err != nil {
+		return "", fmt.Errorf("could not get the chat history: %s", status.Convert(err).Message())
+	}
+	if len(history.Messages) == 0 {
+		return "There are no more messages.", nil
+	}
+	c.historyBefore[channel] = history.Messages[0].GetId()
+
+	for _, message := range history.Messages {
+		c.PostMessageToConsole(message)
+	}
+	if history.More {
+		return "Use 'history " + strings.TrimSpace(channel+" more") + "' for older messages.", nil
+	}
+	return "", nil
+}
+
+// postWhisperToConsole shows a whisper to or from the player, and remembers the other player for reply
+func (c *Chat) postWhisperToConsole(message *fgrpc.ChatMessage) {
+	c.whisperLock.Lock()
+	defer c.whisperLock.Unlock()
+	if message.GetSendingPlayerId() == c.GetPlayerId() { // our own whisper, echoed back by the server
+		c.whisperPartner = message.GetRecipientId()
+		c.ToConsolef("[WHISPER] you -> %s: %s", message.GetRecipientName(), message.GetMessage())
+		return
+	}
+	c.whisperPartner = message.GetSendingPlayerId()
+	c.ToConsolef("[WHISPER] %s -> you: %s", message.GetSendingPlayerName(), message.GetMessage())
+}
+
+// SendWhisper sends a message to just the player with the given name or id
+func (c *Chat) SendWhisper(recipient string, message string) error {
+	_, err := c.ChatClient.Whisper(context.Background(), &fgrpc.WhisperRequest{Recipient: recipient, Message: message})
+	if err != nil {
+		return fmt.Errorf("could not whisper to %s: %s", recipient, status.Convert(err).Message())
 	}
+	return nil
+}
+
+// ReplyToWhisper whispers to the last player the player whispered with
+func (c *Chat) ReplyToWhisper(message string) error {
+	c.whisperLock.Lock()
+	partner := c.whisperPartner
+	c.whisperLock.Unlock()
+	if partner == "" {
+		return fmt.Errorf("there is nobody to reply to, use 'whisper <player> <message>' first")
+	}
+	_, err := c.ChatClient.Whisper(context.Background(), &fgrpc.WhisperRequest{Recipient: partner, Message: message})
+	if err != nil {
+		return fmt.Errorf("could not reply: %s", status.Convert(err).Message())
+	}
+	return nil
+}
+
+// JoinChatChannel joins the channel (making it if it does not exist) and makes it the one that say sends to
+func (c *Chat) JoinChatChannel(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", fmt.Errorf("which channel? Syntax: join <channel>")
+	}
+	_, err := c.ChatClient.Join(context.Background(), &fgrpc.ChatRequest{ChannelName: name})
+	if status.Code(err) == codes.AlreadyExists { // already in it, just switch to it
+		c.channel = name
+		return fmt.Sprintf("You are now talking in %s.", name), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not join %s: %s", name, status.Convert(err).Message())
+	}
+	c.channel = name
+	return fmt.Sprintf("Joined %s, you are now talking in it.", name), nil
+}
+
+// LeaveChatChannel leaves the channel. If say was sending to it, it goes back to the server's default channel
+func (c *Chat) LeaveChatChannel(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = c.channel
+	}
+	if name == "" {
+		return "", fmt.Errorf("which channel? Syntax: leave <channel>")
+	}
+	if _, err := c.ChatClient.Leave(context.Background(), &fgrpc.ChatRequest{ChannelName: name}); err != nil {
+		return "", fmt.Errorf("could not leave %s: %s", name, status.Convert(err).Message())
+	}
+	if c.channel == name {
+		c.channel = ""
+	}
+	return fmt.Sprintf("Left %s.", name), nil
+}
+
+// ListChatChannels describes the server's chat channels and which of them the player is in
+func (c *Chat) ListChatChannels() (string, error) {
+	list, err := c.ChatClient.ListChannels(context.Background(), &fgrpc.Empty{})
+	if err != nil {
+		return "", fmt.Errorf("could not list the chat channels: %s", status.Convert(err).Message())
+	}
+	if len(list.Channels) == 0 {
+		return "There are no chat channels.", nil
+	}
+
+	var output strings.Builder
+	output.WriteString("Chat channels:\n\r")
+	for _, channel := range list.Channels {
+		output.WriteString(fmt.Sprintf("    %-24s %d members", channel.Name, channel.Members))
+		if channel.Joined {
+			output.WriteString(", joined")
+		}
+		if channel.Name == c.channel {
+			output.WriteString(", talking")
+		}
+		output.WriteString("\n\r")
+	}
+	return strings.TrimSuffix(output.String(), "\n\r"), nil
 }
 
 func (s *Chat) StartChannelMonitor() func() {
@@ -67,10 +228,13 @@ func (s *Chat) StartChannelMonitor() func() {
 						cancel()
 						s.Fatal("Server is offline, closing...")
 					}
+					if status.Code(err) == codes.ResourceExhausted { // we fell too far behind and the server ended the stream
+						s.ToConsole(status.Convert(err).Message())
+						return
+					}
 					s.Error(err.Error())
 				}
 				s.PostMessageToConsole(message)
-				time.Sleep(chatMonitorInterval)
 			}
 		}
 	}(ctx)
@@ -78,7 +242,7 @@ func (s *Chat) StartChannelMonitor() func() {
 	return cancel
 }
 func NewChatHandler(remote *Remote) *Chat {
-	return &Chat{remote, nil}
+	return &Chat{Remote: remote, whisperLock: &sync.Mutex{}, historyBefore: make(map[string]int64)}
 }
 
 func (c *Chat) JoinChat() {
@@ -87,6 +251,7 @@ func (c *Chat) JoinChat() {
 		return
 	}
 	c.ChatStream = c.GetChatChannel()
+	c.channel = "" // a new stream starts out in the default channels
 	c.monitorCancelFunc = c.StartChannelMonitor()
 	c.Log("Joined chat.")
 }
@@ -2,17 +2,27 @@ package handlers
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"os/exec"
+	"runtime"
+	"strings"
 	"time"
 
 	"github.com/cheracc/fortress-grpc"
 	fgrpc "github.com/cheracc/fortress-grpc/grpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 const server_addr string = "localhost:50051"
 
+// LocalProvider is the LoginProvider for accounts that log in with a username and password instead of a login link
+const LocalProvider string = "local"
+
 // a Remote handles communication with the remote server
 type Remote struct {
 	// the gRPC client for authorizations
@@ -28,25 +38,65 @@ type Remote struct {
 	// the player that's currently loaded
 	*fortress.Player
 	*Chat
+	// the identity provider to log in with, the server chooses if this is empty
+	LoginProvider string
+	// log in by entering a code in any browser instead of opening a login link on this machine
+	DeviceFlow bool
+	// an invite code to join a whitelist-only server with, the server ignores it once the player is on the whitelist
+	InviteCode string
+	// the api key of a bot account, bots log in with this instead of an identity provider
+	ApiKey string
+	// how long to wait between Authorize() calls while a device login is pending, set by the server
+	pollInterval time.Duration
+	// the refresh token of the current session, used to get new session tokens
+	refreshToken string
+	// set while the server waits for a code from the player's authenticator app to finish logging in
+	secondFactorChallenge string
+	// a description of this device that is shown in the player's list of sessions
+	device string
+	// the version of the terms of service that was last shown to the player, the only version they can accept
+	termsVersion int32
 }
 
 // NewRemote constructs a new Remote with the given Logger
 func NewRemote(logger *fortress.Logger) *Remote {
-	conn, err := grpc.NewClient(server_addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	remote := &Remote{Logger: logger, Player: fortress.NewPlayer(), device: deviceName()}
+
+	conn, err := grpc.NewClient(server_addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(remote.unaryAuthInterceptor),
+		grpc.WithStreamInterceptor(remote.streamAuthInterceptor))
 	if err != nil {
 		logger.Fatalf("failed to connect to fortressd at %v", server_addr)
 	}
 	//defer conn.Close()
-	auth := fgrpc.NewAuthClient(conn)
-	cmd := fgrpc.NewCommandClient(conn)
-	pc := fgrpc.NewPlayerClient(conn)
-	chat := fgrpc.NewChatClient(conn)
+	remote.AuthClient = fgrpc.NewAuthClient(conn)
+	remote.CommandClient = fgrpc.NewCommandClient(conn)
+	remote.PlayerClient = fgrpc.NewPlayerClient(conn)
+	remote.ChatClient = fgrpc.NewChatClient(conn)
 
-	remote := &Remote{auth, cmd, pc, chat, logger, fortress.NewPlayer(), nil}
 	remote.Chat = NewChatHandler(remote)
 	return remote
 }
 
+// withSessionToken adds the session token to the outgoing metadata of ctx, if we have one
+func (r *Remote) withSessionToken(ctx context.Context) context.Context {
+	if !r.HasSessionToken() {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+r.GetSessionToken())
+}
+
+// unaryAuthInterceptor sends the session token with every call
+func (r *Remote) unaryAuthInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(r.withSessionToken(ctx), method, req, reply, cc, opts...)
+}
+
+// streamAuthInterceptor sends the session token when a stream is opened
+func (r *Remote) streamAuthInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(r.withSessionToken(ctx), desc, cc, method, opts...)
+}
+
 // HasSessionToken returns whether there is an actual session token saved (this does not verify it)
 func (r *Remote) HasSessionToken() bool {
 	return len(r.GetSessionToken()) > 40
@@ -55,7 +105,7 @@ func (r *Remote) HasSessionToken() bool {
 func (r *Remote) GetChatChannel() *ChatStream {
 	ctx, cancelFunc := context.WithCancel(context.Background())
 
-	stream, err := r.ChatClient.JoinChannel(ctx, &fgrpc.ChatRequest{SessionToken: r.GetSessionToken()})
+	stream, err := r.ChatClient.JoinChannel(ctx, &fgrpc.ChatRequest{})
 	if err != nil {
 		r.Error(err.Error())
 		defer cancelFunc()
@@ -65,7 +115,7 @@ func (r *Remote) GetChatChannel() *ChatStream {
 }
 
 func (r *Remote) GetPlayerData() {
-	payload := &fgrpc.PlayerInfo{Id: r.GetPlayerId(), SessionToken: r.GetSessionToken()}
+	payload := &fgrpc.PlayerInfo{Id: r.GetPlayerId()}
 	response, err := r.PlayerClient.GetPlayerData(context.Background(), payload)
 	if err != nil {
 		r.Error(err.Error())
@@ -78,9 +128,12 @@ func (r *Remote) GetPlayerData() {
 
 // SendCommand sends user commands to the remote server and returns the response
 func (r *Remote) SendCommand(cmd string, args string) string {
-	playerInfo := &fgrpc.PlayerInfo{SessionToken: r.GetSessionToken(), Id: r.GetPlayerId()}
-	r.Logf("Sending command to server: %s %s", cmd, args)
+	playerInfo := &fgrpc.PlayerInfo{Id: r.GetPlayerId()}
+	r.Logf("Sending command to server: %s", cmd) // arguments are not logged since they may contain passwords
 	payload, err := r.CommandClient.Command(context.Background(), &fgrpc.CommandInfo{PlayerInfo: playerInfo, CommandName: cmd, CommandArguments: args})
+	if status.Code(err) == codes.FailedPrecondition { // such as terms of service that have not been accepted
+		return status.Convert(err).Message()
+	}
 	if err != nil {
 		r.Errorf("error calling SendCommand(): %v", err)
 	}
@@ -90,17 +143,61 @@ func (r *Remote) SendCommand(cmd string, args string) string {
 // Authorize() handles all client authorization
 // On the first call to Authorize(), the server should respond with an oauth token (state) and a URL
 //
-//	The user needs to click on the URL to sign in to Google, once they do, the server completes the authorization of the account and waits for another Authorize() request
-//	Authorize() will continue to be called every few seconds until the user completes the Google sign-in
-//	Once logged in through Google, Authorize() will return a valid session token to be used henceforth
-//	Authorize() is then called periodically to refresh the session token
+//	The user needs to click on the URL to sign in with the identity provider (Google unless LoginProvider is set), once they do, the server completes the authorization of the account and waits for another Authorize() request
+//	Authorize() will continue to be called every few seconds until the user completes the sign-in
+//	Once logged in through the provider, Authorize() will return a valid session token to be used henceforth
+//	Along with the session token the server sends a refresh token, after that Authorize() uses the refresh token to get new session tokens
 func (r *Remote) Authorize() {
-	authInfo, err := r.AuthClient.Authorize(context.Background(), &fgrpc.PlayerInfo{Id: r.GetPlayerId(), SessionToken: r.GetSessionToken()})
+	if r.refreshToken != "" {
+		r.refresh()
+		return
+	}
+	if r.secondFactorChallenge != "" {
+		return // waiting for the player to use the verify command
+	}
+	if r.ApiKey != "" {
+		r.authorizeApiKey()
+		return
+	}
+	if r.LoginProvider == LocalProvider && !r.HasSessionToken() {
+		return // local accounts log in with the login or register commands instead
+	}
+
+	authInfo, err := r.AuthClient.Authorize(context.Background(), &fgrpc.PlayerInfo{Id: r.GetPlayerId(), SessionToken: r.GetSessionToken(), Provider: r.LoginProvider, Device: r.device, DeviceFlow: r.DeviceFlow, InviteCode: r.InviteCode})
+	if status.Code(err) == codes.PermissionDenied { // signed in with the provider, but the server refused the login (such as a ban)
+		r.ToConsolef("Login refused: %s", status.Convert(err).Message())
+		r.SetSessionToken("")
+		return
+	}
 	if err != nil {
 		r.Errorf("error calling Authorize(): %v", err)
 		return
 	}
 
+	if authInfo.Interval > 0 {
+		r.pollInterval = time.Duration(authInfo.Interval) * time.Second
+	}
+	switch authInfo.Error {
+	case "authorization_pending", "slow_down": // the device login has not been completed yet, keep polling
+		return
+	case "expired_token":
+		r.ToConsole("The login code has expired, getting a new one...")
+		r.SetSessionToken("")
+		return
+	}
+
+	if authInfo.SecondFactorChallenge != "" { // signed in with the provider, but the player has two-factor authentication on
+		r.askForSecondFactor(authInfo)
+		return
+	}
+
+	if authInfo.UserCode != "" { // server started a device login, the user enters the code in a browser anywhere
+		r.SetPlayerId(authInfo.PlayerID)
+		r.SetSessionToken(authInfo.SessionToken) // this is the device code that we poll with until the user has logged in
+		r.ToConsolef("To log in, visit %s and enter the code %s\n\r", authInfo.VerificationURL, authInfo.UserCode)
+		return
+	}
+
 	if authInfo.LoginURL != "" { // server sent a login url, so we will need to log in first
 		r.SetPlayerId(authInfo.PlayerID)
 		r.SetSessionToken(authInfo.SessionToken) // this should be an oauth token that we will return to confirm we are the same as who logged in with that link
@@ -117,8 +214,11 @@ func (r *Remote) Authorize() {
 	if len(authInfo.SessionToken) > 40 {
 		if len(r.GetSessionToken()) < 40 { // received a session token but had an oauth token. this means we've successfully logged in
 			r.SetSessionToken(authInfo.SessionToken)
+			r.refreshToken = authInfo.RefreshToken
 			r.GetPlayerData()
 			r.Logf("Logged in as %s(%s)", r.GetName(), r.GetPlayerId())
+			r.showRecoveryCodes(authInfo.RecoveryCodes)
+			r.checkTerms()
 		}
 	}
 
@@ -128,11 +228,210 @@ func (r *Remote) Authorize() {
 	}
 }
 
-// Logout clears player data and calls Authorize()
+// PollInterval returns how long to wait before calling Authorize() again while logging in
+func (r *Remote) PollInterval() time.Duration {
+	if r.pollInterval > 0 {
+		return r.pollInterval
+	}
+	return 5 * time.Second
+}
+
+// refresh exchanges the refresh token for a new session token and refresh token. If the session has ended
+// the tokens are cleared so the next call to Authorize() starts a new login
+func (r *Remote) refresh() {
+	authInfo, err := r.AuthClient.Refresh(context.Background(), &fgrpc.RefreshRequest{RefreshToken: r.refreshToken})
+	if err != nil {
+		if status.Code(err) == codes.Unavailable {
+			r.Errorf("error calling Refresh(): %v", err)
+			return
+		}
+		r.ToConsolef("Your session has ended (%s), please log in again", status.Convert(err).Message())
+		r.refreshToken = ""
+		r.SetSessionToken("")
+		return
+	}
+
+	r.refreshToken = authInfo.RefreshToken
+	r.SetSessionToken(authInfo.SessionToken)
+}
+
+// authorizeApiKey logs a bot in with its api key
+func (r *Remote) authorizeApiKey() {
+	authInfo, err := r.AuthClient.AuthorizeApiKey(context.Background(), &fgrpc.ApiKeyRequest{ApiKey: r.ApiKey, Device: r.device})
+	if err != nil {
+		r.Errorf("could not log in with the api key: %s", status.Convert(err).Message())
+		return
+	}
+	r.setLocalSession(authInfo)
+}
+
+// Login logs in with a local account on the server
+func (r *Remote) Login(username string, password string) error {
+	authInfo, err := r.AuthClient.Login(context.Background(), &fgrpc.Credentials{Username: username, Password: password, Device: r.device, InviteCode: r.InviteCode})
+	if err != nil {
+		return fmt.Errorf("could not log in: %s", status.Convert(err).Message())
+	}
+	if authInfo.SecondFactorChallenge != "" {
+		r.askForSecondFactor(authInfo)
+		return fmt.Errorf("not logged in yet")
+	}
+	r.setLocalSession(authInfo)
+	return nil
+}
+
+// Register creates a new local account on the server and logs in with it
+func (r *Remote) Register(username string, password string) error {
+	authInfo, err := r.AuthClient.Register(context.Background(), &fgrpc.Credentials{Username: username, Password: password, Device: r.device, InviteCode: r.InviteCode})
+	if err != nil {
+		return fmt.Errorf("could not register: %s", status.Convert(err).Message())
+	}
+	r.setLocalSession(authInfo)
+	return nil
+}
+
+// askForSecondFactor holds on to the challenge the server sent until the player enters a code with the verify command
+func (r *Remote) askForSecondFactor(authInfo *fgrpc.AuthInfo) {
+	r.SetPlayerId(authInfo.PlayerID)
+	r.SetSessionToken("")
+	r.secondFactorChallenge = authInfo.SecondFactorChallenge
+	r.ToConsole("Two-factor authentication is on for this account, use 'verify <code>' with the code from your authenticator app (or a backup code)")
+}
+
+// VerifySecondFactor finishes a login that is waiting for a code from the player's authenticator app
+func (r *Remote) VerifySecondFactor(code string) error {
+	if r.secondFactorChallenge == "" {
+		return fmt.Errorf("there is no login waiting for a code")
+	}
+	authInfo, err := r.AuthClient.VerifySecondFactor(context.Background(), &fgrpc.SecondFactorRequest{Challenge: r.secondFactorChallenge, Code: code})
+	if status.Code(err) == codes.InvalidArgument { // wrong code, the player can try again
+		return fmt.Errorf("%s", status.Convert(err).Message())
+	}
+	r.secondFactorChallenge = ""
+	if err != nil {
+		return fmt.Errorf("could not log in: %s", status.Convert(err).Message())
+	}
+	r.setLocalSession(authInfo)
+	return nil
+}
+
+// setLocalSession stores the session received from a local, api key or second factor login and loads the player's data
+func (r *Remote) setLocalSession(authInfo *fgrpc.AuthInfo) {
+	r.SetPlayerId(authInfo.PlayerID)
+	r.SetSessionToken(authInfo.SessionToken)
+	r.refreshToken = authInfo.RefreshToken
+	r.GetPlayerData()
+	r.Logf("Logged in as %s(%s)", r.GetName(), r.GetPlayerId())
+	r.showRecoveryCodes(authInfo.RecoveryCodes)
+	r.checkTerms()
+}
+
+// showRecoveryCodes shows the recovery codes the server sends on a player's first login, it never sends them again
+func (r *Remote) showRecoveryCodes(codes []string) {
+	if len(codes) == 0 {
+		return
+	}
+	r.ToConsolef("Keep these recovery codes somewhere safe, each one gets you back into your account once if you can't log in with any of your identities:\n\r    %s\n\r"+
+		"They will not be shown again, use 'recovery regenerate' if you lose them.", strings.Join(codes, "\n\r    "))
+}
+
+// Recover gets back into the account of the named player with one of its recovery codes, by signing in with a new identity that is
+// linked to the account. With the local provider the username and password are used to make a new local account instead
+func (r *Remote) Recover(playerName string, code string, username string, password string) error {
+	if r.refreshToken != "" {
+		return fmt.Errorf("you are already logged in, log out first")
+	}
+	authInfo, err := r.AuthClient.Recover(context.Background(), &fgrpc.RecoveryRequest{PlayerName: playerName, Code: code, Provider: r.LoginProvider,
+		Device: r.device, DeviceFlow: r.DeviceFlow, Username: username, Password: password})
+	if err != nil {
+		return fmt.Errorf("could not recover the account: %s", status.Convert(err).Message())
+	}
+
+	if r.LoginProvider == LocalProvider {
+		if authInfo.SecondFactorChallenge != "" {
+			r.askForSecondFactor(authInfo)
+			return fmt.Errorf("not logged in yet")
+		}
+		r.setLocalSession(authInfo)
+		return nil
+	}
+
+	// from here it is like any other login, Authorize() polls with the oauth state (or device code) until the player has signed in
+	r.SetPlayerId(authInfo.PlayerID)
+	r.SetSessionToken(authInfo.SessionToken)
+	if authInfo.UserCode != "" {
+		r.ToConsolef("To link a new identity to %s, visit %s and enter the code %s\n\r", playerName, authInfo.VerificationURL, authInfo.UserCode)
+		return nil
+	}
+	r.ToConsolef("Use the following link to sign in with the identity to link to %s: %s\n\r", playerName, authInfo.LoginURL)
+	exec.Command("rundll32", "url.dll,FileProtocolHandler", authInfo.LoginURL).Start()
+	return nil
+}
+
+// checkTerms shows the terms of service if the player still has to accept them
+func (r *Remote) checkTerms() {
+	terms, err := r.PlayerClient.GetTerms(context.Background(), &fgrpc.Empty{})
+	if err != nil {
+		r.Errorf("error calling GetTerms(): %v", err)
+		return
+	}
+	if terms.Version > terms.AcceptedVersion {
+		r.ToConsole(r.describeTerms(terms))
+	}
+}
+
+// ShowTerms returns the server's current terms of service, after which the player can accept them
+func (r *Remote) ShowTerms() (string, error) {
+	terms, err := r.PlayerClient.GetTerms(context.Background(), &fgrpc.Empty{})
+	if err != nil {
+		return "", fmt.Errorf("could not get the terms of service: %s", status.Convert(err).Message())
+	}
+	if terms.Version == 0 {
+		return "This server has no terms of service.", nil
+	}
+	return r.describeTerms(terms), nil
+}
+
+// AcceptTerms accepts the version of the terms of service that was last shown to the player
+func (r *Remote) AcceptTerms() error {
+	if r.termsVersion == 0 {
+		return fmt.Errorf("use 'terms' to read the terms of service first")
+	}
+	terms, err := r.PlayerClient.AcceptTerms(context.Background(), &fgrpc.AcceptTermsRequest{Version: r.termsVersion})
+	if err != nil {
+		return fmt.Errorf("could not accept the terms of service: %s", status.Convert(err).Message())
+	}
+	r.termsVersion = terms.Version
+	return nil
+}
+
+// describeTerms remembers the version of the terms and returns their text with what the player has to do
+func (r *Remote) describeTerms(terms *fgrpc.TermsMessage) string {
+	r.termsVersion = terms.Version
+	if terms.AcceptedVersion >= terms.Version {
+		return fmt.Sprintf("Terms of service (version %d):\n\r%s\n\rYou accepted them on %s.", terms.Version, terms.Text,
+			time.Unix(terms.AcceptedAt, 0).Format(time.DateTime))
+	}
+	return fmt.Sprintf("Terms of service (version %d):\n\r%s\n\rUse 'accept' to accept them, you can not play until you do.", terms.Version, terms.Text)
+}
+
+// Logout ends the session on the server, clears player data and calls Authorize()
 func (r *Remote) Logout() {
+	if _, err := r.AuthClient.Logout(context.Background(), &fgrpc.PlayerInfo{Id: r.GetPlayerId()}); err != nil {
+		r.Errorf("error calling Logout(): %v", err)
+	}
+	r.refreshToken = ""
 	r.SetPlayerId("")
 	r.SetName("")
 	r.SetSessionToken("")
 	r.CloseChatConnections()
 	r.Authorize()
 }
+
+// deviceName describes this device for the server, such as "mypc (linux)"
+func deviceName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s (%s)", host, runtime.GOOS)
+}
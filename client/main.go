@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"os"
 	"runtime"
 	"strings"
@@ -13,13 +14,39 @@ import (
 )
 
 func main() {
+	provider := flag.String("provider", "", "the identity provider to log in with (the server's default if empty), or guest to look around without signing in on servers that allow it")
+	deviceFlow := flag.Bool("device", false, "log in by entering a code in any browser, for machines that can't open one")
+	inviteCode := flag.String("invite", "", "an invite code, needed to join a whitelist-only server for the first time")
+	flag.Parse()
+
 	logger := fortress.NewLogger()
 	remote := handlers.NewRemote(logger)
+	remote.LoginProvider = *provider
+	remote.DeviceFlow = *deviceFlow
+	remote.InviteCode = *inviteCode
+	remote.ApiKey = os.Getenv("FORTRESS_API_KEY") // read from the environment so the key doesn't show up in the process list
 	cmd := handlers.NewCommandHandler(logger)
 
 	cmd.RegisterCommand(commands.LogoutCommand{LogoutFunc: remote.Logout})
 	cmd.RegisterCommand(commands.SayCommand{SayFunc: remote.SendChatMessageToServer})
+	cmd.RegisterCommand(commands.JoinCommand{JoinFunc: remote.JoinChatChannel})
+	cmd.RegisterCommand(commands.LeaveCommand{LeaveFunc: remote.LeaveChatChannel})
+	cmd.RegisterCommand(commands.ChannelsCommand{ListFunc: remote.ListChatChannels})
+	cmd.RegisterCommand(commands.HistoryCommand{HistoryFunc: remote.ShowChatHistory})
+	cmd.RegisterCommand(commands.WhisperCommand{WhisperFunc: remote.SendWhisper})
+	cmd.RegisterCommand(commands.ReplyCommand{ReplyFunc: remote.ReplyToWhisper})
 	cmd.RegisterCommand(commands.QuitCommand{})
+	cmd.RegisterCommand(commands.LoginCommand{LoginFunc: remote.Login})
+	cmd.RegisterCommand(commands.RegisterCommand{RegisterFunc: remote.Register})
+	cmd.RegisterCommand(commands.VerifyCommand{VerifyFunc: remote.VerifySecondFactor})
+	cmd.RegisterCommand(commands.RecoverCommand{RecoverFunc: remote.Recover, Local: remote.LoginProvider == handlers.LocalProvider})
+	cmd.RegisterCommand(commands.TermsCommand{ShowTermsFunc: remote.ShowTerms})
+	cmd.RegisterCommand(commands.AcceptCommand{AcceptFunc: remote.AcceptTerms})
+
+	if remote.LoginProvider == handlers.LocalProvider {
+		logger.ToConsole("Use 'login <username> <password>' or 'register <username> <password>' to log in")
+	}
+	logger.ToConsole("If you can't log in to your account anymore, use 'recover' with one of its recovery codes")
 
 	go refreshTokenEveryMinute(remote)
 	go joinChatOnceLoggedIn(remote)
@@ -34,7 +61,12 @@ func main() {
 		}
 
 		if c := cmd.GetCommandOrNil(cmdName); c != nil {
-			c.Execute(remote.Player, args)
+			response, err := c.Execute(remote.Player, args)
+			if err != nil {
+				logger.ToConsole(err.Error())
+			} else if response != "" {
+				logger.ToConsole(response)
+			}
 			continue
 		}
 
@@ -62,7 +94,7 @@ func refreshTokenEveryMinute(remote *handlers.Remote) {
 	for {
 		remote.Authorize()
 		if !remote.HasSessionToken() {
-			time.Sleep(5 * time.Second)
+			time.Sleep(remote.PollInterval())
 		} else {
 			time.Sleep(1 * time.Minute)
 		}
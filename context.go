@@ -6,15 +6,37 @@ type contextKey struct {
 	string
 }
 
-func NewContextWithSessionToken(token string) context.Context {
-	return context.WithValue(context.Background(), contextKey{"session_token"}, token)
+var (
+	sessionTokenKey = contextKey{"session_token"}
+	playerKey       = contextKey{"player"}
+)
+
+// NewContextWithSessionToken returns a copy of ctx that carries the session token
+func NewContextWithSessionToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, sessionTokenKey, token)
 }
 
+// SessionTokenFromContext returns the session token carried by the context, or "" if there is none
 func SessionTokenFromContext(c context.Context) string {
-	token := c.Value(&contextKey{"session_token"})
+	token, ok := c.Value(sessionTokenKey).(string)
 
-	if token == nil {
+	if !ok {
 		return ""
 	}
-	return token.(string)
+	return token
+}
+
+// NewContextWithPlayer returns a copy of ctx that carries the player
+func NewContextWithPlayer(ctx context.Context, player *Player) context.Context {
+	return context.WithValue(ctx, playerKey, player)
+}
+
+// PlayerFromContext returns the player carried by the context, or nil if there is none
+func PlayerFromContext(c context.Context) *Player {
+	player, ok := c.Value(playerKey).(*Player)
+
+	if !ok {
+		return nil
+	}
+	return player
 }
@@ -13,6 +13,7 @@ require cloud.google.com/go/compute/metadata v0.5.2 // indirect
 
 require (
 	github.com/mattn/go-sqlite3 v1.14.24
+	golang.org/x/crypto v0.28.0
 	golang.org/x/net v0.30.0 // indirect
 	golang.org/x/oauth2 v0.24.0
 	golang.org/x/sys v0.26.0 // indirect
@@ -19,7 +19,14 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Auth_Authorize_FullMethodName = "/grpc.Auth/Authorize"
+	Auth_Authorize_FullMethodName          = "/grpc.Auth/Authorize"
+	Auth_Register_FullMethodName           = "/grpc.Auth/Register"
+	Auth_Login_FullMethodName              = "/grpc.Auth/Login"
+	Auth_Refresh_FullMethodName            = "/grpc.Auth/Refresh"
+	Auth_Logout_FullMethodName             = "/grpc.Auth/Logout"
+	Auth_AuthorizeApiKey_FullMethodName    = "/grpc.Auth/AuthorizeApiKey"
+	Auth_VerifySecondFactor_FullMethodName = "/grpc.Auth/VerifySecondFactor"
+	Auth_Recover_FullMethodName            = "/grpc.Auth/Recover"
 )
 
 // AuthClient is the client API for Auth service.
@@ -27,6 +34,13 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type AuthClient interface {
 	Authorize(ctx context.Context, in *PlayerInfo, opts ...grpc.CallOption) (*AuthInfo, error)
+	Register(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*AuthInfo, error)
+	Login(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*AuthInfo, error)
+	Refresh(ctx context.Context, in *RefreshRequest, opts ...grpc.CallOption) (*AuthInfo, error)
+	Logout(ctx context.Context, in *PlayerInfo, opts ...grpc.CallOption) (*Empty, error)
+	AuthorizeApiKey(ctx context.Context, in *ApiKeyRequest, opts ...grpc.CallOption) (*AuthInfo, error)
+	VerifySecondFactor(ctx context.Context, in *SecondFactorRequest, opts ...grpc.CallOption) (*AuthInfo, error)
+	Recover(ctx context.Context, in *RecoveryRequest, opts ...grpc.CallOption) (*AuthInfo, error)
 }
 
 type authClient struct {
@@ -47,11 +61,88 @@ func (c *authClient) Authorize(ctx context.Context, in *PlayerInfo, opts ...grpc
 	return out, nil
 }
 
+func (c *authClient) Register(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*AuthInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AuthInfo)
+	err := c.cc.Invoke(ctx, Auth_Register_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) Login(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*AuthInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AuthInfo)
+	err := c.cc.Invoke(ctx, Auth_Login_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) Refresh(ctx context.Context, in *RefreshRequest, opts ...grpc.CallOption) (*AuthInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AuthInfo)
+	err := c.cc.Invoke(ctx, Auth_Refresh_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) Logout(ctx context.Context, in *PlayerInfo, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Auth_Logout_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) AuthorizeApiKey(ctx context.Context, in *ApiKeyRequest, opts ...grpc.CallOption) (*AuthInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AuthInfo)
+	err := c.cc.Invoke(ctx, Auth_AuthorizeApiKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) VerifySecondFactor(ctx context.Context, in *SecondFactorRequest, opts ...grpc.CallOption) (*AuthInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AuthInfo)
+	err := c.cc.Invoke(ctx, Auth_VerifySecondFactor_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authClient) Recover(ctx context.Context, in *RecoveryRequest, opts ...grpc.CallOption) (*AuthInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AuthInfo)
+	err := c.cc.Invoke(ctx, Auth_Recover_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AuthServer is the server API for Auth service.
 // All implementations must embed UnimplementedAuthServer
 // for forward compatibility.
 type AuthServer interface {
 	Authorize(context.Context, *PlayerInfo) (*AuthInfo, error)
+	Register(context.Context, *Credentials) (*AuthInfo, error)
+	Login(context.Context, *Credentials) (*AuthInfo, error)
+	Refresh(context.Context, *RefreshRequest) (*AuthInfo, error)
+	Logout(context.Context, *PlayerInfo) (*Empty, error)
+	AuthorizeApiKey(context.Context, *ApiKeyRequest) (*AuthInfo, error)
+	VerifySecondFactor(context.Context, *SecondFactorRequest) (*AuthInfo, error)
+	Recover(context.Context, *RecoveryRequest) (*AuthInfo, error)
 	mustEmbedUnimplementedAuthServer()
 }
 
@@ -65,6 +156,27 @@ type UnimplementedAuthServer struct{}
 func (UnimplementedAuthServer) Authorize(context.Context, *PlayerInfo) (*AuthInfo, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Authorize not implemented")
 }
+func (UnimplementedAuthServer) Register(context.Context, *Credentials) (*AuthInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (UnimplementedAuthServer) Login(context.Context, *Credentials) (*AuthInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
+func (UnimplementedAuthServer) Refresh(context.Context, *RefreshRequest) (*AuthInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Refresh not implemented")
+}
+func (UnimplementedAuthServer) Logout(context.Context, *PlayerInfo) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Logout not implemented")
+}
+func (UnimplementedAuthServer) AuthorizeApiKey(context.Context, *ApiKeyRequest) (*AuthInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AuthorizeApiKey not implemented")
+}
+func (UnimplementedAuthServer) VerifySecondFactor(context.Context, *SecondFactorRequest) (*AuthInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifySecondFactor not implemented")
+}
+func (UnimplementedAuthServer) Recover(context.Context, *RecoveryRequest) (*AuthInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Recover not implemented")
+}
 func (UnimplementedAuthServer) mustEmbedUnimplementedAuthServer() {}
 func (UnimplementedAuthServer) testEmbeddedByValue()              {}
 
@@ -104,6 +216,132 @@ func _Auth_Authorize_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Auth_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Credentials)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Auth_Register_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).Register(ctx, req.(*Credentials))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Credentials)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Auth_Login_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).Login(ctx, req.(*Credentials))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_Refresh_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).Refresh(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Auth_Refresh_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).Refresh(ctx, req.(*RefreshRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_Logout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlayerInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).Logout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Auth_Logout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).Logout(ctx, req.(*PlayerInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_AuthorizeApiKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApiKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).AuthorizeApiKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Auth_AuthorizeApiKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).AuthorizeApiKey(ctx, req.(*ApiKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_VerifySecondFactor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SecondFactorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).VerifySecondFactor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Auth_VerifySecondFactor_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).VerifySecondFactor(ctx, req.(*SecondFactorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Auth_Recover_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecoveryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServer).Recover(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Auth_Recover_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServer).Recover(ctx, req.(*RecoveryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Auth_ServiceDesc is the grpc.ServiceDesc for Auth service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -115,6 +353,34 @@ var Auth_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Authorize",
 			Handler:    _Auth_Authorize_Handler,
 		},
+		{
+			MethodName: "Register",
+			Handler:    _Auth_Register_Handler,
+		},
+		{
+			MethodName: "Login",
+			Handler:    _Auth_Login_Handler,
+		},
+		{
+			MethodName: "Refresh",
+			Handler:    _Auth_Refresh_Handler,
+		},
+		{
+			MethodName: "Logout",
+			Handler:    _Auth_Logout_Handler,
+		},
+		{
+			MethodName: "AuthorizeApiKey",
+			Handler:    _Auth_AuthorizeApiKey_Handler,
+		},
+		{
+			MethodName: "VerifySecondFactor",
+			Handler:    _Auth_VerifySecondFactor_Handler,
+		},
+		{
+			MethodName: "Recover",
+			Handler:    _Auth_Recover_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "fortress.proto",
@@ -224,6 +490,8 @@ var Command_ServiceDesc = grpc.ServiceDesc{
 
 const (
 	Player_GetPlayerData_FullMethodName = "/grpc.Player/GetPlayerData"
+	Player_GetTerms_FullMethodName      = "/grpc.Player/GetTerms"
+	Player_AcceptTerms_FullMethodName   = "/grpc.Player/AcceptTerms"
 )
 
 // PlayerClient is the client API for Player service.
@@ -231,6 +499,8 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type PlayerClient interface {
 	GetPlayerData(ctx context.Context, in *PlayerInfo, opts ...grpc.CallOption) (*PlayerMessage, error)
+	GetTerms(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*TermsMessage, error)
+	AcceptTerms(ctx context.Context, in *AcceptTermsRequest, opts ...grpc.CallOption) (*TermsMessage, error)
 }
 
 type playerClient struct {
@@ -251,11 +521,33 @@ func (c *playerClient) GetPlayerData(ctx context.Context, in *PlayerInfo, opts .
 	return out, nil
 }
 
+func (c *playerClient) GetTerms(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*TermsMessage, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TermsMessage)
+	err := c.cc.Invoke(ctx, Player_GetTerms_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerClient) AcceptTerms(ctx context.Context, in *AcceptTermsRequest, opts ...grpc.CallOption) (*TermsMessage, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TermsMessage)
+	err := c.cc.Invoke(ctx, Player_AcceptTerms_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PlayerServer is the server API for Player service.
 // All implementations must embed UnimplementedPlayerServer
 // for forward compatibility.
 type PlayerServer interface {
 	GetPlayerData(context.Context, *PlayerInfo) (*PlayerMessage, error)
+	GetTerms(context.Context, *Empty) (*TermsMessage, error)
+	AcceptTerms(context.Context, *AcceptTermsRequest) (*TermsMessage, error)
 	mustEmbedUnimplementedPlayerServer()
 }
 
@@ -269,6 +561,12 @@ type UnimplementedPlayerServer struct{}
 func (UnimplementedPlayerServer) GetPlayerData(context.Context, *PlayerInfo) (*PlayerMessage, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetPlayerData not implemented")
 }
+func (UnimplementedPlayerServer) GetTerms(context.Context, *Empty) (*TermsMessage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTerms not implemented")
+}
+func (UnimplementedPlayerServer) AcceptTerms(context.Context, *AcceptTermsRequest) (*TermsMessage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AcceptTerms not implemented")
+}
 func (UnimplementedPlayerServer) mustEmbedUnimplementedPlayerServer() {}
 func (UnimplementedPlayerServer) testEmbeddedByValue()                {}
 
@@ -308,6 +606,42 @@ func _Player_GetPlayerData_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Player_GetTerms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServer).GetTerms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Player_GetTerms_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServer).GetTerms(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Player_AcceptTerms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AcceptTermsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServer).AcceptTerms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Player_AcceptTerms_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServer).AcceptTerms(ctx, req.(*AcceptTermsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Player_ServiceDesc is the grpc.ServiceDesc for Player service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -319,22 +653,44 @@ var Player_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetPlayerData",
 			Handler:    _Player_GetPlayerData_Handler,
 		},
+		{
+			MethodName: "GetTerms",
+			Handler:    _Player_GetTerms_Handler,
+		},
+		{
+			MethodName: "AcceptTerms",
+			Handler:    _Player_AcceptTerms_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "fortress.proto",
 }
 
 const (
-	Chat_JoinChannel_FullMethodName = "/grpc.Chat/JoinChannel"
-	Chat_SendMessage_FullMethodName = "/grpc.Chat/SendMessage"
+	Chat_JoinChannel_FullMethodName  = "/grpc.Chat/JoinChannel"
+	Chat_SendMessage_FullMethodName  = "/grpc.Chat/SendMessage"
+	Chat_Join_FullMethodName         = "/grpc.Chat/Join"
+	Chat_Leave_FullMethodName        = "/grpc.Chat/Leave"
+	Chat_ListChannels_FullMethodName = "/grpc.Chat/ListChannels"
+	Chat_Whisper_FullMethodName      = "/grpc.Chat/Whisper"
+	Chat_History_FullMethodName      = "/grpc.Chat/History"
 )
 
 // ChatClient is the client API for Chat service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ChatClient interface {
+	// opens the session's chat stream, in the channel channelName or (if it is empty) the server's default channels
 	JoinChannel(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChatMessage], error)
 	SendMessage(ctx context.Context, in *ChatMessage, opts ...grpc.CallOption) (*Empty, error)
+	// change which channels the session's open chat stream is in. Joining a channel that does not exist creates it
+	Join(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (*ChannelList, error)
+	Leave(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (*ChannelList, error)
+	ListChannels(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ChannelList, error)
+	// sends a message to one player's chat streams, and echoes it back to the sender's
+	Whisper(ctx context.Context, in *WhisperRequest, opts ...grpc.CallOption) (*Empty, error)
+	// returns a page of the messages sent to a channel the session is in, older than beforeId (or the newest if it is 0)
+	History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*ChatHistory, error)
 }
 
 type chatClient struct {
@@ -374,12 +730,71 @@ func (c *chatClient) SendMessage(ctx context.Context, in *ChatMessage, opts ...g
 	return out, nil
 }
 
+func (c *chatClient) Join(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (*ChannelList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChannelList)
+	err := c.cc.Invoke(ctx, Chat_Join_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatClient) Leave(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (*ChannelList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChannelList)
+	err := c.cc.Invoke(ctx, Chat_Leave_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatClient) ListChannels(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ChannelList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChannelList)
+	err := c.cc.Invoke(ctx, Chat_ListChannels_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatClient) Whisper(ctx context.Context, in *WhisperRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Chat_Whisper_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatClient) History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*ChatHistory, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChatHistory)
+	err := c.cc.Invoke(ctx, Chat_History_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ChatServer is the server API for Chat service.
 // All implementations must embed UnimplementedChatServer
 // for forward compatibility.
 type ChatServer interface {
+	// opens the session's chat stream, in the channel channelName or (if it is empty) the server's default channels
 	JoinChannel(*ChatRequest, grpc.ServerStreamingServer[ChatMessage]) error
 	SendMessage(context.Context, *ChatMessage) (*Empty, error)
+	// change which channels the session's open chat stream is in. Joining a channel that does not exist creates it
+	Join(context.Context, *ChatRequest) (*ChannelList, error)
+	Leave(context.Context, *ChatRequest) (*ChannelList, error)
+	ListChannels(context.Context, *Empty) (*ChannelList, error)
+	// sends a message to one player's chat streams, and echoes it back to the sender's
+	Whisper(context.Context, *WhisperRequest) (*Empty, error)
+	// returns a page of the messages sent to a channel the session is in, older than beforeId (or the newest if it is 0)
+	History(context.Context, *HistoryRequest) (*ChatHistory, error)
 	mustEmbedUnimplementedChatServer()
 }
 
@@ -396,6 +811,21 @@ func (UnimplementedChatServer) JoinChannel(*ChatRequest, grpc.ServerStreamingSer
 func (UnimplementedChatServer) SendMessage(context.Context, *ChatMessage) (*Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SendMessage not implemented")
 }
+func (UnimplementedChatServer) Join(context.Context, *ChatRequest) (*ChannelList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Join not implemented")
+}
+func (UnimplementedChatServer) Leave(context.Context, *ChatRequest) (*ChannelList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Leave not implemented")
+}
+func (UnimplementedChatServer) ListChannels(context.Context, *Empty) (*ChannelList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListChannels not implemented")
+}
+func (UnimplementedChatServer) Whisper(context.Context, *WhisperRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Whisper not implemented")
+}
+func (UnimplementedChatServer) History(context.Context, *HistoryRequest) (*ChatHistory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method History not implemented")
+}
 func (UnimplementedChatServer) mustEmbedUnimplementedChatServer() {}
 func (UnimplementedChatServer) testEmbeddedByValue()              {}
 
@@ -446,6 +876,96 @@ func _Chat_SendMessage_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Chat_Join_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServer).Join(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Chat_Join_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServer).Join(ctx, req.(*ChatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chat_Leave_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServer).Leave(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Chat_Leave_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServer).Leave(ctx, req.(*ChatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chat_ListChannels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServer).ListChannels(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Chat_ListChannels_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServer).ListChannels(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chat_Whisper_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WhisperRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServer).Whisper(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Chat_Whisper_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServer).Whisper(ctx, req.(*WhisperRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chat_History_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServer).History(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Chat_History_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServer).History(ctx, req.(*HistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Chat_ServiceDesc is the grpc.ServiceDesc for Chat service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -457,6 +977,26 @@ var Chat_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SendMessage",
 			Handler:    _Chat_SendMessage_Handler,
 		},
+		{
+			MethodName: "Join",
+			Handler:    _Chat_Join_Handler,
+		},
+		{
+			MethodName: "Leave",
+			Handler:    _Chat_Leave_Handler,
+		},
+		{
+			MethodName: "ListChannels",
+			Handler:    _Chat_ListChannels_Handler,
+		},
+		{
+			MethodName: "Whisper",
+			Handler:    _Chat_Whisper_Handler,
+		},
+		{
+			MethodName: "History",
+			Handler:    _Chat_History_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
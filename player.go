@@ -7,11 +7,19 @@ import (
 	"github.com/google/uuid"
 )
 
+// BotProvider is the identity provider of bot accounts, which log in with api keys instead of signing in
+const BotProvider = "bot"
+
+// GuestProvider is the identity provider of guest accounts, which have not signed in with a real identity yet
+const GuestProvider = "guest"
+
 // a player object. used by both client and server
 type Player struct {
 	*sync.RWMutex
 	playerId     string
 	googleId     string
+	provider     string
+	subject      string
 	name         string
 	sessionToken string
 	avatarURL    string
@@ -34,8 +42,8 @@ func NewPlayer() *Player {
 	return &player
 }
 
-func LoadPlayer(pId string, gId string, name string, sToken string, aUrl string, created time.Time, updated time.Time, read time.Time) Player {
-	p := Player{&sync.RWMutex{}, pId, gId, name, sToken, aUrl, created, updated, read}
+func LoadPlayer(pId string, gId string, provider string, subject string, name string, sToken string, aUrl string, created time.Time, updated time.Time, read time.Time) Player {
+	p := Player{&sync.RWMutex{}, pId, gId, provider, subject, name, sToken, aUrl, created, updated, read}
 	p.setAccessed()
 	return p
 }
@@ -86,6 +94,31 @@ func (p *Player) SetGoogleId(id string) {
 	p.setUpdated()
 }
 
+func (p *Player) GetProvider() string {
+	p.RLock()
+	provider := p.provider
+	p.RUnlock()
+	p.setAccessed()
+	return provider
+}
+
+func (p *Player) GetSubject() string {
+	p.RLock()
+	subject := p.subject
+	p.RUnlock()
+	p.setAccessed()
+	return subject
+}
+
+// SetIdentity sets the identity provider and the provider's subject id that this player logged in with
+func (p *Player) SetIdentity(provider string, subject string) {
+	p.Lock()
+	p.provider = provider
+	p.subject = subject
+	p.Unlock()
+	p.setUpdated()
+}
+
 func (p *Player) GetName() string {
 	p.RLock()
 	name := p.name
@@ -101,6 +134,24 @@ func (p *Player) SetName(name string) {
 	p.setUpdated()
 }
 
+// IsBot returns whether this player is a bot account
+func (p *Player) IsBot() bool {
+	return p.GetProvider() == BotProvider
+}
+
+// IsGuest returns whether this player is a guest account
+func (p *Player) IsGuest() bool {
+	return p.GetProvider() == GuestProvider
+}
+
+// GetDisplayName returns the name to show other players, bots are labelled with [BOT]
+func (p *Player) GetDisplayName() string {
+	if p.IsBot() {
+		return "[BOT] " + p.GetName()
+	}
+	return p.GetName()
+}
+
 func (p *Player) GetSessionToken() string {
 	p.RLock()
 	sessionToken := p.sessionToken
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cheracc/fortress-grpc/server/handlers/commands"
+)
+
+// the kinds of security event that are kept in the audit log
+const (
+	EventLoginSuccess = "login.success"
+	EventLoginFailure = "login.failure"
+	EventTokenRefresh = "token.refresh"
+	EventTokenInvalid = "token.invalid"
+	EventLogout       = "logout"
+	EventRoleGrant    = "role.grant"
+	EventRoleRevoke   = "role.revoke"
+	EventRoleCreate   = "role.create"
+	EventRoleDelete   = "role.delete"
+	EventBan          = "ban"
+	EventUnban        = "unban"
+	EventChatFlagged  = "chat.flagged"
+)
+
+const (
+	// how many events the audit command shows at a time
+	auditPageSize              = 20
+	securityEventSweepInterval = 1 * time.Hour
+)
+
+// A SecurityEvent is an entry in the audit log. playerId is the player it happened to and actorId the player that caused it, if
+// that was someone else (such as the admin that banned them). Either may be "" if it is not known, as for a login with an unknown
+// username. ipAddress is the address without its port
+type SecurityEvent struct {
+	id        int64
+	at        time.Time
+	kind      string
+	playerId  string
+	actorId   string
+	ipAddress string
+	detail    string
+}
+
+// A throttledEvent counts the events of one kind, for one ip address and player, that were left out of the audit log because one
+// was already added in the last authAttemptWindow
+type throttledEvent struct {
+	recordedAt time.Time
+	skipped    int
+	last       *SecurityEvent // the last one that was left out
+}
+
+// summary returns an event that stands in for the ones that were left out
+func (t *throttledEvent) summary() *SecurityEvent {
+	return &SecurityEvent{at: t.last.at, kind: t.last.kind, playerId: t.last.playerId, ipAddress: t.last.ipAddress,
+		detail: fmt.Sprintf("%d more since %s, the last: %s", t.skipped, t.recordedAt.UTC().Format(time.DateTime), t.last.detail)}
+}
+
+// recordLoginFailure adds a failed login to the audit log. playerId is "" if it is not known who tried to log in
+func (h *AuthHandler) recordLoginFailure(playerId string, ipAddress string, reason string) {
+	h.recordThrottled(&SecurityEvent{kind: EventLoginFailure, playerId: playerId, ipAddress: ipAddress, detail: reason})
+}
+
+// recordThrottled adds an event that anyone can cause without logging in to the audit log, at most once per authAttemptWindow for
+// each kind, ip address and player. The others are counted, and added as one event once the window is over
+func (h *AuthHandler) recordThrottled(e *SecurityEvent) {
+	key := e.kind + " " + hostOf(e.ipAddress) + " " + e.playerId
+	now := time.Now()
+
+	pending := &h.AuthenticatingPlayers
+	pending.Lock()
+	t := pending.throttled[key]
+	if t != nil && now.Sub(t.recordedAt) < authAttemptWindow {
+		e.at = now.UTC()
+		t.skipped++
+		t.last = e
+		pending.Unlock()
+		return
+	}
+	pending.throttled[key] = &throttledEvent{recordedAt: now}
+	pending.Unlock()
+
+	if t != nil && t.skipped > 0 {
+		h.SqliteHandler.RecordSecurityEvent(t.summary())
+	}
+	h.SqliteHandler.RecordSecurityEvent(e)
+}
+
+// sweepThrottledEvents forgets the throttled events whose window is over, and returns the events that stand in for the ones that
+// were left out
+func (a *AuthenticatingPlayers) sweepThrottledEvents() []*SecurityEvent {
+	now := time.Now()
+	a.Lock()
+	defer a.Unlock()
+
+	summaries := make([]*SecurityEvent, 0)
+	for key, t := range a.throttled {
+		if now.Sub(t.recordedAt) >= authAttemptWindow {
+			if t.skipped > 0 {
+				summaries = append(summaries, t.summary())
+			}
+			delete(a.throttled, key)
+		}
+	}
+	return summaries
+}
+
+func (h *AuthHandler) startSecurityEventSweeper() {
+	go func() {
+		for {
+			time.Sleep(securityEventSweepInterval)
+			if h.config.SecurityEventLifetime <= 0 {
+				continue
+			}
+			if removed := h.SqliteHandler.DeleteSecurityEventsBefore(time.Now().UTC().Add(-h.config.SecurityEventLifetime)); removed > 0 {
+				h.Logf("Removed %d old security events", removed)
+			}
+		}
+	}()
+}
+
+// ListSecurityEvents returns one page of the security events that match the filter, newest first, and whether there are older ones.
+// This error gets passed back to the user/client
+func (h *AuthHandler) ListSecurityEvents(filter commands.AuditFilter) ([]commands.SecurityEventInfo, bool, error) {
+	playerId := ""
+	if filter.Player != "" {
+		player := h.FindPlayer(filter.Player)
+		if player == nil {
+			return nil, false, fmt.Errorf("there is no player named %s", filter.Player)
+		}
+		playerId = player.GetPlayerId()
+	}
+
+	events := h.SqliteHandler.LookupSecurityEvents(filter.Kind, playerId, filter.IpAddress, filter.Since, filter.Page*auditPageSize, auditPageSize+1)
+	more := len(events) > auditPageSize
+	if more {
+		events = events[:auditPageSize]
+	}
+
+	names := make(map[string]string) // the same few players tend to show up on every line
+	name := func(id string) string {
+		if id == "" {
+			return ""
+		}
+		if _, ok := names[id]; !ok {
+			names[id] = id
+			if p := h.FindPlayer(id); p != nil && p.GetName() != "" {
+				names[id] = p.GetName()
+			}
+		}
+		return names[id]
+	}
+
+	infos := make([]commands.SecurityEventInfo, 0)
+	for _, e := range events {
+		infos = append(infos, commands.SecurityEventInfo{At: e.at, Kind: e.kind, Player: name(e.playerId), Actor: name(e.actorId), IpAddress: e.ipAddress, Detail: e.detail})
+	}
+	return infos, more, nil
+}
+
+func (h *SqliteHandler) initializeSecurityEventsTable() {
+	_, err := h.db.Exec("CREATE TABLE IF NOT EXISTS security_events (" +
+		"id INTEGER PRIMARY KEY AUTOINCREMENT, " +
+		"at INTEGER, " +
+		"kind TEXT, " +
+		"player_id TEXT, " +
+		"actor_id TEXT, " +
+		"ip_address TEXT, " +
+		"detail TEXT)")
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+
+	for _, column := range []string{"at", "player_id", "ip_address"} {
+		if _, err := h.db.Exec("CREATE INDEX IF NOT EXISTS security_events_" + column + " ON security_events (" + column + ")"); err != nil {
+			h.Fatal(err.Error())
+		}
+	}
+}
+
+// RecordSecurityEvent adds the event to the audit log. The ip address is stored without its port
+func (h *SqliteHandler) RecordSecurityEvent(e *SecurityEvent) {
+	if e.at.IsZero() {
+		e.at = time.Now().UTC()
+	}
+	_, err := h.db.Exec("INSERT INTO security_events (at, kind, player_id, actor_id, ip_address, detail) VALUES (?, ?, ?, ?, ?, ?)",
+		e.at.Unix(), e.kind, e.playerId, e.actorId, hostOf(e.ipAddress), e.detail)
+	if err != nil {
+		h.Errorf("SQL: could not record %s event for player %s: %s", e.kind, e.playerId, err)
+	}
+}
+
+// LookupSecurityEvents returns up to limit events, newest first, skipping the first offset. Empty filters match every event. A kind
+// also matches the kinds that start with it and a dot, so "login" matches both login.success and login.failure
+func (h *SqliteHandler) LookupSecurityEvents(kind string, playerId string, ipAddress string, since time.Time, offset int, limit int) []*SecurityEvent {
+	conditions := make([]string, 0)
+	args := make([]any, 0)
+	if kind != "" {
+		conditions = append(conditions, "(kind = ? OR kind LIKE ?)")
+		args = append(args, kind, kind+".%")
+	}
+	if playerId != "" {
+		conditions = append(conditions, "(player_id = ? OR actor_id = ?)")
+		args = append(args, playerId, playerId)
+	}
+	if ipAddress != "" {
+		conditions = append(conditions, "ip_address = ?")
+		args = append(args, hostOf(ipAddress))
+	}
+	if !since.IsZero() {
+		conditions = append(conditions, "at >= ?")
+		args = append(args, since.Unix())
+	}
+
+	query := "SELECT id, at, kind, player_id, actor_id, ip_address, detail FROM security_events"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		h.Errorf("SQL: could not look up security events: %s", err)
+		return nil
+	}
+	defer rows.Close()
+
+	events := make([]*SecurityEvent, 0)
+	for rows.Next() {
+		e := &SecurityEvent{}
+		var at int64
+		if err := rows.Scan(&e.id, &at, &e.kind, &e.playerId, &e.actorId, &e.ipAddress, &e.detail); err != nil {
+			h.Errorf("SQL: %s", err)
+			return nil
+		}
+		e.at = time.Unix(at, 0)
+		events = append(events, e)
+	}
+	return events
+}
+
+// DeleteSecurityEventsBefore deletes every security event from before the cutoff, and returns how many were deleted
+func (h *SqliteHandler) DeleteSecurityEventsBefore(cutoff time.Time) int64 {
+	result, err := h.db.Exec("DELETE FROM security_events WHERE at < ?", cutoff.Unix())
+	if err != nil {
+		h.Errorf("SQL: could not delete old security events: %s", err)
+		return 0
+	}
+	n, _ := result.RowsAffected()
+	return n
+}
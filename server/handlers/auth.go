@@ -2,46 +2,88 @@ package handlers
 
 import (
 	"context"
-	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/cheracc/fortress-grpc"
 	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"github.com/cheracc/fortress-grpc/server/handlers/commands"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 // The AuthHandler handles all user authorizations. It holds a reference to the PlayerHandler
 type AuthHandler struct {
-	fgrpc.UnimplementedAuthServer                   // the gRPC server that accepts auth requests
-	*PlayerHandler                                  // a pointer to the PlayerHandler to lookup players
-	OauthHandler                                    // a separate handler to deal with oAuth stuff
-	*fortress.Logger                                // the logger
-	key                           *ecdsa.PrivateKey // the private key used for signing auth tokens
-	AuthenticatingPlayers                           // the players that are currently in the process of authenticating
+	fgrpc.UnimplementedAuthServer                  // the gRPC server that accepts auth requests
+	*PlayerHandler                                 // a pointer to the PlayerHandler to lookup players
+	OauthHandler                                   // a separate handler to deal with oAuth stuff
+	*fortress.Logger                               // the logger
+	keys                          *Keyring         // the keys used for signing and verifying session tokens
+	AuthenticatingPlayers                          // the players that are currently in the process of authenticating
+	config                        *Config          // the server configuration
+	sessions                      *SessionRegistry // the sessions of all players
+	terms                         *TermsOfService  // the terms of service players must accept
 }
 
-// JwtTokenClaims contains the data that we save on the session token
+// JwtTokenClaims contains the data that we save on the session token. Besides the player and session, the registered claims hold
+// the issuer, audience, issue and expiry times, and a unique id, so that other services can verify the token (see the verify package)
 type JwtTokenClaims struct {
-	PlayerID string `json:"player-id"`
+	PlayerID  string `json:"player-id"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
 // Auth contains the information used during the authorization of the player. The session token will be the final field to be populated
 type Auth struct {
-	googleId     string
+	provider     string
+	subject      string
 	oauthState   string
+	codeVerifier string // the PKCE verifier, only its challenge is sent to the provider
+	nonce        string // must come back in the provider's id token
+	callbackUsed bool   // each oauth state can only be used by one callback
 	sessionToken string
+	refreshToken string
 	avatarUrl    string
 	ipAddress    string
+	device       string
+	inviteCode   string // redeemed when the player finishes logging in, if they need it to join a whitelist-only server
+	failure      string // why the login was refused after the player signed in with the provider, such as a ban
+	createdAt    time.Time
+	expiresAt    time.Time // pending auths are forgotten after config.PendingAuthLifetime
+
+	// only used by device logins, see startDeviceAuth
+	loginURL     string
+	deviceCode   string
+	userCode     string
+	pollInterval time.Duration // pollInterval and lastPoll are guarded by the AuthenticatingPlayers lock, see recordPoll
+	lastPoll     time.Time
+
+	// the player that signed in, and for players with two-factor authentication on the challenge they answer (see startSecondFactor)
+	playerId     string
+	challenge    string
+	codeAttempts int
+
+	linkPlayerId    string // set when a logged in player is linking another identity instead of logging in, see StartLink
+	recoverPlayerId string // set when a player is recovering their account, the identity they sign in with is linked to it (see Recover)
+	recoveryCode    string // the hash of the recovery code they proved it with, used up once they have a session (see useRecoveryCode)
 }
 
+// AuthenticatingPlayers contains the pending auths keyed by their oauth state (and device code), and the recent login attempts
+// of each ip address. The methods of AuthenticatingPlayers are thread-safe
 type AuthenticatingPlayers struct {
 	*sync.RWMutex
-	auths map[string]*Auth
+	auths     map[string]*Auth
+	attempts  map[string][]time.Time
+	throttled map[string]*throttledEvent // see recordThrottled
+	// each player's recent attempts at their second factor codes, see claimAccountCodeAttempt
+	codeAttempts map[string][]time.Time
+	// the device codes of device logins that expired, and when, so devices still polling them are told so (see expireDeviceAuth)
+	expiredDeviceCodes map[string]time.Time
 }
 
 func (a *AuthenticatingPlayers) AddAuth(oauthTokenString string, auth *Auth) {
@@ -60,53 +102,70 @@ func (a *AuthenticatingPlayers) DeleteAuth(oauthTokenString string) {
 	a.Unlock()
 }
 
+// removeAuth deletes the auth under its oauth state and, for device logins, its device code
+func (a *AuthenticatingPlayers) removeAuth(auth *Auth) {
+	a.Lock()
+	delete(a.auths, auth.oauthState)
+	if auth.deviceCode != "" {
+		delete(a.auths, auth.deviceCode)
+	}
+	a.Unlock()
+}
+
+// GetAuthByUserCode returns the pending device login with the given user code, or nil if there is none
+func (a *AuthenticatingPlayers) GetAuthByUserCode(userCode string) *Auth {
+	a.RLock()
+	defer a.RUnlock()
+	for _, auth := range a.auths {
+		if auth.userCode != "" && auth.userCode == userCode {
+			return auth
+		}
+	}
+	return nil
+}
+
 // isComplete returns whether the Auth is fully populated
 func (a *Auth) isComplete() bool {
-	return (a.googleId != "") && (a.oauthState != "") && (a.sessionToken != "")
+	return (a.subject != "") && (a.oauthState != "") && (a.sessionToken != "" || a.challenge != "")
 }
 
 // Authorize is the gRPC receiving function for authorization requests.
 func (h *AuthHandler) Authorize(ctx context.Context, playerInfo *fgrpc.PlayerInfo) (*fgrpc.AuthInfo, error) {
-	ip, _ := peer.FromContext(ctx)
+	ip := peerAddress(ctx)
 	unverifiedPlayerId := playerInfo.GetId() // if this is the first time a user is connecting, this is just a randomly generated uuid from the client
-	h.Logf("Recieved authorization request from %s", ip.Addr.String())
+	h.Logf("Recieved authorization request from %s", ip)
 
 	authInfo := fgrpc.AuthInfo{PlayerID: unverifiedPlayerId} // set up the response payload
 
 	receivedSessionToken := playerInfo.GetSessionToken() // the token the user sent - either a random uuid oauthstate token or a signed session token
 	if receivedSessionToken == "" {                      // this is user's first attempt to auth
-		var state string
-		authInfo.LoginURL, state = h.OauthHandler.GenerateLoginURL()
-		authInfo.SessionToken = state
-
-		h.AddAuth(state, &Auth{
-			oauthState: state,
-			ipAddress:  ip.Addr.String()})
-		return &authInfo, nil
+		return h.startAuth(&authInfo, playerInfo, ip)
 	}
 
-	if len(receivedSessionToken) < 40 { // this is an oauthstate token, this user is in the process of authenticating
+	if len(receivedSessionToken) < 40 { // this is an oauthstate token (or a device code), this user is in the process of authenticating
 		auth := h.GetAuth(receivedSessionToken)
+		if auth != nil && auth.deviceCode != "" && auth.deviceCode != receivedSessionToken {
+			auth = nil // the state of a device login was seen by the browser, only the device code may be used to collect the session
+		}
+		if auth != nil && auth.deviceCode == "" && auth.isExpired() {
+			h.removeAuth(auth)
+			auth = nil
+		}
+		if auth == nil && h.isExpiredDeviceCode(receivedSessionToken) {
+			return expiredDeviceAuth(&authInfo), nil
+		}
 		if auth == nil { // this player had an auth token, but we have no record of it. it may be very old. just send them a new one and a link
-			var state string
-			authInfo.LoginURL, state = h.OauthHandler.GenerateLoginURL()
-			authInfo.SessionToken = state
-
-			h.AddAuth(state, &Auth{
-				oauthState: state,
-				ipAddress:  ip.Addr.String()})
-			return &authInfo, nil
+			return h.startAuth(&authInfo, playerInfo, ip)
+		}
+		if auth.deviceCode != "" {
+			return h.pollDeviceAuth(auth, &authInfo)
 		}
-		if auth.isComplete() { // this authorization is complete (logged in with google). load their player and send them a session token
-			player, _ := h.GetPlayer(PlayerFilter{googleId: auth.googleId}, true) // fetching by google id since its trusted
-			player.SetAvatarUrl(auth.avatarUrl)
-			player.SetSessionToken(auth.sessionToken)
-			authInfo.PlayerID = player.GetPlayerId()
-			authInfo.SessionToken = auth.sessionToken
-			authInfo.LoginURL = ""
-
-			h.DeleteAuth(auth.oauthState) // remove their auth
-			return &authInfo, nil
+		if auth.failure != "" { // the player signed in but was not allowed to log in
+			h.removeAuth(auth)
+			return nil, status.Error(codes.PermissionDenied, auth.failure)
+		}
+		if auth.isComplete() { // this authorization is complete (logged in with the provider). load their player and send them a session token
+			return h.completeAuth(auth, &authInfo), nil
 		}
 		// from here they are still authenticating, just send their same info back
 		authInfo.SessionToken = playerInfo.SessionToken
@@ -115,12 +174,19 @@ func (h *AuthHandler) Authorize(ctx context.Context, playerInfo *fgrpc.PlayerInf
 	}
 
 	// if reaching here, the user has an actual session token, but we don't know if its valid
-	hasValidToken := h.verifySessionToken(receivedSessionToken) // see if this token is valid (and not expired)
+	hasValidToken := h.verifySessionToken(receivedSessionToken) // see if this token is valid (and not expired, and its session is active)
 	if hasValidToken {                                          // already had valid token
-		playerId := h.GetPlayerIdFromTokenString(receivedSessionToken)   // use the playerId from the session token, as it is signed
-		player, _ := h.GetPlayer(PlayerFilter{playerId: playerId}, true) // log this player in if they are not already
-		h.Logf("Player %s(%s) refreshed session token", player.GetName(), playerId)
-		h.AuthorizePlayer(player)
+		_, claims := h.getTokenFromString(receivedSessionToken)                 // use the playerId and session from the session token, as it is signed
+		player, _ := h.GetPlayer(PlayerFilter{playerId: claims.PlayerID}, true) // log this player in if they are not already
+		if err := h.checkSessionAllowed(player, claims.SessionID, ip); err != nil {
+			return nil, err
+		}
+		// the token is only confirmed, never renewed here. New tokens come from Refresh, so the refresh token is rotated and a
+		// leaked session token stops working when it expires
+		h.Logf("Player %s(%s) confirmed session %s", player.GetName(), claims.PlayerID, claims.SessionID)
+		player.SetSessionToken(receivedSessionToken)
+		h.AddOnlinePlayer(player)
+		h.sessions.Touch(claims.SessionID, ip)
 
 		authInfo.PlayerID = player.GetPlayerId()
 		authInfo.SessionToken = player.GetSessionToken()
@@ -129,61 +195,278 @@ func (h *AuthHandler) Authorize(ctx context.Context, playerInfo *fgrpc.PlayerInf
 		return &authInfo, nil
 	} else {
 		h.Logf("Received token from %s[unverified] invalid or expired", unverifiedPlayerId) // this user had a token but its invalid or expired
-		var state string
-		authInfo.LoginURL, state = h.GenerateLoginURL()
-		authInfo.SessionToken = state
+		h.recordThrottled(&SecurityEvent{kind: EventTokenInvalid, ipAddress: ip,
+			detail: fmt.Sprintf("invalid or expired session token sent to Authorize by %s (unverified)", unverifiedPlayerId)})
+		return h.startAuth(&authInfo, playerInfo, ip)
+	}
+}
 
-		h.AddAuth(state, &Auth{
-			oauthState: state,
-			ipAddress:  ip.Addr.String()})
-		return &authInfo, nil
+// startAuth begins a new login with the identity provider the player asked for (or the default provider). It fills authInfo with
+// the login url and the oauth state the client should send back, and records the pending Auth
+func (h *AuthHandler) startAuth(authInfo *fgrpc.AuthInfo, playerInfo *fgrpc.PlayerInfo, ipAddress string) (*fgrpc.AuthInfo, error) {
+	if playerInfo.GetProvider() == fortress.GuestProvider {
+		return h.authorizeGuest(authInfo, playerInfo, ipAddress)
+	}
+	if playerInfo.GetDeviceFlow() {
+		return h.startDeviceAuth(authInfo, playerInfo, ipAddress)
 	}
+
+	providerName := playerInfo.GetProvider()
+	if providerName == "" {
+		providerName = h.config.DefaultProvider
+	}
+
+	url, auth, err := h.OauthHandler.GenerateLoginURL(providerName)
+	if err != nil {
+		return nil, h.Errorf("could not start login for %s: %s", ipAddress, err)
+	}
+
+	auth.ipAddress = ipAddress
+	auth.device = playerInfo.GetDevice()
+	auth.inviteCode = playerInfo.GetInviteCode()
+	if err := h.addPendingAuth(auth); err != nil {
+		return nil, err
+	}
+	authInfo.LoginURL = url
+	authInfo.SessionToken = auth.oauthState
+	return authInfo, nil
+}
+
+// completeAuth fills authInfo with the session of a player who has finished signing in with the identity provider,
+// and forgets the auth
+func (h *AuthHandler) completeAuth(auth *Auth, authInfo *fgrpc.AuthInfo) *fgrpc.AuthInfo {
+	if auth.challenge != "" { // the player has two-factor authentication on, the auth stays under its challenge until they send a code
+		authInfo.PlayerID = auth.playerId
+		authInfo.SessionToken = ""
+		authInfo.SecondFactorChallenge = auth.challenge
+		authInfo.LoginURL = ""
+		authInfo.Error = ""
+		h.removeAuth(auth)
+		return authInfo
+	}
+
+	player, _ := h.GetPlayer(PlayerFilter{playerId: auth.playerId}, true) // the player the trusted identity resolved to in the callback
+	player.SetAvatarUrl(auth.avatarUrl)
+	player.SetSessionToken(auth.sessionToken)
+	authInfo.PlayerID = player.GetPlayerId()
+	authInfo.SessionToken = auth.sessionToken
+	authInfo.RefreshToken = auth.refreshToken
+	authInfo.RecoveryCodes = h.issueFirstRecoveryCodes(player)
+	authInfo.LoginURL = ""
+	authInfo.Error = ""
+
+	h.removeAuth(auth) // remove their auth
+	return authInfo
+}
+
+// Refresh is the gRPC receiving function that exchanges a refresh token for a new session token and a new refresh token
+func (h *AuthHandler) Refresh(ctx context.Context, request *fgrpc.RefreshRequest) (*fgrpc.AuthInfo, error) {
+	session, refreshToken, err := h.sessions.Refresh(request.GetRefreshToken(), peerAddress(ctx))
+	if err != nil {
+		h.Logf("Refresh from %s failed: %s", peerAddress(ctx), err)
+		h.recordThrottled(&SecurityEvent{kind: EventTokenInvalid, ipAddress: peerAddress(ctx), detail: "refresh failed: " + err.Error()})
+		return nil, err
+	}
+
+	player, _ := h.GetPlayer(PlayerFilter{playerId: session.GetPlayerId()}, true)
+	if err := h.checkSessionAllowed(player, session.GetSessionId(), peerAddress(ctx)); err != nil {
+		return nil, err
+	}
+	player.SetSessionToken(h.generateToken(player.GetPlayerId(), session.GetSessionId()))
+	h.AddOnlinePlayer(player)
+	h.Logf("Player %s(%s) refreshed session %s", player.GetName(), player.GetPlayerId(), session.GetSessionId())
+	h.SqliteHandler.RecordSecurityEvent(&SecurityEvent{kind: EventTokenRefresh, playerId: player.GetPlayerId(), ipAddress: peerAddress(ctx), detail: "session " + session.GetSessionId()})
+
+	return &fgrpc.AuthInfo{PlayerID: player.GetPlayerId(), SessionToken: player.GetSessionToken(), RefreshToken: refreshToken}, nil
 }
 
-// NewAuthHandler constructs a new AuthHandler using the given gRCP Server, PlayerHandler, and Logger
-func NewAuthHandler(playerHandler *PlayerHandler, logger *fortress.Logger) *AuthHandler {
-	randomKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+// checkSessionAllowed returns an error if the player of an existing session has since been banned or (on a whitelist-only server)
+// is no longer on the whitelist. The session is ended if so
+func (h *AuthHandler) checkSessionAllowed(player *fortress.Player, sessionId string, ipAddress string) error {
+	err := h.checkNotBanned(player)
+	if err == nil {
+		err = h.checkAdmitted(player, "")
+	}
+	if err != nil {
+		h.sessions.Revoke(sessionId, err.Error())
+		h.recordLoginFailure(player.GetPlayerId(), ipAddress, err.Error())
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return nil
+}
+
+// Logout is the gRPC receiving function that ends the session of the sent session token
+func (h *AuthHandler) Logout(ctx context.Context, playerInfo *fgrpc.PlayerInfo) (*fgrpc.Empty, error) {
+	claims := claimsFromContext(ctx) // set by the auth interceptor once it has verified the session token
+	if claims == nil {
+		return nil, h.Error("invalid session token")
+	}
+
+	h.sessions.Revoke(claims.SessionID, "logged out")
+	h.Logf("Player %s logged out of session %s", claims.PlayerID, claims.SessionID)
+	h.SqliteHandler.RecordSecurityEvent(&SecurityEvent{kind: EventLogout, playerId: claims.PlayerID, ipAddress: peerAddress(ctx), detail: "session " + claims.SessionID})
+	return &fgrpc.Empty{}, nil
+}
 
+// NewAuthHandler constructs a new AuthHandler using the given PlayerHandler, Config, and Logger. It registers
+// every identity provider that is configured
+func NewAuthHandler(playerHandler *PlayerHandler, config *Config, logger *fortress.Logger) *AuthHandler {
+	handler := newAuthHandler(playerHandler, config, logger)
+	handler.OauthHandler.StartListener()
+	handler.startPendingAuthSweeper()
+	handler.startGuestSweeper()
+	handler.startSecurityEventSweeper()
+
+	logger.Logf("Started AuthHandler, session tokens are issued by %s for %s and can be verified with %s", config.TokenIssuer, config.TokenAudience, config.JWKSURL())
+	return handler
+}
+
+// newAuthHandler constructs the AuthHandler, its http routes, identity providers and terms of service, without starting the http
+// listener or the sweepers
+func newAuthHandler(playerHandler *PlayerHandler, config *Config, logger *fortress.Logger) *AuthHandler {
 	handler := &AuthHandler{
 		fgrpc.UnimplementedAuthServer{},
 		playerHandler,
-		NewOauthHandler(logger),
+		NewOauthHandler(config, logger),
 		logger,
-		randomKey,
-		AuthenticatingPlayers{&sync.RWMutex{}, make(map[string]*Auth)}}
+		NewKeyring(config.KeyFile, logger),
+		AuthenticatingPlayers{&sync.RWMutex{}, make(map[string]*Auth), make(map[string][]time.Time), make(map[string]*throttledEvent),
+			make(map[string][]time.Time), make(map[string]time.Time)},
+		config,
+		NewSessionRegistry(playerHandler.SqliteHandler, config.RefreshTokenLifetime, logger),
+		&TermsOfService{&sync.RWMutex{}, config.TermsDir, nil}}
+
+	handler.checkSessionConfig()
+	handler.OauthHandler.registerRoutes(handler)
+	handler.registerIdentityProviders()
+	handler.loadTerms()
+	return handler
+}
 
-	handler.OauthHandler.StartListener(handler)
+// registerIdentityProviders registers the identity providers that have credentials in the config
+func (h *AuthHandler) registerIdentityProviders() {
+	if h.config.GoogleClientID != "" {
+		h.RegisterProvider(NewGoogleProvider(h.config.GoogleClientID, h.config.GoogleClientSecret, h.config.CallbackURL("google")))
+		h.Log("Registered identity provider google")
+	}
 
-	logger.Log("Started AuthHandler")
-	return handler
+	if h.config.OidcIssuer != "" {
+		provider, err := NewOidcProvider(context.Background(), h.config.OidcName, h.config.OidcIssuer, h.config.OidcClientID,
+			h.config.OidcClientSecret, h.config.CallbackURL(h.config.OidcName), h.config.OidcScopes)
+		if err != nil {
+			h.Errorf("could not register OpenID Connect provider %s: %s", h.config.OidcName, err)
+		} else {
+			h.RegisterProvider(provider)
+			h.Logf("Registered identity provider %s (%s)", provider.Name(), h.config.OidcIssuer)
+		}
+	}
 }
 
-// AuthorizePlayer sets a new fresh session token on the player and saves them to the database
-func (h *AuthHandler) AuthorizePlayer(player *fortress.Player) error {
-	player.SetSessionToken(h.generateToken(player.GetPlayerId()))
+// AuthorizePlayer starts a new session for the player on the given device and ip address, sets a fresh session token on the player
+// and adds them to the online players. It returns the refresh token of the new session, or an error if the player is banned or
+// (on a whitelist-only server) not on the whitelist. inviteCode is redeemed if the player needs it to join, it may be ""
+func (h *AuthHandler) AuthorizePlayer(player *fortress.Player, device string, ipAddress string, inviteCode string) (string, error) {
+	return h.authorizeSession(player, device, ipAddress, "", inviteCode)
+}
+
+// authorizeSession does the work of AuthorizePlayer. apiKeyId is the api key a bot is logging in with, or "" for players
+func (h *AuthHandler) authorizeSession(player *fortress.Player, device string, ipAddress string, apiKeyId string, inviteCode string) (string, error) {
+	if err := h.checkNotBanned(player); err != nil {
+		h.recordLoginFailure(player.GetPlayerId(), ipAddress, err.Error())
+		return "", status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err := h.checkAdmitted(player, inviteCode); err != nil {
+		h.recordLoginFailure(player.GetPlayerId(), ipAddress, err.Error())
+		return "", status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	h.applySessionPolicy(player)
+
+	session, refreshToken := h.sessions.CreateSession(player.GetPlayerId(), device, ipAddress, apiKeyId)
+	detail := fmt.Sprintf("session %s on %q", session.GetSessionId(), device)
+	if apiKeyId != "" {
+		detail += " with api key " + apiKeyId
+	}
+	h.SqliteHandler.RecordSecurityEvent(&SecurityEvent{kind: EventLoginSuccess, playerId: player.GetPlayerId(), ipAddress: ipAddress, detail: detail})
+	player.SetSessionToken(h.generateToken(player.GetPlayerId(), session.GetSessionId()))
 	h.AddOnlinePlayer(player)
 
+	return refreshToken, nil
+}
+
+// checkSessionConfig falls back to SessionPolicyMultiple if the config names an unknown session policy, so a typo does not
+// silently change how many devices players can log in on
+func (h *AuthHandler) checkSessionConfig() {
+	if h.config.SessionPolicy != SessionPolicyMultiple && h.config.SessionPolicy != SessionPolicyTakeover {
+		h.Errorf("Unknown session policy %s, expected %s or %s. Using %s", h.config.SessionPolicy, SessionPolicyMultiple, SessionPolicyTakeover, SessionPolicyMultiple)
+		h.config.SessionPolicy = SessionPolicyMultiple
+	}
+}
+
+// applySessionPolicy makes room for a new session of the player. With the takeover policy every other session of the player
+// is ended, otherwise the player's oldest sessions are ended if they already have the maximum number of sessions
+func (h *AuthHandler) applySessionPolicy(player *fortress.Player) {
+	if h.config.SessionPolicy == SessionPolicyTakeover {
+		h.sessions.RevokeAll(player.GetPlayerId(), "", "you logged in on another device")
+		return
+	}
+
+	if h.config.MaxSessionsPerPlayer <= 0 {
+		return
+	}
+	active := h.sessions.GetActiveSessions(player.GetPlayerId())
+	for i := 0; i <= len(active)-h.config.MaxSessionsPerPlayer; i++ {
+		h.sessions.Revoke(active[i].GetSessionId(), "you logged in on too many devices, this was the oldest session")
+	}
+}
+
+// ListSessions describes the player's sessions that have not ended, oldest first
+func (h *AuthHandler) ListSessions(playerId string) []commands.SessionInfo {
+	infos := make([]commands.SessionInfo, 0)
+	for _, s := range h.sessions.GetActiveSessions(playerId) {
+		infos = append(infos, commands.SessionInfo{Id: s.GetSessionId(), Device: s.GetDevice(), IpAddress: s.GetIpAddress(), IssuedAt: s.GetIssuedAt(), LastSeen: s.GetLastSeen()})
+	}
+	return infos
+}
+
+// RevokePlayerSession ends one of the player's own sessions. The session can be given by the start of its id.
+// This error gets passed back to the user/client
+func (h *AuthHandler) RevokePlayerSession(playerId string, sessionIdPrefix string) error {
+	matches := make([]*Session, 0)
+	for _, s := range h.sessions.GetActiveSessions(playerId) {
+		if strings.HasPrefix(s.GetSessionId(), sessionIdPrefix) {
+			matches = append(matches, s)
+		}
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("you have no active session starting with %s", sessionIdPrefix)
+	}
+	if len(matches) > 1 {
+		return fmt.Errorf("more than one of your sessions starts with %s, use more of the id", sessionIdPrefix)
+	}
+
+	h.sessions.Revoke(matches[0].GetSessionId(), "it was ended from another session")
 	return nil
 }
 
-// generateToken generates a new JWT session token using the provided playerId
-func (h *AuthHandler) generateToken(playerId string) string {
-	expirationTime := time.Now().UTC().Add(5 * time.Minute)
+// generateToken generates a new JWT session token for the given session of the player
+func (h *AuthHandler) generateToken(playerId string, sessionId string) string {
+	now := time.Now().UTC()
 
 	claims := &JwtTokenClaims{
-		PlayerID: playerId,
+		PlayerID:  playerId,
+		SessionID: sessionId,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			Issuer:    h.config.TokenIssuer,
+			Subject:   playerId,
+			Audience:  jwt.ClaimStrings{h.config.TokenAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(sessionTokenLifetime)),
+			ID:        uuid.NewString(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
-
-	if h.key == nil {
-		h.Fatal("Tried to create new session token but there is no private key loaded.")
-	}
-
-	tokenString, err := token.SignedString(h.key)
+	tokenString, err := h.keys.Sign(claims)
 	if err != nil {
 		h.Logf("could not create new jwt for pid %s: %s", playerId, err)
 		return ""
@@ -191,22 +474,10 @@ func (h *AuthHandler) generateToken(playerId string) string {
 	return tokenString
 }
 
-// verifySessionToken verifies the signature of the token and its expiry, and returns whether both are valid
+// verifySessionToken verifies the signature of the token, its expiry, and that its session has not ended, and returns whether all are valid
 func (h *AuthHandler) verifySessionToken(token string) bool {
-	tkn, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
-		return &h.key.PublicKey, nil
-	})
-
-	if err != nil {
-		h.Errorf("error parsing jwt token: %s: %s", token, err)
-		return false
-	}
-
-	if !tkn.Valid {
-		h.Errorf("session token %s is invalid (privatekey %s)", token, h.key)
-		return false
-	}
-	return true
+	tkn, _ := h.getTokenFromString(token)
+	return tkn != nil
 }
 
 // GetPlayerIdFromTokenString accepts a session token string and extracts the PlayerId from it.
@@ -219,16 +490,37 @@ func (h *AuthHandler) GetPlayerIdFromTokenString(tokenString string) string {
 func (h *AuthHandler) getTokenFromString(tokenString string) (*jwt.Token, *JwtTokenClaims) {
 	claims := &JwtTokenClaims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
-		return &h.key.PublicKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, h.keys.KeyFunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg()}),
+		jwt.WithIssuer(h.config.TokenIssuer),
+		jwt.WithAudience(h.config.TokenAudience),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired())
 	if err != nil {
-		h.Errorf("could not parse jwt token %s: %w", tokenString, err)
+		h.Errorf("could not parse jwt token %s: %s", tokenString, err)
+		return nil, &JwtTokenClaims{}
+	}
+	if !h.sessions.IsActive(claims.SessionID) {
+		h.Logf("session token for player %s belongs to session %s which has ended", claims.PlayerID, claims.SessionID)
 		return nil, &JwtTokenClaims{}
 	}
 	return token, claims
 }
 
+// peerAddress returns the ip address of the client that sent the request in ctx
+func peerAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return p.Addr.String()
+}
+
+// RotateSigningKey replaces the key that signs new session tokens. Tokens signed by the old key stay valid until they expire
+func (h *AuthHandler) RotateSigningKey() (string, error) {
+	return h.keys.Rotate()
+}
+
 // IsValidToken is an exported function to perform token validations, it simply calls verifySessionToken() with the given token string
 func (h *AuthHandler) IsValidToken(tokenString string) bool {
 	valid := h.verifySessionToken(tokenString)
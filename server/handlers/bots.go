@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cheracc/fortress-grpc"
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"github.com/cheracc/fortress-grpc/server/handlers/commands"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// api keys look like fk_<key id>.<secret>, only the hash of the secret is stored
+	apiKeyPrefix    = "fk_"
+	maxBotsPerOwner = 5
+)
+
+// An ApiKey lets a bot log in without signing in with an identity provider. The sessions it starts can only use the
+// permissions listed on the key
+type ApiKey struct {
+	keyId       string
+	botId       string
+	keyHash     string
+	permissions []string
+	createdAt   time.Time
+	lastUsedAt  time.Time
+	revokedAt   time.Time
+}
+
+// allows returns whether the key includes the permission
+func (k *ApiKey) allows(permission string) bool {
+	return slices.Contains(k.permissions, AllPermissions) || slices.Contains(k.permissions, permission)
+}
+
+// AuthorizeApiKey is the gRPC receiving function that logs a bot in with one of its api keys
+func (h *AuthHandler) AuthorizeApiKey(ctx context.Context, request *fgrpc.ApiKeyRequest) (*fgrpc.AuthInfo, error) {
+	ip := peerAddress(ctx)
+	if err := h.checkAuthLimits(ip); err != nil {
+		return nil, err
+	}
+
+	keyId, secret, _ := strings.Cut(strings.TrimPrefix(request.GetApiKey(), apiKeyPrefix), ".")
+	key := h.SqliteHandler.LookupApiKey(keyId)
+	if key == nil || !key.revokedAt.IsZero() || subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(key.keyHash)) != 1 {
+		h.Logf("Refused api key login from %s: invalid or revoked key %s", ip, keyId)
+		h.recordLoginFailure("", ip, "invalid or revoked api key "+keyId)
+		return nil, status.Error(codes.Unauthenticated, "invalid or revoked api key")
+	}
+	bot := h.FindPlayer(key.botId)
+	if bot == nil {
+		return nil, status.Error(codes.Unauthenticated, "the bot of this api key no longer exists")
+	}
+
+	refreshToken, err := h.authorizeSession(bot, request.GetDevice(), ip, key.keyId, "")
+	if err != nil {
+		return nil, err
+	}
+	h.SqliteHandler.TouchApiKey(key.keyId)
+	h.Logf("Bot %s(%s) logged in with api key %s from %s", bot.GetName(), bot.GetPlayerId(), key.keyId, ip)
+
+	return &fgrpc.AuthInfo{PlayerID: bot.GetPlayerId(), SessionToken: bot.GetSessionToken(), RefreshToken: refreshToken}, nil
+}
+
+// HasSessionPermission returns whether the player may use the permission in the given session. Players use the permissions of
+// the roles they can use (see usableRoles). Bots can only use the permissions on the api key their session was started with,
+// and only those their owner can use too
+func (h *RoleHandler) HasSessionPermission(player *fortress.Player, sessionId string, permission string) bool {
+	if !player.IsBot() {
+		return h.rolesGrant(h.usableRoles(player), permission)
+	}
+
+	session := h.SqliteHandler.LookupSessionFromDb(sessionId)
+	if session == nil || session.GetApiKeyId() == "" {
+		return false
+	}
+	key := h.SqliteHandler.LookupApiKey(session.GetApiKeyId())
+	if key == nil || !key.allows(permission) {
+		return false
+	}
+	owner := h.SqliteHandler.LookupPlayerFromDb(PlayerFilter{playerId: h.SqliteHandler.LookupBotOwner(player.GetPlayerId())})
+	return owner != nil && h.rolesGrant(h.usableRoles(owner), permission)
+}
+
+// A BotHandler manages bot accounts and their api keys for the players that own them
+type BotHandler struct {
+	*AuthHandler
+	roles *RoleHandler
+	*fortress.Logger
+}
+
+// NewBotHandler constructs a new BotHandler
+func NewBotHandler(auth *AuthHandler, roles *RoleHandler, logger *fortress.Logger) *BotHandler {
+	return &BotHandler{auth, roles, logger}
+}
+
+// CreateBot creates a bot account owned by the player. This error gets passed back to the user/client
+func (h *BotHandler) CreateBot(owner *fortress.Player, name string) error {
+	if owner.IsBot() {
+		return fmt.Errorf("bots can not own bots")
+	}
+	if !validUsername.MatchString(name) {
+		return fmt.Errorf("bot names must be 3 to 24 letters, numbers, dashes or underscores")
+	}
+	if h.SqliteHandler.LookupPlayerIdByName(name) != "" {
+		return fmt.Errorf("the name %s is already in use", name)
+	}
+	if len(h.SqliteHandler.LookupBotIds(owner.GetPlayerId())) >= maxBotsPerOwner {
+		return fmt.Errorf("you already have %d bots, delete one first", maxBotsPerOwner)
+	}
+
+	bot := fortress.NewPlayer()
+	bot.SetIdentity(fortress.BotProvider, bot.GetPlayerId())
+	bot.SetName(name)
+	h.SqliteHandler.CreateNewPlayerDbRecord(bot)
+	h.SqliteHandler.CreateBotDbRecord(bot.GetPlayerId(), owner.GetPlayerId())
+	h.Logf("%s(%s) created bot %s(%s)", owner.GetName(), owner.GetPlayerId(), bot.GetName(), bot.GetPlayerId())
+	return nil
+}
+
+// DeleteBot deletes a bot of the player, along with its api keys and sessions. This error gets passed back to the user/client
+func (h *BotHandler) DeleteBot(owner *fortress.Player, name string) error {
+	bot, err := h.findOwnedBot(owner, name)
+	if err != nil {
+		return err
+	}
+
+	h.SqliteHandler.RevokeApiKeysOfBot(bot.GetPlayerId())
+	h.sessions.RevokeAll(bot.GetPlayerId(), "", "the bot was deleted")
+	h.RemoveOnlinePlayer(bot.GetPlayerId())
+	h.SqliteHandler.DeleteBotDbRecords(bot.GetPlayerId())
+	h.Logf("%s(%s) deleted bot %s(%s)", owner.GetName(), owner.GetPlayerId(), bot.GetName(), bot.GetPlayerId())
+	return nil
+}
+
+// ListBots describes the bots the player owns
+func (h *BotHandler) ListBots(owner *fortress.Player) []commands.BotInfo {
+	infos := make([]commands.BotInfo, 0)
+	for _, id := range h.SqliteHandler.LookupBotIds(owner.GetPlayerId()) {
+		if bot := h.FindPlayer(id); bot != nil {
+			infos = append(infos, commands.BotInfo{Name: bot.GetName(), Id: id, Keys: len(h.SqliteHandler.LookupApiKeys(id))})
+		}
+	}
+	return infos
+}
+
+// CreateApiKey makes a new api key for a bot of the player, limited to the given permissions. The owner must have every
+// permission they give the key. It returns the key, which is not stored and can't be shown again.
+// This error gets passed back to the user/client
+func (h *BotHandler) CreateApiKey(owner *fortress.Player, botName string, permissions []string) (string, error) {
+	bot, err := h.findOwnedBot(owner, botName)
+	if err != nil {
+		return "", err
+	}
+	for _, permission := range permissions {
+		if !h.roles.HasPermission(owner, permission) {
+			return "", fmt.Errorf("you can not give a key the %s permission because you do not have it", permission)
+		}
+	}
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		h.Errorf("could not make an api key for bot %s: %s", bot.GetPlayerId(), err)
+		return "", fmt.Errorf("could not make an api key, please try again")
+	}
+	secret, hash := generateRefreshToken()
+	key := &ApiKey{keyId: hex.EncodeToString(b), botId: bot.GetPlayerId(), keyHash: hash, permissions: permissions, createdAt: time.Now().UTC()}
+	h.SqliteHandler.CreateApiKeyDbRecord(key)
+	h.Logf("%s(%s) created api key %s for bot %s with permissions %s", owner.GetName(), owner.GetPlayerId(), key.keyId, bot.GetName(), strings.Join(permissions, ","))
+
+	return apiKeyPrefix + key.keyId + "." + secret, nil
+}
+
+// ListApiKeys describes the api keys of a bot of the player that have not been revoked. This error gets passed back to the user/client
+func (h *BotHandler) ListApiKeys(owner *fortress.Player, botName string) ([]commands.ApiKeyInfo, error) {
+	bot, err := h.findOwnedBot(owner, botName)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]commands.ApiKeyInfo, 0)
+	for _, k := range h.SqliteHandler.LookupApiKeys(bot.GetPlayerId()) {
+		infos = append(infos, commands.ApiKeyInfo{Id: k.keyId, Permissions: k.permissions, CreatedAt: k.createdAt, LastUsedAt: k.lastUsedAt})
+	}
+	return infos, nil
+}
+
+// RevokeApiKey revokes an api key of one of the player's bots and ends the sessions it started. This error gets passed back to the user/client
+func (h *BotHandler) RevokeApiKey(owner *fortress.Player, keyId string) error {
+	key := h.SqliteHandler.LookupApiKey(keyId)
+	if key == nil || !key.revokedAt.IsZero() {
+		return fmt.Errorf("there is no api key %s", keyId)
+	}
+	if _, err := h.findOwnedBot(owner, key.botId); err != nil {
+		return fmt.Errorf("there is no api key %s", keyId)
+	}
+
+	h.SqliteHandler.RevokeApiKey(keyId)
+	for _, session := range h.sessions.GetActiveSessions(key.botId) {
+		if session.GetApiKeyId() == keyId {
+			h.sessions.Revoke(session.GetSessionId(), "the api key was revoked")
+		}
+	}
+	h.Logf("%s(%s) revoked api key %s", owner.GetName(), owner.GetPlayerId(), keyId)
+	return nil
+}
+
+// findOwnedBot returns the bot with the given name or id if the player owns it. Admins can manage every bot
+func (h *BotHandler) findOwnedBot(owner *fortress.Player, nameOrId string) (*fortress.Player, error) {
+	bot := h.FindPlayer(nameOrId)
+	if bot == nil || !bot.IsBot() {
+		return nil, fmt.Errorf("there is no bot named %s", nameOrId)
+	}
+	if h.SqliteHandler.LookupBotOwner(bot.GetPlayerId()) != owner.GetPlayerId() && !h.roles.HasPermission(owner, AllPermissions) {
+		return nil, fmt.Errorf("%s is not your bot", bot.GetName())
+	}
+	return bot, nil
+}
+
+func (h *SqliteHandler) initializeBotsTables() {
+	_, err := h.db.Exec("CREATE TABLE IF NOT EXISTS bots (" +
+		"player_id TEXT PRIMARY KEY, " +
+		"owner_id TEXT, " +
+		"created_at INTEGER)")
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+
+	_, err = h.db.Exec("CREATE TABLE IF NOT EXISTS api_keys (" +
+		"key_id TEXT PRIMARY KEY, " +
+		"bot_id TEXT, " +
+		"key_hash TEXT, " +
+		"permissions TEXT, " +
+		"created_at INTEGER, " +
+		"last_used_at INTEGER, " +
+		"revoked_at INTEGER)")
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+}
+
+func (h *SqliteHandler) CreateBotDbRecord(botId string, ownerId string) {
+	_, err := h.db.Exec("INSERT INTO bots (player_id, owner_id, created_at) VALUES (?, ?, ?)", botId, ownerId, time.Now().UTC().Unix())
+	if err != nil {
+		h.Errorf("SQL: could not create bot %s: %s", botId, err)
+	}
+}
+
+// DeleteBotDbRecords deletes the bot and its player record
+func (h *SqliteHandler) DeleteBotDbRecords(botId string) {
+	if _, err := h.db.Exec("DELETE FROM bots WHERE player_id = ?", botId); err != nil {
+		h.Errorf("SQL: could not delete bot %s: %s", botId, err)
+	}
+	if _, err := h.db.Exec("DELETE FROM players WHERE player_id = ?", botId); err != nil {
+		h.Errorf("SQL: could not delete player record of bot %s: %s", botId, err)
+	}
+}
+
+// LookupBotOwner returns the player id of the bot's owner, or "" if there is no such bot
+func (h *SqliteHandler) LookupBotOwner(botId string) string {
+	var ownerId string
+	err := h.db.QueryRow("SELECT owner_id FROM bots WHERE player_id = ?", botId).Scan(&ownerId)
+	if err != nil && err != sql.ErrNoRows {
+		h.Errorf("SQL: could not look up owner of bot %s: %s", botId, err)
+	}
+	return ownerId
+}
+
+// LookupBotIds returns the player ids of the bots the player owns
+func (h *SqliteHandler) LookupBotIds(ownerId string) []string {
+	rows, err := h.db.Query("SELECT player_id FROM bots WHERE owner_id = ? ORDER BY created_at", ownerId)
+	if err != nil {
+		h.Errorf("SQL: could not look up bots of player %s: %s", ownerId, err)
+		return nil
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			h.Errorf("SQL: %s", err)
+			return nil
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (h *SqliteHandler) CreateApiKeyDbRecord(k *ApiKey) {
+	_, err := h.db.Exec("INSERT INTO api_keys (key_id, bot_id, key_hash, permissions, created_at, last_used_at, revoked_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		k.keyId, k.botId, k.keyHash, strings.Join(k.permissions, ","), k.createdAt.Unix(), unixOrZero(k.lastUsedAt), unixOrZero(k.revokedAt))
+	if err != nil {
+		h.Errorf("SQL: could not create api key %s: %s", k.keyId, err)
+	}
+}
+
+// TouchApiKey records that the key was just used
+func (h *SqliteHandler) TouchApiKey(keyId string) {
+	if _, err := h.db.Exec("UPDATE api_keys SET last_used_at = ? WHERE key_id = ?", time.Now().UTC().Unix(), keyId); err != nil {
+		h.Errorf("SQL: could not update api key %s: %s", keyId, err)
+	}
+}
+
+func (h *SqliteHandler) RevokeApiKey(keyId string) {
+	if _, err := h.db.Exec("UPDATE api_keys SET revoked_at = ? WHERE key_id = ?", time.Now().UTC().Unix(), keyId); err != nil {
+		h.Errorf("SQL: could not revoke api key %s: %s", keyId, err)
+	}
+}
+
+func (h *SqliteHandler) RevokeApiKeysOfBot(botId string) {
+	if _, err := h.db.Exec("UPDATE api_keys SET revoked_at = ? WHERE bot_id = ? AND revoked_at = 0", time.Now().UTC().Unix(), botId); err != nil {
+		h.Errorf("SQL: could not revoke api keys of bot %s: %s", botId, err)
+	}
+}
+
+// LookupApiKey returns the api key with the given id, or nil if there is no such key
+func (h *SqliteHandler) LookupApiKey(keyId string) *ApiKey {
+	row := h.db.QueryRow("SELECT key_id, bot_id, key_hash, permissions, created_at, last_used_at, revoked_at FROM api_keys WHERE key_id = ?", keyId)
+	k, err := scanApiKey(row)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		h.Errorf("SQL: could not load api key %s: %s", keyId, err)
+		return nil
+	}
+	return k
+}
+
+// LookupApiKeys returns the api keys of the bot that have not been revoked, oldest first
+func (h *SqliteHandler) LookupApiKeys(botId string) []*ApiKey {
+	rows, err := h.db.Query("SELECT key_id, bot_id, key_hash, permissions, created_at, last_used_at, revoked_at FROM api_keys "+
+		"WHERE bot_id = ? AND revoked_at = 0 ORDER BY created_at", botId)
+	if err != nil {
+		h.Errorf("SQL: could not look up api keys of bot %s: %s", botId, err)
+		return nil
+	}
+	defer rows.Close()
+
+	keys := make([]*ApiKey, 0)
+	for rows.Next() {
+		k, err := scanApiKey(rows)
+		if err != nil {
+			h.Errorf("SQL: %s", err)
+			return nil
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// scanApiKey reads an api key from a row selected with every column of the api_keys table, in order
+func scanApiKey(row interface{ Scan(...any) error }) (*ApiKey, error) {
+	k := &ApiKey{}
+	var permissions string
+	var created, used, revoked int64
+	if err := row.Scan(&k.keyId, &k.botId, &k.keyHash, &permissions, &created, &used, &revoked); err != nil {
+		return nil, err
+	}
+	k.permissions = make([]string, 0)
+	if permissions != "" {
+		k.permissions = strings.Split(permissions, ",")
+	}
+	k.createdAt = time.Unix(created, 0)
+	k.lastUsedAt = timeOrZero(used)
+	k.revokedAt = timeOrZero(revoked)
+	return k, nil
+}
@@ -3,75 +3,239 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/cheracc/fortress-grpc"
 	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
 	purgeInterval = 1 * time.Minute
+	// room in a chat stream's queue beyond its backfill, for the join messages and chat that arrive before it starts sending
+	chatQueueHeadroom = 32
 )
 
+var channelNamePattern = regexp.MustCompile(`^[a-z0-9_-]{2,24}$`)
+
 type ChatHandler struct {
 	fgrpc.UnimplementedChatServer
 	*AuthHandler
 	*fortress.Logger
-	*ChatChannel
+	*ChatChannels
+	roles   *RoleHandler
+	filters []ChatFilter // every chat message and whisper goes through these, in order
+}
+
+// ChatChannels holds the chat channels by name, and every member (an open chat stream) whatever channels it is in. A member can
+// be in several channels at once. The channels from the config always exist, the ones players make are removed once they are
+// empty. Its methods are thread-safe
+type ChatChannels struct {
+	*sync.RWMutex
+	channels     map[string]*ChatChannel
+	members      []*ChannelMember
+	clearHistory func(name string) // called with the lock held when a player made channel is made or removed
+}
+
+// addStream keeps track of a newly opened chat stream, before it has joined any channels
+func (c *ChatChannels) addStream(m *ChannelMember) {
+	c.Lock()
+	c.members = append(c.members, m)
+	c.Unlock()
+}
+
+// joinChannel adds the member to the named channel. If there is no such channel it is made when create is true. It returns whether
+// the channel was made
+func (c *ChatChannels) joinChannel(name string, m *ChannelMember, create bool) (bool, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	channel, ok := c.channels[name]
+	if !ok {
+		if !create {
+			return false, status.Errorf(codes.NotFound, "there is no channel named %s", name)
+		}
+		channel = &ChatChannel{&sync.RWMutex{}, name, false, make([]*ChannelMember, 0)}
+		c.channels[name] = channel
+		c.clearHistory(name) // left over from an earlier channel with the same name, such as one from before a restart
+	}
+	if channel.hasMember(m) {
+		return false, status.Errorf(codes.AlreadyExists, "you are already in %s", name)
+	}
+	channel.addMember(m)
+	return !ok, nil
+}
+
+// leaveChannel removes the member from the named channel, and removes the channel if that left it empty and it is not permanent
+func (c *ChatChannels) leaveChannel(name string, m *ChannelMember) error {
+	c.Lock()
+	defer c.Unlock()
+
+	channel, ok := c.channels[name]
+	if !ok || !channel.removeMember(m) {
+		return status.Errorf(codes.NotFound, "you are not in %s", name)
+	}
+	c.removeIfEmpty(channel)
+	return nil
+}
+
+// closeStream closes the member, which ends its chat stream, and removes it from every channel it is in
+func (c *ChatChannels) closeStream(m *ChannelMember) {
+	c.Lock()
+	defer c.Unlock()
+
+	m.close()
+	c.members = slices.DeleteFunc(c.members, func(other *ChannelMember) bool { return other == m })
+	for _, channel := range c.channels {
+		if channel.removeMember(m) {
+			c.removeIfEmpty(channel)
+		}
+	}
+}
+
+// removeIfEmpty removes a player made channel, and its history, once nobody is in it. The caller must hold the lock
+func (c *ChatChannels) removeIfEmpty(channel *ChatChannel) {
+	if !channel.permanent && channel.size() == 0 {
+		delete(c.channels, channel.name)
+		c.clearHistory(channel.name)
+	}
+}
+
+// getChannel returns the named channel, or nil if there is none
+func (c *ChatChannels) getChannel(name string) *ChatChannel {
+	c.RLock()
+	defer c.RUnlock()
+	return c.channels[name]
+}
+
+// sessionStreams returns the open chat streams of the session. A session normally has one
+func (c *ChatChannels) sessionStreams(sessionId string) []*ChannelMember {
+	c.RLock()
+	defer c.RUnlock()
+	streams := make([]*ChannelMember, 0)
+	for _, m := range c.members {
+		if m.sessionId == sessionId {
+			streams = append(streams, m)
+		}
+	}
+	return streams
+}
+
+// playerStreams returns the open chat streams of every session the player has
+func (c *ChatChannels) playerStreams(playerId string) []*ChannelMember {
+	c.RLock()
+	defer c.RUnlock()
+	streams := make([]*ChannelMember, 0)
+	for _, m := range c.members {
+		if m.playerId == playerId {
+			streams = append(streams, m)
+		}
+	}
+	return streams
+}
+
+// sortedChannels returns every channel, in order of name
+func (c *ChatChannels) sortedChannels() []*ChatChannel {
+	c.RLock()
+	defer c.RUnlock()
+	channels := make([]*ChatChannel, 0, len(c.channels))
+	for _, channel := range c.channels {
+		channels = append(channels, channel)
+	}
+	slices.SortFunc(channels, func(a, b *ChatChannel) int { return strings.Compare(a.name, b.name) })
+	return channels
 }
 
 // ChatChannel represents a chat channel. Its methods are thread-safe
 type ChatChannel struct {
 	*sync.RWMutex
-	members []*ChannelMember
+	name      string
+	permanent bool // one of the default channels from the config, which are kept when they are empty
+	members   []*ChannelMember
 }
 
-func (c *ChatChannel) addMember(m *ChannelMember, playerName string) {
-	c.sendMessage(fmt.Sprintf("%s has joined chat", playerName), "SERVER") // send this before adding the player so the new player doesn't get it (they already get their own msg)
+func (c *ChatChannel) addMember(m *ChannelMember) {
+	c.sendMessage(fmt.Sprintf("%s has joined %s", m.playerName, c.name), "SERVER") // send this before adding the player so the new player doesn't get it (they already get their own msg)
 	c.Lock()
 	c.members = append(c.members, m)
 	c.Unlock()
 }
 func (c *ChatChannel) sendMessage(message string, playerName string) {
+	c.broadcast(&fgrpc.ChatMessage{Message: message, SendingPlayerName: playerName, ChannelName: c.name})
+}
+
+// broadcast queues the message for every member. It does not wait for any of them to send it
+func (c *ChatChannel) broadcast(msg *fgrpc.ChatMessage) {
 	c.RLock()
 	for _, m := range c.members {
-		m.stream.Send(&fgrpc.ChatMessage{Message: message, SendingPlayerName: playerName})
+		m.send(msg)
 	}
 	c.RUnlock()
 }
-func (c *ChatChannel) removeMember(playerId string) {
-	toRemove := -1
+
+// removeMember removes the member and tells the rest of the channel. It returns whether the member was in the channel
+func (c *ChatChannel) removeMember(m *ChannelMember) bool {
 	c.Lock()
-	for i, m := range c.members {
-		if m.playerId == playerId {
-			toRemove = i
-			m.closed = true
-			break
-		}
+	i := slices.Index(c.members, m)
+	if i >= 0 {
+		c.members = slices.Delete(c.members, i, i+1)
 	}
 	c.Unlock()
 
-	if toRemove >= 0 {
-		c.Lock()
-		c.members = slices.Delete(c.members, toRemove, toRemove+1)
-		c.Unlock()
-		c.sendMessage(fmt.Sprintf("%s has left chat", playerId), "SERVER")
+	if i >= 0 {
+		c.sendMessage(fmt.Sprintf("%s has left %s", m.playerName, c.name), "SERVER")
 	}
+	return i >= 0
+}
+func (c *ChatChannel) hasMember(m *ChannelMember) bool {
+	c.RLock()
+	defer c.RUnlock()
+	return slices.Contains(c.members, m)
 }
 
-type ChannelMember struct {
-	playerId string
-	stream   fgrpc.Chat_JoinChannelServer
-	closed   bool
+// hasSession returns whether any of the session's chat streams is in the channel
+func (c *ChatChannel) hasSession(sessionId string) bool {
+	c.RLock()
+	defer c.RUnlock()
+	return slices.ContainsFunc(c.members, func(m *ChannelMember) bool { return m.sessionId == sessionId })
+}
+func (c *ChatChannel) size() int {
+	c.RLock()
+	defer c.RUnlock()
+	return len(c.members)
 }
 
-func NewChatHandler(logger *fortress.Logger, auth *AuthHandler) *ChatHandler {
+func NewChatHandler(logger *fortress.Logger, auth *AuthHandler, roles *RoleHandler) *ChatHandler {
 	h := &ChatHandler{fgrpc.UnimplementedChatServer{},
 		auth,
 		logger,
-		&ChatChannel{&sync.RWMutex{}, make([]*ChannelMember, 0)}}
+		&ChatChannels{&sync.RWMutex{}, make(map[string]*ChatChannel), make([]*ChannelMember, 0), nil},
+		roles,
+		buildChatFilters(auth.config, logger)}
+
+	for _, name := range auth.config.ChatChannels {
+		name = strings.ToLower(name)
+		if !channelNamePattern.MatchString(name) {
+			logger.Errorf("Skipping chat channel %s from the config, channel names are 2 to 24 letters, numbers, dashes or underscores", name)
+			continue
+		}
+		h.channels[name] = &ChatChannel{&sync.RWMutex{}, name, true, make([]*ChannelMember, 0)}
+	}
+	h.checkQueueConfig()
+	h.ChatChannels.clearHistory = func(name string) {
+		if removed := h.SqliteHandler.DeleteChatMessages(name); removed > 0 {
+			h.Logf("Removed %d chat messages of channel %s", removed, name)
+		}
+	}
+
+	auth.sessions.OnRevoke(func(session *Session, reason string) {
+		h.removeSession(session.GetSessionId(), reason)
+	})
 
 	go func() {
 		time.Sleep(20 * time.Second)
@@ -80,52 +244,267 @@ func NewChatHandler(logger *fortress.Logger, auth *AuthHandler) *ChatHandler {
 			time.Sleep(purgeInterval)
 		}
 	}()
+	h.startChatHistorySweeper()
 
 	return h
 }
 
+// checkQueueConfig falls back to ChatPolicyDrop if the config names an unknown slow stream policy, and makes sure a new stream's
+// queue can hold the backfill of every default channel, which is queued before the stream starts sending
+func (h *ChatHandler) checkQueueConfig() {
+	if h.config.ChatSlowStreamPolicy != ChatPolicyDrop && h.config.ChatSlowStreamPolicy != ChatPolicyDisconnect {
+		h.Errorf("Unknown chat slow stream policy %s, expected %s or %s. Using %s", h.config.ChatSlowStreamPolicy, ChatPolicyDrop, ChatPolicyDisconnect, ChatPolicyDrop)
+		h.config.ChatSlowStreamPolicy = ChatPolicyDrop
+	}
+
+	backfill := max(h.config.ChatBackfill, 0) * len(h.channels)
+	if h.config.ChatQueueSize < backfill+chatQueueHeadroom {
+		h.Logf("Raising the chat queue size from %d to %d to fit the backfill of the default channels", h.config.ChatQueueSize, backfill+chatQueueHeadroom)
+		h.config.ChatQueueSize = backfill + chatQueueHeadroom
+	}
+}
+
+// JoinChannel is the gRPC server function that opens a chat stream. The stream starts in the requested channel, or in every
+// default channel if the request does not name one
 func (h *ChatHandler) JoinChannel(req *fgrpc.ChatRequest, stream fgrpc.Chat_JoinChannelServer) error {
-	playerId := h.GetPlayerIdFromTokenString(req.GetSessionToken())
+	claims := claimsFromContext(stream.Context()) // set by the auth interceptor once it has verified the session token
 
-	if playerId == "" {
+	if claims == nil {
 		return h.Error("invalid or expired session token")
 	}
+	player := fortress.PlayerFromContext(stream.Context())
 
-	member := &ChannelMember{playerId, stream, false}
-	h.addMember(member, h.GetPlayerNameFromId(playerId, false))
-	for {
-		if member.closed {
-			break
+	member := newChannelMember(claims.PlayerID, claims.SessionID, player.GetDisplayName(), stream, h.config.ChatQueueSize, h.config.ChatSlowStreamPolicy)
+	h.addStream(member)
+	if req.GetChannelName() != "" {
+		if err := h.enterChannel(player, member, req.GetChannelName()); err != nil {
+			h.closeStream(member)
+			return err
+		}
+	} else {
+		for _, channel := range h.sortedChannels() {
+			if !channel.permanent {
+				continue
+			}
+			if _, err := h.joinChannel(channel.name, member, false); err == nil {
+				h.backfill(member, channel.name)
+			}
 		}
-		time.Sleep(2 * time.Second)
 	}
+
+	err := member.serve()
+	h.closeStream(member)
+	if n := member.dropped.Load(); n > 0 {
+		h.Logf("Chat stream of player %s(%s) dropped %d messages it could not keep up with", player.GetName(), player.GetPlayerId(), n)
+	}
+	return err
+}
+
+// Join is the gRPC server function that adds the session's open chat stream to a channel, making the channel if it does not exist
+func (h *ChatHandler) Join(ctx context.Context, req *fgrpc.ChatRequest) (*fgrpc.ChannelList, error) {
+	player, streams, err := h.chatStreams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range streams {
+		if err := h.enterChannel(player, m, req.GetChannelName()); err != nil {
+			return nil, err
+		}
+	}
+	return h.channelList(streams[0].sessionId), nil
+}
+
+// Leave is the gRPC server function that removes the session's open chat stream from a channel
+func (h *ChatHandler) Leave(ctx context.Context, req *fgrpc.ChatRequest) (*fgrpc.ChannelList, error) {
+	_, streams, err := h.chatStreams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.ToLower(req.GetChannelName())
+	for _, m := range streams {
+		if err := h.leaveChannel(name, m); err != nil {
+			return nil, err
+		}
+	}
+	return h.channelList(streams[0].sessionId), nil
+}
+
+// ListChannels is the gRPC server function that lists every chat channel, and which of them the session is in
+func (h *ChatHandler) ListChannels(ctx context.Context, _ *fgrpc.Empty) (*fgrpc.ChannelList, error) {
+	claims := claimsFromContext(ctx) // set by the auth interceptor once it has verified the session token
+	if claims == nil {
+		return nil, h.Errorf("invalid or expired session token")
+	}
+	return h.channelList(claims.SessionID), nil
+}
+
+// chatStreams returns the player and the open chat streams of the calling session. The error is passed back to the client
+func (h *ChatHandler) chatStreams(ctx context.Context) (*fortress.Player, []*ChannelMember, error) {
+	player := fortress.PlayerFromContext(ctx) // set by the auth interceptor once it has verified the session token
+	claims := claimsFromContext(ctx)
+	if player == nil || claims == nil {
+		return nil, nil, h.Errorf("invalid or expired session token")
+	}
+	streams := h.sessionStreams(claims.SessionID)
+	if len(streams) == 0 {
+		return nil, nil, status.Error(codes.FailedPrecondition, "chat is not open, open it before joining or leaving channels")
+	}
+	return player, streams, nil
+}
+
+// enterChannel adds the member to the named channel. Players that can chat make the channel if it does not exist, everyone else
+// can only join channels that do
+func (h *ChatHandler) enterChannel(player *fortress.Player, m *ChannelMember, name string) error {
+	name = strings.ToLower(name)
+	if !channelNamePattern.MatchString(name) {
+		return status.Error(codes.InvalidArgument, "channel names are 2 to 24 letters, numbers, dashes or underscores")
+	}
+	created, err := h.joinChannel(name, m, h.roles.HasSessionPermission(player, m.sessionId, PermissionChat))
+	if err != nil {
+		return err
+	}
+	if created {
+		h.Logf("Player %s(%s) made chat channel %s", player.GetName(), player.GetPlayerId(), name)
+	}
+	h.backfill(m, name)
 	return nil
 }
 
-// ChatHandler.SendMessage is the gRPC server function that receives chat messages from clients
+// channelList describes every channel, and whether the session is in it
+func (h *ChatHandler) channelList(sessionId string) *fgrpc.ChannelList {
+	list := &fgrpc.ChannelList{}
+	for _, channel := range h.sortedChannels() {
+		list.Channels = append(list.Channels, &fgrpc.ChannelInfo{
+			Name:      channel.name,
+			Members:   int32(channel.size()),
+			Joined:    channel.hasSession(sessionId),
+			Permanent: channel.permanent,
+		})
+	}
+	return list
+}
+
+// ChatHandler.SendMessage is the gRPC server function that receives chat messages from clients. The message goes to the channel it
+// names, or the first default channel if it names none, and only a session that is in the channel can send to it
 func (h *ChatHandler) SendMessage(ctx context.Context, msg *fgrpc.ChatMessage) (*fgrpc.Empty, error) {
-	playerId := h.GetPlayerIdFromTokenString(msg.GetSessionToken())
+	player, sessionId, err := h.checkCanChat(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	if playerId == "" {
-		return nil, h.Errorf("invalid or expired session token")
+	name := h.channelOrDefault(msg.GetChannelName())
+	channel := h.getChannel(name)
+	if channel == nil || !channel.hasSession(sessionId) {
+		return nil, status.Errorf(codes.FailedPrecondition, "you are not in channel %s, join it first", name)
+	}
+	text, err := h.filterMessage(player, name, msg.GetMessage())
+	if err != nil {
+		return nil, err
 	}
 
-	h.ChatChannel.sendMessage(msg.GetMessage(), h.GetPlayerNameFromId(playerId, false))
+	stored := &StoredChatMessage{channel: name, sentAt: time.Now().UTC(), playerId: player.GetPlayerId(), playerName: player.GetDisplayName(), message: text}
+	h.SqliteHandler.SaveChatMessage(stored)
+	channel.broadcast(stored.toChatMessage(false))
 	return nil, nil
 }
 
+// channelOrDefault returns the channel name in lower case, or the first default channel if it is empty (such as from clients that
+// are older than channels)
+func (h *ChatHandler) channelOrDefault(name string) string {
+	if name == "" && len(h.config.ChatChannels) > 0 {
+		return strings.ToLower(h.config.ChatChannels[0])
+	}
+	return strings.ToLower(name)
+}
+
+// Whisper is the gRPC server function that sends a message to one player, by name or id. It goes to each of the recipient's chat
+// streams and is echoed to each of the sender's, so the sender's other devices see it too
+func (h *ChatHandler) Whisper(ctx context.Context, req *fgrpc.WhisperRequest) (*fgrpc.Empty, error) {
+	player, _, err := h.checkCanChat(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recipient := h.FindPlayer(req.GetRecipient())
+	if recipient == nil {
+		return nil, status.Errorf(codes.NotFound, "there is no player named %s", req.GetRecipient())
+	}
+	if recipient.GetPlayerId() == player.GetPlayerId() {
+		return nil, status.Error(codes.InvalidArgument, "you can't whisper to yourself")
+	}
+	streams := h.playerStreams(recipient.GetPlayerId())
+	if len(streams) == 0 {
+		if !h.IsOnline(PlayerFilter{playerId: recipient.GetPlayerId()}) || len(h.sessions.GetActiveSessions(recipient.GetPlayerId())) == 0 {
+			return nil, status.Errorf(codes.FailedPrecondition, "%s is not online", recipient.GetDisplayName())
+		}
+		return nil, status.Errorf(codes.FailedPrecondition, "%s does not have chat open", recipient.GetDisplayName())
+	}
+
+	text, err := h.filterMessage(player, "", req.GetMessage())
+	if err != nil {
+		return nil, err
+	}
+
+	whisper := &fgrpc.ChatMessage{
+		Message:           text,
+		SendingPlayerName: player.GetDisplayName(),
+		SendingPlayerId:   player.GetPlayerId(),
+		RecipientName:     recipient.GetDisplayName(),
+		RecipientId:       recipient.GetPlayerId(),
+	}
+	for _, m := range append(streams, h.playerStreams(player.GetPlayerId())...) {
+		m.send(whisper)
+	}
+	return &fgrpc.Empty{}, nil
+}
+
+// checkCanChat returns the calling player and their session id if they may send chat messages: they have accepted the terms,
+// have the chat permission and are not muted (bots are muted with their owner). The error is passed back to the client
+func (h *ChatHandler) checkCanChat(ctx context.Context) (*fortress.Player, string, error) {
+	player := fortress.PlayerFromContext(ctx) // set by the auth interceptor once it has verified the session token
+
+	if player == nil {
+		return nil, "", h.Errorf("invalid or expired session token")
+	}
+	if err := h.checkAcceptedTerms(player); err != nil {
+		return nil, "", err
+	}
+	sessionId := ""
+	if claims := claimsFromContext(ctx); claims != nil {
+		sessionId = claims.SessionID
+	}
+	if !h.roles.HasSessionPermission(player, sessionId, PermissionChat) {
+		if player.IsGuest() {
+			return nil, "", status.Error(codes.PermissionDenied, "guests can only read chat, use 'link <provider>' to make a full account")
+		}
+		return nil, "", status.Error(codes.PermissionDenied, "you do not have permission to chat")
+	}
+	if mute := h.activeSanction(player, SanctionMute); mute != nil {
+		return nil, "", status.Error(codes.PermissionDenied, mute.describeTo(player))
+	}
+	return player, sessionId, nil
+}
+
+// removeSession tells the chat streams that belong to the session why they are being closed, then closes them
+func (h *ChatHandler) removeSession(sessionId string, reason string) {
+	for _, m := range h.sessionStreams(sessionId) {
+		m.send(&fgrpc.ChatMessage{Message: fmt.Sprintf("Your session has ended: %s", reason), SendingPlayerName: "SERVER"})
+		h.closeStream(m)
+	}
+}
+
 // PurgeInactives will check each channel member to see if they are still online, then remove those who are not
 func (h *ChatHandler) PurgeInactives() {
-	inactives := []string{}
-	h.RLock()
+	inactives := []*ChannelMember{}
+	h.ChatChannels.RLock()
 	for _, m := range h.members {
 		if !h.IsOnline(PlayerFilter{playerId: m.playerId}) {
-			inactives = append(inactives, m.playerId)
+			inactives = append(inactives, m)
 		}
 	}
-	h.RUnlock()
-	for _, id := range inactives {
-		h.Logf("Removing %s from chat for inactivity", id)
-		h.removeMember(id)
+	h.ChatChannels.RUnlock()
+	for _, m := range inactives {
+		h.Logf("Removing %s from chat for inactivity", m.playerId)
+		h.closeStream(m)
 	}
 }
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cheracc/fortress-grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// the names of the built in chat filters, which config.ChatFilters lists in the order they run
+const (
+	ChatFilterControls  = "controls"
+	ChatFilterLength    = "length"
+	ChatFilterBlocklist = "blocklist"
+	ChatFilterLinks     = "links"
+)
+
+var (
+	// ANSI escape sequences: CSI sequences such as colours and cursor movement, OSC sequences such as window titles (ended by BEL
+	// or ST), and the two character escapes
+	ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)?|\x1b[@-Z\\-_]`)
+	linkPattern       = regexp.MustCompile(`(?i)\b([a-z][a-z0-9+.-]*://|www\.)\S+`)
+)
+
+// FilteredMessage is a chat message on its way through the chat filters. Filters can rewrite Text, and flag the message for the
+// moderators. Channel is "" for whispers
+type FilteredMessage struct {
+	Text    string
+	Sender  *fortress.Player
+	Channel string
+	Flags   []string
+}
+
+// Flag marks the message for the moderators with the reason. A flagged message is still sent, and goes in the audit log
+func (m *FilteredMessage) Flag(reason string) {
+	m.Flags = append(m.Flags, reason)
+}
+
+// A ChatFilter checks, and can rewrite or flag, a chat message before it is sent. It returns an error with the reason if the message
+// should not be sent at all. This error gets passed back to the sender
+type ChatFilter interface {
+	Filter(m *FilteredMessage) error
+}
+
+// ChatFilterFunc lets an ordinary function be used as a ChatFilter
+type ChatFilterFunc func(m *FilteredMessage) error
+
+func (f ChatFilterFunc) Filter(m *FilteredMessage) error {
+	return f(m)
+}
+
+// AddFilter adds a filter to the end of the chain that every chat message and whisper goes through. It is meant to be called
+// while the server starts, before any messages are sent
+func (h *ChatHandler) AddFilter(filter ChatFilter) {
+	h.filters = append(h.filters, filter)
+}
+
+// filterMessage runs the text through every filter in order, and returns the text to send. Flagged messages are logged and added
+// to the audit log. The error is passed back to the sender
+func (h *ChatHandler) filterMessage(player *fortress.Player, channel string, text string) (string, error) {
+	m := &FilteredMessage{Text: text, Sender: player, Channel: channel}
+	for _, filter := range h.filters {
+		if err := filter.Filter(m); err != nil {
+			return "", status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	if len(m.Flags) > 0 {
+		where := "a whisper"
+		if channel != "" {
+			where = "channel " + channel
+		}
+		reasons := strings.Join(m.Flags, ", ")
+		h.Logf("Flagged chat message from %s(%s) in %s (%s): %s", player.GetName(), player.GetPlayerId(), where, reasons, m.Text)
+		h.SqliteHandler.RecordSecurityEvent(&SecurityEvent{kind: EventChatFlagged, playerId: player.GetPlayerId(),
+			detail: fmt.Sprintf("%s in %s: %s", reasons, where, m.Text)})
+	}
+	return m.Text, nil
+}
+
+// buildChatFilters returns the built in filters that the config names, in the order it names them
+func buildChatFilters(config *Config, logger *fortress.Logger) []ChatFilter {
+	filters := make([]ChatFilter, 0)
+	for _, name := range config.ChatFilters {
+		switch strings.ToLower(name) {
+		case ChatFilterControls:
+			filters = append(filters, ChatFilterFunc(stripControlCharacters))
+		case ChatFilterLength:
+			filters = append(filters, NewLengthFilter(config.ChatMaxLength))
+		case ChatFilterBlocklist:
+			if len(config.ChatBlockedWords) > 0 {
+				filters = append(filters, NewBlocklistFilter(config.ChatBlockedWords))
+			}
+		case ChatFilterLinks:
+			filters = append(filters, ChatFilterFunc(blockLinks))
+		default:
+			logger.Errorf("Skipping unknown chat filter %s from the config", name)
+		}
+	}
+	return filters
+}
+
+// stripControlCharacters removes ANSI escape sequences and control characters, which the client would print straight to the
+// player's terminal, and invisible format characters such as zero width spaces, which could hide a blocked word. Tabs and line
+// breaks become spaces, so a message can not start a line of its own
+func stripControlCharacters(m *FilteredMessage) error {
+	text := ansiEscapePattern.ReplaceAllString(m.Text, "")
+	text = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return ' '
+		}
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, text)
+
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("there is nothing to send")
+	}
+	m.Text = text
+	return nil
+}
+
+// NewLengthFilter returns a filter that rejects messages longer than max characters
+func NewLengthFilter(max int) ChatFilter {
+	return ChatFilterFunc(func(m *FilteredMessage) error {
+		if max > 0 && utf8.RuneCountInString(m.Text) > max {
+			return fmt.Errorf("messages can be at most %d characters long", max)
+		}
+		return nil
+	})
+}
+
+// NewBlocklistFilter returns a filter that masks the words with asterisks wherever they appear as whole words, in any case, and
+// flags the message
+func NewBlocklistFilter(words []string) ChatFilter {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		if word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	// longest first, so that where one word starts with another the longer one is tried first
+	slices.SortFunc(quoted, func(a, b string) int { return len(b) - len(a) })
+	pattern := regexp.MustCompile(`(?i)(?:` + strings.Join(quoted, "|") + `)`)
+
+	return ChatFilterFunc(func(m *FilteredMessage) error {
+		masked := maskWholeWords(pattern, m.Text)
+		if masked != m.Text {
+			m.Text = masked
+			m.Flag("blocked word")
+		}
+		return nil
+	})
+}
+
+// maskWholeWords replaces every match of the pattern that is a whole word with asterisks. Regexp's \b only knows ASCII letters, so
+// the word boundaries are checked here, with isWordRune
+func maskWholeWords(pattern *regexp.Regexp, text string) string {
+	var masked strings.Builder
+	pos := 0
+	for pos < len(text) {
+		loc := pattern.FindStringIndex(text[pos:])
+		if loc == nil || loc[0] == loc[1] {
+			break
+		}
+		start, end := pos+loc[0], pos+loc[1]
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if (start == 0 || !isWordRune(before)) && (end == len(text) || !isWordRune(after)) {
+			masked.WriteString(text[pos:start])
+			masked.WriteString(strings.Repeat("*", utf8.RuneCountInString(text[start:end])))
+			pos = end
+			continue
+		}
+		// part of a longer word, look again from the next character
+		_, size := utf8.DecodeRuneInString(text[start:])
+		masked.WriteString(text[pos : start+size])
+		pos = start + size
+	}
+	masked.WriteString(text[pos:])
+	return masked.String()
+}
+
+// isWordRune returns whether the rune can be part of a word, in any language
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.Is(unicode.M, r) || r == '_'
+}
+
+// blockLinks rejects messages with links in them
+func blockLinks(m *FilteredMessage) error {
+	if linkPattern.MatchString(m.Text) {
+		return fmt.Errorf("links are not allowed in chat")
+	}
+	return nil
+}
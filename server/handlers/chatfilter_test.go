@@ -0,0 +1,53 @@
+package handlers
+
+import "testing"
+
+func TestBlocklistFilter(t *testing.T) {
+	filter := NewBlocklistFilter([]string{"bad", "badword", "ärger"})
+	tests := []struct {
+		text    string
+		want    string
+		flagged bool
+	}{
+		{"this is bad", "this is ***", true},
+		{"BAD bad,bad", "*** ***,***", true},
+		{"a badword here", "a ******* here", true},
+		{"badminton and abad", "badminton and abad", false},
+		{"bad_guy", "bad_guy", false},
+		{"ébad and badé", "ébad and badé", false}, // \b would see é as a boundary
+		{"日本bad", "日本bad", false},
+		{"kein Ärger!", "kein *****!", true},
+		{"Ärgerlich", "Ärgerlich", false},
+	}
+	for _, test := range tests {
+		m := &FilteredMessage{Text: test.text}
+		if err := filter.Filter(m); err != nil {
+			t.Fatalf("%q: %v", test.text, err)
+		}
+		if m.Text != test.want || (len(m.Flags) > 0) != test.flagged {
+			t.Errorf("%q: got %q flagged %v, want %q flagged %v", test.text, m.Text, len(m.Flags) > 0, test.want, test.flagged)
+		}
+	}
+}
+
+func TestStripControlCharacters(t *testing.T) {
+	tests := map[string]string{
+		"hello\x1b[31m red":     "hello red",
+		"line\none":             "line one",
+		"b\u200bad":             "bad",
+		"b\u200dad\u2060\ufeff": "bad",
+		"right\u202eto left":    "rightto left",
+	}
+	for text, want := range tests {
+		m := &FilteredMessage{Text: text}
+		if err := stripControlCharacters(m); err != nil {
+			t.Fatalf("%q: %v", text, err)
+		}
+		if m.Text != want {
+			t.Errorf("%q: got %q, want %q", text, m.Text, want)
+		}
+	}
+	if err := stripControlCharacters(&FilteredMessage{Text: "\u200b\u200b"}); err == nil {
+		t.Errorf("a message of only zero width spaces was not rejected")
+	}
+}
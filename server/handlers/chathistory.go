@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	chatHistorySweepInterval = 10 * time.Minute
+	// how many messages History returns when the client does not ask for a number, and the most it can ask for
+	chatHistoryPageSize = 50
+)
+
+// StoredChatMessage is a message a player sent to a channel, as it is kept in the channel's history. Server notices (such as
+// players joining) and whispers are not kept
+type StoredChatMessage struct {
+	id         int64
+	channel    string
+	sentAt     time.Time
+	playerId   string
+	playerName string
+	message    string
+}
+
+// toChatMessage returns the message as it is sent to clients. history marks it as an older message that is being sent again
+func (m *StoredChatMessage) toChatMessage(history bool) *fgrpc.ChatMessage {
+	return &fgrpc.ChatMessage{
+		Id:                m.id,
+		SentAt:            m.sentAt.Unix(),
+		ChannelName:       m.channel,
+		SendingPlayerName: m.playerName,
+		Message:           m.message,
+		History:           history,
+	}
+}
+
+// backfill sends the stream the channel's most recent messages, oldest first, so a player that joins or reconnects sees what was
+// said before they got there
+func (h *ChatHandler) backfill(m *ChannelMember, channel string) {
+	if h.config.ChatBackfill <= 0 {
+		return
+	}
+	messages := h.SqliteHandler.LookupChatMessages(channel, 0, h.config.ChatBackfill)
+	for i := len(messages) - 1; i >= 0; i-- {
+		m.send(messages[i].toChatMessage(true))
+	}
+}
+
+// History is the gRPC server function that returns a page of a channel's history, oldest first. Only a session that is in the
+// channel can read it
+func (h *ChatHandler) History(ctx context.Context, req *fgrpc.HistoryRequest) (*fgrpc.ChatHistory, error) {
+	claims := claimsFromContext(ctx) // set by the auth interceptor once it has verified the session token
+	if claims == nil {
+		return nil, h.Errorf("invalid or expired session token")
+	}
+	name := h.channelOrDefault(req.GetChannelName())
+	channel := h.getChannel(name)
+	if channel == nil || !channel.hasSession(claims.SessionID) {
+		return nil, status.Errorf(codes.FailedPrecondition, "you are not in channel %s, join it first", name)
+	}
+
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > chatHistoryPageSize {
+		limit = chatHistoryPageSize
+	}
+	messages := h.SqliteHandler.LookupChatMessages(name, req.GetBeforeId(), limit+1)
+	history := &fgrpc.ChatHistory{More: len(messages) > limit}
+	if history.More {
+		messages = messages[:limit]
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		history.Messages = append(history.Messages, messages[i].toChatMessage(true))
+	}
+	return history, nil
+}
+
+func (h *ChatHandler) startChatHistorySweeper() {
+	go func() {
+		for {
+			time.Sleep(chatHistorySweepInterval)
+			if removed := h.purgeChatHistory(); removed > 0 {
+				h.Logf("Removed %d old chat messages", removed)
+			}
+		}
+	}()
+}
+
+// purgeChatHistory deletes the messages that are older than config.ChatHistoryLifetime, then the oldest messages of every channel
+// that has more than its limit. It returns how many were deleted
+func (h *ChatHandler) purgeChatHistory() int64 {
+	removed := int64(0)
+	if h.config.ChatHistoryLifetime > 0 {
+		removed += h.SqliteHandler.DeleteChatMessagesBefore(time.Now().UTC().Add(-h.config.ChatHistoryLifetime))
+	}
+	for _, channel := range h.SqliteHandler.LookupChatHistoryChannels() {
+		if limit := h.config.HistoryLimit(channel); limit > 0 {
+			removed += h.SqliteHandler.TrimChatMessages(channel, limit)
+		}
+	}
+	return removed
+}
+
+func (h *SqliteHandler) initializeChatMessagesTable() {
+	_, err := h.db.Exec("CREATE TABLE IF NOT EXISTS chat_messages (" +
+		"id INTEGER PRIMARY KEY AUTOINCREMENT, " +
+		"channel TEXT, " +
+		"sent_at INTEGER, " +
+		"player_id TEXT, " +
+		"player_name TEXT, " +
+		"message TEXT)")
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+	if _, err := h.db.Exec("CREATE INDEX IF NOT EXISTS chat_messages_channel ON chat_messages (channel, id)"); err != nil {
+		h.Fatal(err.Error())
+	}
+}
+
+// SaveChatMessage adds the message to its channel's history and sets its id
+func (h *SqliteHandler) SaveChatMessage(m *StoredChatMessage) {
+	result, err := h.db.Exec("INSERT INTO chat_messages (channel, sent_at, player_id, player_name, message) VALUES (?, ?, ?, ?, ?)",
+		m.channel, m.sentAt.Unix(), m.playerId, m.playerName, m.message)
+	if err != nil {
+		h.Errorf("SQL: could not save chat message from player %s: %s", m.playerId, err)
+		return
+	}
+	m.id, _ = result.LastInsertId()
+}
+
+// LookupChatMessages returns up to limit of the channel's messages with ids below beforeId (or the newest if it is 0), newest first
+func (h *SqliteHandler) LookupChatMessages(channel string, beforeId int64, limit int) []*StoredChatMessage {
+	query := "SELECT id, channel, sent_at, player_id, player_name, message FROM chat_messages WHERE channel = ?"
+	args := []any{channel}
+	if beforeId > 0 {
+		query += " AND id < ?"
+		args = append(args, beforeId)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		h.Errorf("SQL: could not look up the history of channel %s: %s", channel, err)
+		return nil
+	}
+	defer rows.Close()
+
+	messages := make([]*StoredChatMessage, 0)
+	for rows.Next() {
+		m := &StoredChatMessage{}
+		var sentAt int64
+		if err := rows.Scan(&m.id, &m.channel, &sentAt, &m.playerId, &m.playerName, &m.message); err != nil {
+			h.Errorf("SQL: %s", err)
+			return nil
+		}
+		m.sentAt = time.Unix(sentAt, 0)
+		messages = append(messages, m)
+	}
+	return messages
+}
+
+// LookupChatHistoryChannels returns the name of every channel that has messages in its history
+func (h *SqliteHandler) LookupChatHistoryChannels() []string {
+	rows, err := h.db.Query("SELECT DISTINCT channel FROM chat_messages")
+	if err != nil {
+		h.Errorf("SQL: could not look up chat channels: %s", err)
+		return nil
+	}
+	defer rows.Close()
+
+	channels := make([]string, 0)
+	for rows.Next() {
+		var channel string
+		if err := rows.Scan(&channel); err != nil {
+			h.Errorf("SQL: %s", err)
+			return nil
+		}
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// TrimChatMessages deletes all but the newest keep messages of the channel, and returns how many were deleted
+func (h *SqliteHandler) TrimChatMessages(channel string, keep int) int64 {
+	result, err := h.db.Exec("DELETE FROM chat_messages WHERE channel = ? AND id <= "+
+		"(SELECT id FROM chat_messages WHERE channel = ? ORDER BY id DESC LIMIT 1 OFFSET ?)", channel, channel, keep)
+	if err != nil {
+		h.Errorf("SQL: could not trim the history of channel %s: %s", channel, err)
+		return 0
+	}
+	n, _ := result.RowsAffected()
+	return n
+}
+
+// DeleteChatMessages deletes the channel's whole history, and returns how many messages were deleted
+func (h *SqliteHandler) DeleteChatMessages(channel string) int64 {
+	result, err := h.db.Exec("DELETE FROM chat_messages WHERE channel = ?", channel)
+	if err != nil {
+		h.Errorf("SQL: could not delete the history of channel %s: %s", channel, err)
+		return 0
+	}
+	n, _ := result.RowsAffected()
+	return n
+}
+
+// DeleteChatMessagesBefore deletes every chat message sent before the cutoff, and returns how many were deleted
+func (h *SqliteHandler) DeleteChatMessagesBefore(cutoff time.Time) int64 {
+	result, err := h.db.Exec("DELETE FROM chat_messages WHERE sent_at < ?", cutoff.Unix())
+	if err != nil {
+		h.Errorf("SQL: could not delete old chat messages: %s", err)
+		return 0
+	}
+	n, _ := result.RowsAffected()
+	return n
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"sync"
+	"sync/atomic"
+
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ChannelMember is one open chat stream. playerName is the name the player had when they opened it, which is used in join and
+// leave messages. Messages for the stream wait in its queue until the stream's own goroutine (see serve) sends them, so a slow
+// or dead client never holds up anybody else. Its methods are thread-safe
+type ChannelMember struct {
+	playerId   string
+	sessionId  string
+	playerName string
+	stream     fgrpc.Chat_JoinChannelServer
+	queue      chan *fgrpc.ChatMessage
+	policy     string        // what happens when the queue is full, ChatPolicyDrop or ChatPolicyDisconnect
+	done       chan struct{} // closed when the stream should end
+	closeOnce  *sync.Once
+	lagged     atomic.Bool  // set when the stream was ended because its queue was full
+	dropped    atomic.Int64 // how many messages did not fit in the queue
+}
+
+func newChannelMember(playerId string, sessionId string, playerName string, stream fgrpc.Chat_JoinChannelServer, queueSize int, policy string) *ChannelMember {
+	return &ChannelMember{
+		playerId:   playerId,
+		sessionId:  sessionId,
+		playerName: playerName,
+		stream:     stream,
+		queue:      make(chan *fgrpc.ChatMessage, max(queueSize, 1)),
+		policy:     policy,
+		done:       make(chan struct{}),
+		closeOnce:  &sync.Once{},
+	}
+}
+
+// send queues the message for the stream without waiting. If the queue is full the message is dropped, and with
+// ChatPolicyDisconnect the stream is ended too
+func (m *ChannelMember) send(msg *fgrpc.ChatMessage) {
+	select {
+	case m.queue <- msg:
+	default:
+		m.dropped.Add(1)
+		if m.policy == ChatPolicyDisconnect {
+			m.lagged.Store(true)
+			m.close()
+		}
+	}
+}
+
+// close ends the stream once it has sent what is already queued, unless it is being ended for falling behind
+func (m *ChannelMember) close() {
+	m.closeOnce.Do(func() { close(m.done) })
+}
+
+func (m *ChannelMember) isClosed() bool {
+	select {
+	case <-m.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// serve sends the queued messages to the client until the stream is closed or the client goes away. It runs on the goroutine of
+// the JoinChannel call, and returns the error that ends the call
+func (m *ChannelMember) serve() error {
+	for {
+		select {
+		case msg := <-m.queue:
+			if err := m.stream.Send(msg); err != nil {
+				return err
+			}
+		case <-m.stream.Context().Done(): // the client went away
+			return nil
+		case <-m.done:
+			if m.lagged.Load() {
+				return status.Errorf(codes.ResourceExhausted, "chat closed because it fell %d messages behind", m.dropped.Load())
+			}
+			m.flush()
+			return nil
+		}
+	}
+}
+
+// flush sends whatever is left in the queue, such as the reason the session ended
+func (m *ChannelMember) flush() {
+	for {
+		select {
+		case msg := <-m.queue:
+			if err := m.stream.Send(msg); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
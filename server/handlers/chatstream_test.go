@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const benchQueueSize = 256
+
+// fakeChatStream stands in for a client's chat stream. A stalled stream never finishes a Send, like a client that stopped reading.
+// If record is set the messages it sends are kept in sent
+type fakeChatStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	stalled   bool
+	delivered *atomic.Int64
+	record    bool
+	sent      []*fgrpc.ChatMessage
+}
+
+func (s *fakeChatStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeChatStream) Send(msg *fgrpc.ChatMessage) error {
+	if s.stalled {
+		<-s.ctx.Done()
+		return s.ctx.Err()
+	}
+	s.delivered.Add(1)
+	if s.record {
+		s.sent = append(s.sent, msg)
+	}
+	return nil
+}
+
+// newTestMember returns a member with a queue of queueSize messages, whose stream records what it sends until ctx is done
+func newTestMember(ctx context.Context, queueSize int, policy string) (*ChannelMember, *fakeChatStream) {
+	stream := &fakeChatStream{ctx: ctx, delivered: &atomic.Int64{}, record: true}
+	return newChannelMember("player", "session", "player", stream, queueSize, policy), stream
+}
+
+// serveWithTimeout runs the member's serve and returns its error, failing the test if it does not return
+func serveWithTimeout(t *testing.T, m *ChannelMember) error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- m.serve() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatalf("serve did not return")
+		return nil
+	}
+}
+
+func TestDropPolicy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m, stream := newTestMember(ctx, 2, ChatPolicyDrop)
+	for i := range 5 {
+		m.send(&fgrpc.ChatMessage{Message: fmt.Sprint(i)})
+	}
+	if n := m.dropped.Load(); n != 3 {
+		t.Errorf("%d messages were dropped, want 3", n)
+	}
+	if m.isClosed() {
+		t.Fatalf("the member was closed, the drop policy should keep it")
+	}
+
+	// the member keeps receiving once its queue has room again
+	m.flush()
+	m.send(&fgrpc.ChatMessage{Message: "later"})
+	m.flush()
+	var got []string
+	for _, msg := range stream.sent {
+		got = append(got, msg.Message)
+	}
+	if fmt.Sprint(got) != "[0 1 later]" {
+		t.Errorf("the stream was sent %v, want [0 1 later]", got)
+	}
+}
+
+func TestDisconnectPolicy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m, _ := newTestMember(ctx, 2, ChatPolicyDisconnect)
+	for i := range 3 {
+		m.send(&fgrpc.ChatMessage{Message: fmt.Sprint(i)})
+	}
+	if !m.isClosed() {
+		t.Fatalf("the member was not closed when its queue overflowed")
+	}
+	if err := serveWithTimeout(t, m); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("serve returned %v, want ResourceExhausted", err)
+	}
+}
+
+// TestCloseFlushes checks that messages queued before close, such as the notice that the session ended, are sent before serve returns
+func TestCloseFlushes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m, stream := newTestMember(ctx, 8, ChatPolicyDisconnect)
+	m.send(&fgrpc.ChatMessage{Message: "hello"})
+	m.send(&fgrpc.ChatMessage{Message: "Your session has ended"})
+	m.close()
+
+	if err := serveWithTimeout(t, m); err != nil {
+		t.Fatalf("serve returned %v after close", err)
+	}
+	if len(stream.sent) != 2 || stream.sent[1].Message != "Your session has ended" {
+		t.Errorf("the stream was sent %d messages before serve returned, want both", len(stream.sent))
+	}
+}
+
+func TestServeEndsWithStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m, _ := newTestMember(ctx, 8, ChatPolicyDrop)
+	cancel()
+	if err := serveWithTimeout(t, m); err != nil {
+		t.Errorf("serve returned %v when the client went away", err)
+	}
+}
+
+// newBenchChannel returns a channel with the given number of members, each served by its own goroutine like in JoinChannel. The
+// first member is stalled. The returned function stops every member and waits for them to finish
+func newBenchChannel(members int, policy string) (*ChatChannel, *ChannelMember, *atomic.Int64, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	delivered := &atomic.Int64{}
+	channel := &ChatChannel{&sync.RWMutex{}, "global", true, make([]*ChannelMember, 0, members)}
+	wg := &sync.WaitGroup{}
+
+	for i := range members {
+		stream := &fakeChatStream{ctx: ctx, stalled: i == 0, delivered: delivered}
+		m := newChannelMember(fmt.Sprint("player", i), fmt.Sprint("session", i), fmt.Sprint("player", i), stream, benchQueueSize, policy)
+		channel.members = append(channel.members, m)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.serve()
+		}()
+	}
+	return channel, channel.members[0], delivered, func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// checkStalledMember fails the benchmark if the stalled member was not dropped from or disconnected as the policy says
+func checkStalledMember(b *testing.B, stalled *ChannelMember, policy string, sent int) {
+	if sent <= benchQueueSize {
+		return // the stalled member's queue never filled up
+	}
+	if stalled.dropped.Load() == 0 {
+		b.Fatalf("the stalled member dropped no messages after %d were sent", sent)
+	}
+	if closed := stalled.isClosed(); closed != (policy == ChatPolicyDisconnect) {
+		b.Fatalf("with the %s policy the stalled member should be closed: %v, but it is: %v", policy, policy == ChatPolicyDisconnect, closed)
+	}
+}
+
+// BenchmarkBroadcast measures how long a broadcast takes to queue a message for every member, with one member that has stopped
+// reading. The broadcast should never wait for any member to send
+func BenchmarkBroadcast(b *testing.B) {
+	for _, members := range []int{1000, 5000} {
+		for _, policy := range []string{ChatPolicyDrop, ChatPolicyDisconnect} {
+			b.Run(fmt.Sprintf("members=%d/policy=%s", members, policy), func(b *testing.B) {
+				channel, stalled, _, stop := newBenchChannel(members, policy)
+				defer stop()
+				msg := &fgrpc.ChatMessage{Message: "hello", SendingPlayerName: "bench", ChannelName: channel.name}
+
+				b.ResetTimer()
+				for range b.N {
+					channel.broadcast(msg)
+				}
+				b.StopTimer()
+
+				checkStalledMember(b, stalled, policy, b.N)
+				b.ReportMetric(float64(stalled.dropped.Load()), "stalled-dropped")
+			})
+		}
+	}
+}
+
+// BenchmarkBroadcastDelivery measures how long it takes until every member that is still reading has sent each message, with one
+// member that has stopped reading
+func BenchmarkBroadcastDelivery(b *testing.B) {
+	for _, members := range []int{1000, 5000} {
+		for _, policy := range []string{ChatPolicyDrop, ChatPolicyDisconnect} {
+			b.Run(fmt.Sprintf("members=%d/policy=%s", members, policy), func(b *testing.B) {
+				channel, stalled, delivered, stop := newBenchChannel(members, policy)
+				defer stop()
+				msg := &fgrpc.ChatMessage{Message: "hello", SendingPlayerName: "bench", ChannelName: channel.name}
+				readers := int64(members - 1)
+
+				b.ResetTimer()
+				for i := range b.N {
+					channel.broadcast(msg)
+					for delivered.Load() < readers*int64(i+1) {
+						runtime.Gosched()
+					}
+				}
+				b.StopTimer()
+
+				checkStalledMember(b, stalled, policy, b.N)
+				b.ReportMetric(float64(stalled.dropped.Load()), "stalled-dropped")
+			})
+		}
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/cheracc/fortress-grpc"
 	fgrpc "github.com/cheracc/fortress-grpc/grpc"
 	"github.com/cheracc/fortress-grpc/server/handlers/commands"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // A CommandHandler handles commands sent to the server by a user. It holds references to the AuthHandler and PlayerHandler, as well as the Logger
@@ -19,6 +21,8 @@ type CommandHandler struct {
 	*AuthHandler
 	// the player handler
 	*PlayerHandler
+	// decides which commands each player may use
+	roles *RoleHandler
 	// the logger
 	*fortress.Logger
 }
@@ -29,11 +33,17 @@ type Command struct {
 	Name string
 	// the actual command interface
 	Exec commands.Executable
+	// whether the arguments of this command are secret (such as passwords) and must not be logged
+	HideArguments bool
+	// the permission a player needs to use this command, any logged in player can use it if this is empty
+	Permission string
+	// whether the player must have logged in (not just refreshed their session) within the last few minutes, for sensitive account changes
+	RequiresRecentLogin bool
 }
 
 // NewCommandHandler constructs a new command handler
-func NewCommandHandler(auth *AuthHandler, playerHandler *PlayerHandler, logger *fortress.Logger) *CommandHandler {
-	handler := &CommandHandler{fgrpc.UnimplementedCommandServer{}, make([]*Command, 0), auth, playerHandler, logger}
+func NewCommandHandler(auth *AuthHandler, playerHandler *PlayerHandler, roles *RoleHandler, logger *fortress.Logger) *CommandHandler {
+	handler := &CommandHandler{fgrpc.UnimplementedCommandServer{}, make([]*Command, 0), auth, playerHandler, roles, logger}
 	return handler
 }
 
@@ -45,32 +55,48 @@ func (h *CommandHandler) RegisterCommand(command *Command) {
 
 // receives commands from remote users and sends response as a json payload
 func (h *CommandHandler) Command(ctx context.Context, commandInfo *fgrpc.CommandInfo) (*fgrpc.CommandReturn, error) {
-	h.Logf("Received command execution request %s %s from player %s", commandInfo.GetCommandName(), commandInfo.GetCommandArguments(), commandInfo.GetPlayerInfo().GetId())
-
 	c := h.lookupCommand(commandInfo.GetCommandName())
+	arguments := commandInfo.GetCommandArguments()
+	if c != nil && c.HideArguments {
+		arguments = "<hidden>"
+	}
+	// the auth interceptor has already verified the session token and loaded the player it belongs to
+	player := fortress.PlayerFromContext(ctx)
+	if player == nil {
+		return &fgrpc.CommandReturn{Success: false, JsonPayload: ""}, h.Error("could not find the player for this session")
+	}
+	h.Logf("Received command execution request %s %s from player %s", commandInfo.GetCommandName(), arguments, player.GetPlayerId())
+
 	if c == nil {
 		return &fgrpc.CommandReturn{Success: false, JsonPayload: "command not recognized: %s" + commandInfo.GetCommandName()}, h.Errorf("no command found: %s", commandInfo.GetCommandName())
 	}
 
-	// found the command, validate the user
-	tokenString := commandInfo.GetPlayerInfo().GetSessionToken()
+	if err := h.checkAcceptedTerms(player); err != nil {
+		return &fgrpc.CommandReturn{Success: false, JsonPayload: ""}, err
+	}
 
-	if !h.AuthHandler.IsValidToken(tokenString) {
-		return &fgrpc.CommandReturn{Success: false, JsonPayload: ""}, h.Error("invalid session token")
+	sessionId := ""
+	if claims := claimsFromContext(ctx); claims != nil {
+		sessionId = claims.SessionID
 	}
-	playerId := h.AuthHandler.GetPlayerIdFromTokenString(tokenString)
-	if playerId != commandInfo.GetPlayerInfo().GetId() {
-		h.Logf("session token id: %s, sent id: %s", playerId, commandInfo.GetPlayerInfo().GetId())
-		return &fgrpc.CommandReturn{Success: false, JsonPayload: ""}, h.Error("session token does not match sending player")
+	if c.Permission != "" && !h.roles.HasSessionPermission(player, sessionId, c.Permission) {
+		h.Logf("Player %s(%s) does not have the %s permission needed for command %s", player.GetName(), player.GetPlayerId(), c.Permission, c.Name)
+		if h.roles.MissingSecondFactor(player) && h.roles.HasPermission(player, c.Permission) {
+			return &fgrpc.CommandReturn{Success: false, JsonPayload: ""}, status.Errorf(codes.PermissionDenied, "turn on two-factor authentication with '2fa setup' to use the command %s", c.Name)
+		}
+		if player.IsGuest() {
+			return &fgrpc.CommandReturn{Success: false, JsonPayload: ""}, status.Errorf(codes.PermissionDenied, "guests can not use the command %s, use 'link <provider>' to make a full account", c.Name)
+		}
+		return &fgrpc.CommandReturn{Success: false, JsonPayload: ""}, status.Errorf(codes.PermissionDenied, "you do not have permission to use the command %s", c.Name)
 	}
 
-	player, _ := h.PlayerHandler.GetPlayer(PlayerFilter{playerId: playerId}, false)
-	if player == nil {
-		return &fgrpc.CommandReturn{Success: false, JsonPayload: ""}, h.Errorf("could not find an online player with id %s", playerId)
+	// guests can't log in again (they have no identity to log in with), and linking one is how they stop being a guest
+	if c.RequiresRecentLogin && !player.IsGuest() && !h.isRecentLogin(sessionId) {
+		return &fgrpc.CommandReturn{Success: false, JsonPayload: ""}, status.Errorf(codes.PermissionDenied, "for your security, log out and log in again before using the command %s", c.Name)
 	}
 
 	h.Logf("Executing command %s for player %s(%s)", c.Name, player.GetName(), player.GetPlayerId())
-	response, err := c.Execute(player, getArgs(commandInfo))
+	response, err := c.Execute(player, sessionId, getArgs(commandInfo))
 
 	return &fgrpc.CommandReturn{Success: err != nil, JsonPayload: response}, err
 }
@@ -92,9 +118,15 @@ func getArgs(ci *fgrpc.CommandInfo) []string {
 	return args
 }
 
-// Execute calls the function contained in the command
-func (c *Command) Execute(player *fortress.Player, args []string) (string, error) {
-	s, err := c.Exec.Execute(player, args)
+// Execute calls the function contained in the command. sessionId is the session the player sent the command from
+func (c *Command) Execute(player *fortress.Player, sessionId string, args []string) (string, error) {
+	var s string
+	var err error
+	if exec, ok := c.Exec.(commands.SessionExecutable); ok {
+		s, err = exec.ExecuteInSession(player, sessionId, args)
+	} else {
+		s, err = c.Exec.Execute(player, args)
+	}
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cheracc/fortress-grpc"
+)
+
+// AuditFilter chooses which security events the audit command shows. Empty fields match every event
+type AuditFilter struct {
+	Player    string // a player name or id, matching events that happened to the player or that they caused
+	Kind      string // such as login.failure, or login for every kind of login event
+	IpAddress string
+	Since     time.Time
+	Page      int // the first page (0) has the newest events
+}
+
+// SecurityEventInfo describes an event in the audit log for the audit command
+type SecurityEventInfo struct {
+	At        time.Time
+	Kind      string
+	Player    string
+	Actor     string
+	IpAddress string
+	Detail    string
+}
+
+// AuditCommand represents a command an admin uses to look through the audit log of logins, token use, role changes and bans
+type AuditCommand struct {
+	// ListEventsFunc returns a page of the events that match the filter, newest first, and whether there are older ones
+	ListEventsFunc func(AuditFilter) ([]SecurityEventInfo, bool, error)
+}
+
+// Execute shows the newest security events, which can be narrowed down with any of:
+//
+//	audit player <name>      events that happened to the player or that they caused
+//	audit type <kind>        events of one kind, such as login.failure, or login for all login events
+//	audit ip <address>       events from the ip address
+//	audit since <duration>   events from the last duration, such as 2h or 7d
+//	audit page <n>           older events, page 1 is the newest
+//
+// for example 'audit type login.failure since 7d page 2'
+func (c *AuditCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	filter, err := parseAuditFilter(args)
+	if err != nil {
+		return "", err
+	}
+	events, more, err := c.ListEventsFunc(filter)
+	if err != nil {
+		return "", err
+	}
+	if len(events) == 0 {
+		if filter.Page > 0 {
+			return fmt.Sprintf("There are no security events on page %d.", filter.Page+1), nil
+		}
+		return "There are no matching security events.", nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Security events, newest first (page %d):\n\r", filter.Page+1))
+	for _, e := range events {
+		output.WriteString(fmt.Sprintf("    %s %-13s", e.At.Local().Format(time.DateTime), e.Kind))
+		if e.Player != "" {
+			output.WriteString(" " + e.Player)
+		}
+		if e.Actor != "" {
+			output.WriteString(" by " + e.Actor)
+		}
+		if e.IpAddress != "" {
+			output.WriteString(" from " + e.IpAddress)
+		}
+		if e.Detail != "" {
+			output.WriteString(": " + e.Detail)
+		}
+		output.WriteString("\n\r")
+	}
+	if more {
+		output.WriteString(fmt.Sprintf("Add 'page %d' for older events.", filter.Page+2))
+	}
+	return strings.TrimSuffix(output.String(), "\n\r"), nil
+}
+
+// parseAuditFilter reads the filter from pairs of arguments such as 'player bob since 7d'
+func parseAuditFilter(args []string) (AuditFilter, error) {
+	filter := AuditFilter{}
+	if len(args) == 1 && args[0] == "" {
+		return filter, nil
+	}
+	if len(args)%2 != 0 {
+		return filter, fmt.Errorf("wrong number of arguments. Syntax: audit [player <name>] [type <kind>] [ip <address>] [since <duration>] [page <n>]")
+	}
+
+	for i := 0; i < len(args); i += 2 {
+		value := args[i+1]
+		switch args[i] {
+		case "player":
+			filter.Player = value
+		case "type":
+			filter.Kind = strings.ToLower(value)
+		case "ip":
+			filter.IpAddress = value
+		case "since":
+			duration, err := parseSanctionDuration(value)
+			if err != nil || duration == 0 {
+				return filter, fmt.Errorf("%s is not a duration such as 30m, 2h or 7d", value)
+			}
+			filter.Since = time.Now().Add(-duration)
+		case "page":
+			page, err := strconv.Atoi(value)
+			if err != nil || page < 1 {
+				return filter, fmt.Errorf("%s is not a page number", value)
+			}
+			filter.Page = page - 1
+		default:
+			return filter, fmt.Errorf("unknown filter %s. Syntax: audit [player <name>] [type <kind>] [ip <address>] [since <duration>] [page <n>]", args[i])
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cheracc/fortress-grpc"
+)
+
+// BotInfo describes a bot for the bot command
+type BotInfo struct {
+	Name string
+	Id   string
+	Keys int
+}
+
+// ApiKeyInfo describes an api key of a bot for the bot command. The key itself is never shown again after it is created
+type ApiKeyInfo struct {
+	Id          string
+	Permissions []string
+	CreatedAt   time.Time
+	LastUsedAt  time.Time
+}
+
+// BotCommand represents a command a player uses to manage their bot accounts and the api keys the bots log in with
+type BotCommand struct {
+	// CreateBotFunc creates a bot with the given name owned by the player
+	CreateBotFunc func(*fortress.Player, string) error
+	// DeleteBotFunc deletes a bot of the player
+	DeleteBotFunc func(*fortress.Player, string) error
+	// ListBotsFunc returns the bots the player owns
+	ListBotsFunc func(*fortress.Player) []BotInfo
+	// CreateKeyFunc makes a new api key for a bot of the player with the given permissions, and returns it
+	CreateKeyFunc func(*fortress.Player, string, []string) (string, error)
+	// ListKeysFunc returns the api keys of a bot of the player
+	ListKeysFunc func(*fortress.Player, string) ([]ApiKeyInfo, error)
+	// RevokeKeyFunc revokes an api key of one of the player's bots
+	RevokeKeyFunc func(*fortress.Player, string) error
+}
+
+// Execute lists the player's bots, or with arguments:
+//
+//	bot create <name>                    creates a bot
+//	bot delete <name>                    deletes a bot, its keys and its sessions
+//	bot key <name> [permission...]       makes a new api key for a bot
+//	bot keys <name>                      lists the api keys of a bot
+//	bot revokekey <key id>               revokes an api key and ends its sessions
+func (c *BotCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	if len(args) == 0 || args[0] == "" || args[0] == "list" {
+		return c.listBots(player), nil
+	}
+
+	switch args[0] {
+	case "create":
+		if len(args) != 2 {
+			return "", fmt.Errorf("wrong number of arguments. Syntax: bot create <name>")
+		}
+		if err := c.CreateBotFunc(player, args[1]); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Created the bot %s. Use 'bot key %s' to give it an api key.", args[1], args[1]), nil
+	case "delete":
+		if len(args) != 2 {
+			return "", fmt.Errorf("wrong number of arguments. Syntax: bot delete <name>")
+		}
+		if err := c.DeleteBotFunc(player, args[1]); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Deleted the bot %s.", args[1]), nil
+	case "key":
+		if len(args) < 2 {
+			return "", fmt.Errorf("wrong number of arguments. Syntax: bot key <name> [permission...]")
+		}
+		key, err := c.CreateKeyFunc(player, args[1], args[2:])
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("New api key for %s: %s\n\rKeep it somewhere safe, it will not be shown again.", args[1], key), nil
+	case "keys":
+		if len(args) != 2 {
+			return "", fmt.Errorf("wrong number of arguments. Syntax: bot keys <name>")
+		}
+		return c.listKeys(player, args[1])
+	case "revokekey":
+		if len(args) != 2 {
+			return "", fmt.Errorf("wrong number of arguments. Syntax: bot revokekey <key id>")
+		}
+		if err := c.RevokeKeyFunc(player, args[1]); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Revoked the api key %s.", args[1]), nil
+	}
+	return "", fmt.Errorf("unknown subcommand %s. Syntax: bot [list|create|delete|key|keys|revokekey]", args[0])
+}
+
+func (c *BotCommand) listBots(player *fortress.Player) string {
+	bots := c.ListBotsFunc(player)
+	if len(bots) == 0 {
+		return "You have no bots. Use 'bot create <name>' to make one."
+	}
+
+	var output strings.Builder
+	output.WriteString("Your bots:\n\r")
+	for _, b := range bots {
+		output.WriteString(fmt.Sprintf("    %s (%s): %d api keys\n\r", b.Name, b.Id, b.Keys))
+	}
+	return strings.TrimSuffix(output.String(), "\n\r")
+}
+
+func (c *BotCommand) listKeys(player *fortress.Player, name string) (string, error) {
+	keys, err := c.ListKeysFunc(player, name)
+	if err != nil {
+		return "", err
+	}
+	if len(keys) == 0 {
+		return fmt.Sprintf("%s has no api keys.", name), nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Api keys of %s:\n\r", name))
+	for _, k := range keys {
+		used := "never used"
+		if !k.LastUsedAt.IsZero() {
+			used = "last used " + k.LastUsedAt.Local().Format(time.DateTime)
+		}
+		permissions := strings.Join(k.Permissions, ", ")
+		if permissions == "" {
+			permissions = "no permissions"
+		}
+		output.WriteString(fmt.Sprintf("    %s created %s, %s: %s\n\r", k.Id, k.CreatedAt.Local().Format(time.DateTime), used, permissions))
+	}
+	return strings.TrimSuffix(output.String(), "\n\r"), nil
+}
@@ -8,3 +8,11 @@ type Executable interface {
 	// Execute then returns a response as a string and an error
 	Execute(*fortress.Player, []string) (string, error)
 }
+
+// A SessionExecutable is an Executable that also needs to know which of the player's sessions sent the command. The CommandHandler
+// calls ExecuteInSession instead of Execute for these
+type SessionExecutable interface {
+	Executable
+	// ExecuteInSession is Execute with the id of the session the command was sent from
+	ExecuteInSession(*fortress.Player, string, []string) (string, error)
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cheracc/fortress-grpc"
+)
+
+// IdentityInfo describes an identity linked to a player for the identities command
+type IdentityInfo struct {
+	Provider string
+	Subject  string
+	LinkedAt time.Time
+}
+
+// IdentitiesCommand represents a command a player uses to see the identities they can log in with
+type IdentitiesCommand struct {
+	// ListIdentitiesFunc returns the identities linked to the player
+	ListIdentitiesFunc func(*fortress.Player) []IdentityInfo
+}
+
+// Execute lists the player's identities. Syntax: identities
+func (c *IdentitiesCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	var output strings.Builder
+	output.WriteString("You can log in with:\n\r")
+	for _, i := range c.ListIdentitiesFunc(player) {
+		output.WriteString(fmt.Sprintf("    %s:%s (linked %s)\n\r", i.Provider, i.Subject, i.LinkedAt.Local().Format(time.DateTime)))
+	}
+	output.WriteString("Use 'link <provider>' to add another or 'unlink <provider>' to remove one")
+	return output.String(), nil
+}
+
+// LinkCommand represents a command a player uses to link another identity to their account
+type LinkCommand struct {
+	// StartLinkFunc begins linking an identity from the named provider to the player, and returns the url to sign in at
+	StartLinkFunc func(*fortress.Player, string) (string, error)
+}
+
+// Execute starts linking an identity. Syntax: link <provider>
+func (c *LinkCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	if len(args) != 1 || args[0] == "" {
+		return "", fmt.Errorf("wrong number of arguments. Syntax: link <provider>")
+	}
+	url, err := c.StartLinkFunc(player, args[0])
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Sign in with the %s account you want to link here: %s", args[0], url), nil
+}
+
+// UnlinkCommand represents a command a player uses to remove an identity from their account
+type UnlinkCommand struct {
+	// UnlinkFunc removes the identity (provider or provider:subject) from the player
+	UnlinkFunc func(*fortress.Player, string) error
+}
+
+// Execute unlinks an identity. Syntax: unlink <provider|provider:subject>
+func (c *UnlinkCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	if len(args) != 1 || args[0] == "" {
+		return "", fmt.Errorf("wrong number of arguments. Syntax: unlink <provider|provider:subject>")
+	}
+	if err := c.UnlinkFunc(player, args[0]); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Unlinked %s, you can no longer log in with it.", args[0]), nil
+}
@@ -0,0 +1,173 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cheracc/fortress-grpc"
+)
+
+// InviteInfo describes an invite code for the invite command
+type InviteInfo struct {
+	Code      string
+	CreatedBy string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	Uses      int
+	MaxUses   int
+	Usable    bool
+	Revoked   bool
+}
+
+// RedemptionInfo describes a player that joined with an invite code, and who made the code
+type RedemptionInfo struct {
+	Code       string
+	Player     string
+	InvitedBy  string
+	RedeemedAt time.Time
+}
+
+// InviteCommand represents a command an admin uses to hand out invite codes for a whitelist-only server
+type InviteCommand struct {
+	// CreateInviteFunc makes a new invite code with the given number of uses and lifetime (0 never expires), and returns it
+	CreateInviteFunc func(*fortress.Player, int, time.Duration) (string, error)
+	// RevokeInviteFunc stops an invite code from being redeemed
+	RevokeInviteFunc func(*fortress.Player, string) error
+	// ListInvitesFunc returns every invite code, newest first
+	ListInvitesFunc func() []InviteInfo
+	// ListRedemptionsFunc returns who joined with the invite code, or with any code if it is "", newest first
+	ListRedemptionsFunc func(string) []RedemptionInfo
+}
+
+// Execute lists the invite codes, or with arguments:
+//
+//	invite create <uses> <duration|perm>    makes a new invite code, such as 'invite create 5 7d'
+//	invite revoke <code>                    stops an invite code from being used
+//	invite redemptions [code]               shows who joined with which code, and who invited them
+func (c *InviteCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	if len(args) == 0 || args[0] == "" || args[0] == "list" {
+		return c.listInvites(), nil
+	}
+
+	switch args[0] {
+	case "create":
+		if len(args) != 3 {
+			return "", fmt.Errorf("wrong number of arguments. Syntax: invite create <uses> <duration|perm>")
+		}
+		uses, err := strconv.Atoi(args[1])
+		if err != nil {
+			return "", fmt.Errorf("%s is not a number of uses", args[1])
+		}
+		lifetime, err := parseSanctionDuration(args[2])
+		if err != nil {
+			return "", err
+		}
+		code, err := c.CreateInviteFunc(player, uses, lifetime)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("New invite code: %s\n\rPlayers join by logging in with it, for example with the -invite option of the client.", code), nil
+	case "revoke":
+		if len(args) != 2 {
+			return "", fmt.Errorf("wrong number of arguments. Syntax: invite revoke <code>")
+		}
+		if err := c.RevokeInviteFunc(player, args[1]); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Revoked the invite code %s.", args[1]), nil
+	case "redemptions":
+		if len(args) > 2 {
+			return "", fmt.Errorf("wrong number of arguments. Syntax: invite redemptions [code]")
+		}
+		code := ""
+		if len(args) == 2 {
+			code = args[1]
+		}
+		return c.listRedemptions(code), nil
+	}
+	return "", fmt.Errorf("unknown subcommand %s. Syntax: invite [list|create|revoke|redemptions]", args[0])
+}
+
+func (c *InviteCommand) listInvites() string {
+	invites := c.ListInvitesFunc()
+	if len(invites) == 0 {
+		return "There are no invite codes. Use 'invite create <uses> <duration|perm>' to make one."
+	}
+
+	var output strings.Builder
+	output.WriteString("Invite codes:\n\r")
+	for _, i := range invites {
+		state := "usable"
+		switch {
+		case i.Revoked:
+			state = "revoked"
+		case !i.Usable && i.Uses >= i.MaxUses:
+			state = "used up"
+		case !i.Usable:
+			state = "expired"
+		}
+		expires := "never expires"
+		if !i.ExpiresAt.IsZero() {
+			expires = "expires " + i.ExpiresAt.Local().Format(time.DateTime)
+		}
+		output.WriteString(fmt.Sprintf("    %s by %s, used %d/%d, %s [%s]\n\r", i.Code, i.CreatedBy, i.Uses, i.MaxUses, expires, state))
+	}
+	return strings.TrimSuffix(output.String(), "\n\r")
+}
+
+func (c *InviteCommand) listRedemptions(code string) string {
+	redemptions := c.ListRedemptionsFunc(code)
+	if len(redemptions) == 0 {
+		return "Nobody has joined with an invite code yet."
+	}
+
+	var output strings.Builder
+	output.WriteString("Invite redemptions:\n\r")
+	for _, r := range redemptions {
+		output.WriteString(fmt.Sprintf("    %s %s joined with %s, invited by %s\n\r", r.RedeemedAt.Local().Format(time.DateTime), r.Player, r.Code, r.InvitedBy))
+	}
+	return strings.TrimSuffix(output.String(), "\n\r")
+}
+
+// WhitelistCommand represents a command an admin uses to choose who can log in to a whitelist-only server
+type WhitelistCommand struct {
+	// FindPlayerFunc returns the player with the given name or id, or nil if there is none
+	FindPlayerFunc func(string) *fortress.Player
+	// ListWhitelistFunc returns the names of the players on the whitelist
+	ListWhitelistFunc func() []string
+	// AddFunc adds the second player to the whitelist on behalf of the first
+	AddFunc func(*fortress.Player, *fortress.Player) error
+	// RemoveFunc removes the second player from the whitelist on behalf of the first
+	RemoveFunc func(*fortress.Player, *fortress.Player) error
+}
+
+// Execute lists the whitelist, or adds or removes a player. Syntax: whitelist [add|remove <player>]
+func (c *WhitelistCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	if len(args) == 0 || args[0] == "" || args[0] == "list" {
+		names := c.ListWhitelistFunc()
+		if len(names) == 0 {
+			return "The whitelist is empty.", nil
+		}
+		return fmt.Sprintf("On the whitelist (%d): %s", len(names), strings.Join(names, ", ")), nil
+	}
+	if len(args) != 2 || (args[0] != "add" && args[0] != "remove") {
+		return "", fmt.Errorf("wrong arguments. Syntax: whitelist [add|remove <player>]")
+	}
+
+	target := c.FindPlayerFunc(args[1])
+	if target == nil {
+		return "", fmt.Errorf("there is no player named %s", args[1])
+	}
+	if args[0] == "add" {
+		if err := c.AddFunc(player, target); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s is now on the whitelist.", target.GetName()), nil
+	}
+	if err := c.RemoveFunc(player, target); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s is no longer on the whitelist.", target.GetName()), nil
+}
@@ -18,8 +18,8 @@ func (c *ListCommand) Execute(player *fortress.Player, args []string) (string, e
 	var i int = -1
 	var p *fortress.Player
 	for i, p = range c.GetOnlinePlayersFunc() {
-		name := p.GetName()
-		if name == "" {
+		name := p.GetDisplayName()
+		if p.GetName() == "" {
 			name = "no-name"
 		}
 		if i > 0 {
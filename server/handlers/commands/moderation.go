@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cheracc/fortress-grpc"
+)
+
+// SanctionInfo describes a kick, ban or mute for the sanctions command
+type SanctionInfo struct {
+	Id        int64
+	Kind      string
+	Player    string // the name of the player it was given to
+	Reason    string
+	Moderator string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	LiftedAt  time.Time
+	Active    bool
+}
+
+// KickCommand represents a command a moderator uses to end all of a player's sessions
+type KickCommand struct {
+	// FindPlayerFunc returns the player with the given name or id, or nil if there is none
+	FindPlayerFunc func(string) *fortress.Player
+	// KickFunc kicks the second player on behalf of the first, with the given reason
+	KickFunc func(*fortress.Player, *fortress.Player, string) error
+}
+
+// Execute kicks a player. Syntax: kick <player> <reason>
+func (c *KickCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	if len(args) < 2 || args[0] == "" {
+		return "", fmt.Errorf("wrong number of arguments. Syntax: kick <player> <reason>")
+	}
+	target := c.FindPlayerFunc(args[0])
+	if target == nil {
+		return "", fmt.Errorf("there is no player named %s", args[0])
+	}
+	if err := c.KickFunc(player, target, strings.Join(args[1:], " ")); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s has been kicked.", target.GetName()), nil
+}
+
+// SanctionCommand represents a command a moderator uses to ban or mute a player for a while, or permanently
+type SanctionCommand struct {
+	// the name of the command, used in its syntax message
+	Name string
+	// FindPlayerFunc returns the player with the given name or id, or nil if there is none
+	FindPlayerFunc func(string) *fortress.Player
+	// SanctionFunc bans or mutes the second player on behalf of the first, for the duration (0 is permanent) and with the reason
+	SanctionFunc func(*fortress.Player, *fortress.Player, time.Duration, string) error
+}
+
+// Execute bans or mutes a player. Syntax: <ban|mute> <player> <duration|perm> <reason>, where duration is like 30m, 12h or 7d
+func (c *SanctionCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	if len(args) < 3 || args[0] == "" {
+		return "", fmt.Errorf("wrong number of arguments. Syntax: %s <player> <duration|perm> <reason>", c.Name)
+	}
+	target := c.FindPlayerFunc(args[0])
+	if target == nil {
+		return "", fmt.Errorf("there is no player named %s", args[0])
+	}
+	duration, err := parseSanctionDuration(args[1])
+	if err != nil {
+		return "", err
+	}
+	if err := c.SanctionFunc(player, target, duration, strings.Join(args[2:], " ")); err != nil {
+		return "", err
+	}
+	if duration == 0 {
+		return fmt.Sprintf("Done, the %s of %s is permanent.", c.Name, target.GetName()), nil
+	}
+	return fmt.Sprintf("Done, the %s of %s lasts %s.", c.Name, target.GetName(), duration), nil
+}
+
+// LiftSanctionCommand represents a command a moderator uses to lift a ban or mute early
+type LiftSanctionCommand struct {
+	// the name of the command, used in its syntax message
+	Name string
+	// FindPlayerFunc returns the player with the given name or id, or nil if there is none
+	FindPlayerFunc func(string) *fortress.Player
+	// LiftFunc lifts the sanction of the second player on behalf of the first
+	LiftFunc func(*fortress.Player, *fortress.Player) error
+}
+
+// Execute lifts a ban or mute. Syntax: <unban|unmute> <player>
+func (c *LiftSanctionCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	if len(args) != 1 || args[0] == "" {
+		return "", fmt.Errorf("wrong number of arguments. Syntax: %s <player>", c.Name)
+	}
+	target := c.FindPlayerFunc(args[0])
+	if target == nil {
+		return "", fmt.Errorf("there is no player named %s", args[0])
+	}
+	if err := c.LiftFunc(player, target); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("The %s of %s has been lifted.", strings.TrimPrefix(c.Name, "un"), target.GetName()), nil
+}
+
+// SanctionsCommand represents a command a moderator uses to see the kicks, bans and mutes a player has been given, or every ban
+// and mute that is in effect
+type SanctionsCommand struct {
+	// FindPlayerFunc returns the player with the given name or id, or nil if there is none
+	FindPlayerFunc func(string) *fortress.Player
+	// ListSanctionsFunc returns the sanctions of the player, newest first
+	ListSanctionsFunc func(*fortress.Player) []SanctionInfo
+	// ListActiveSanctionsFunc returns every ban and mute that is in effect, newest first
+	ListActiveSanctionsFunc func() []SanctionInfo
+}
+
+// Execute lists a player's sanctions, or without a player every active ban and mute. Syntax: sanctions [player]
+func (c *SanctionsCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	if len(args) == 0 {
+		return c.listActive(), nil
+	}
+	if len(args) != 1 || args[0] == "" {
+		return "", fmt.Errorf("wrong number of arguments. Syntax: sanctions [player]")
+	}
+	target := c.FindPlayerFunc(args[0])
+	if target == nil {
+		return "", fmt.Errorf("there is no player named %s", args[0])
+	}
+
+	sanctions := c.ListSanctionsFunc(target)
+	if len(sanctions) == 0 {
+		return fmt.Sprintf("%s has never been kicked, banned or muted.", target.GetName()), nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Sanctions of %s:\n\r", target.GetName()))
+	for _, s := range sanctions {
+		output.WriteString(fmt.Sprintf("    %s %s by %s%s: %s\n\r", s.CreatedAt.Local().Format(time.DateTime), s.Kind, s.Moderator, sanctionState(s), s.Reason))
+	}
+	return strings.TrimSuffix(output.String(), "\n\r"), nil
+}
+
+// listActive describes every ban and mute that is in effect
+func (c *SanctionsCommand) listActive() string {
+	sanctions := c.ListActiveSanctionsFunc()
+	if len(sanctions) == 0 {
+		return "Nobody is banned or muted."
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%d active bans and mutes:\n\r", len(sanctions)))
+	for _, s := range sanctions {
+		output.WriteString(fmt.Sprintf("    %s %s of %s by %s%s: %s\n\r", s.CreatedAt.Local().Format(time.DateTime), s.Kind, s.Player, s.Moderator, sanctionState(s), s.Reason))
+	}
+	return strings.TrimSuffix(output.String(), "\n\r")
+}
+
+// sanctionState describes whether the sanction is in effect, such as " [ACTIVE, permanent]", or "" for an expired one or a kick
+func sanctionState(s SanctionInfo) string {
+	switch {
+	case s.Active && s.ExpiresAt.IsZero():
+		return " [ACTIVE, permanent]"
+	case s.Active:
+		return " [ACTIVE until " + s.ExpiresAt.Local().Format(time.DateTime) + "]"
+	case !s.LiftedAt.IsZero():
+		return " [lifted " + s.LiftedAt.Local().Format(time.DateTime) + "]"
+	}
+	return ""
+}
+
+// parseSanctionDuration reads a duration like 30m, 12h or 7d. "perm" or "permanent" is returned as 0
+func parseSanctionDuration(s string) (time.Duration, error) {
+	if s == "perm" || s == "permanent" {
+		return 0, nil
+	}
+	if days, found := strings.CutSuffix(s, "d"); found {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("%s is not a valid duration, use something like 30m, 12h, 7d or perm", s)
+}
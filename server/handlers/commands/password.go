@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/cheracc/fortress-grpc"
+)
+
+// PasswordCommand represents a command a player uses to change the password of their local account
+type PasswordCommand struct {
+	// ChangePasswordFunc checks the old password and sets the new one, it is passed the playerId, the session to keep logged in,
+	// the old password and the new password
+	ChangePasswordFunc func(string, string, string, string) error
+}
+
+// Execute changes the password without keeping any session logged in
+func (c *PasswordCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	return c.ExecuteInSession(player, "", args)
+}
+
+// ExecuteInSession checks the arguments and calls ChangePasswordFunc
+func (c *PasswordCommand) ExecuteInSession(player *fortress.Player, sessionId string, args []string) (string, error) {
+	if len(args) != 2 {
+		return "", fmt.Errorf("wrong number of arguments. Syntax: passwd <old password> <new password>")
+	}
+	if err := c.ChangePasswordFunc(player.GetPlayerId(), sessionId, args[0], args[1]); err != nil {
+		return "", err
+	}
+	return "Your password has been changed, your other sessions have been logged out.", nil
+}
+
+// ResetPasswordCommand represents a command an admin uses to set a new password on any local account
+type ResetPasswordCommand struct {
+	// ResetPasswordFunc sets the password, it is passed the username and the new password
+	ResetPasswordFunc func(string, string) error
+}
+
+// Execute checks the arguments and calls ResetPasswordFunc
+func (c *ResetPasswordCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	if len(args) != 2 {
+		return "", fmt.Errorf("wrong number of arguments. Syntax: resetpassword <username> <new password>")
+	}
+	if err := c.ResetPasswordFunc(args[0], args[1]); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("The password of %s has been reset.", args[0]), nil
+}
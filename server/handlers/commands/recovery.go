@@ -0,0 +1,33 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cheracc/fortress-grpc"
+)
+
+// RecoveryCommand represents a command a player uses to see or replace the recovery codes that get them back into their account
+type RecoveryCommand struct {
+	// CountFunc returns how many unused recovery codes the player has
+	CountFunc func(*fortress.Player) int
+	// RegenerateFunc replaces the player's recovery codes and returns the new ones
+	RegenerateFunc func(*fortress.Player) ([]string, error)
+}
+
+// Execute shows how many recovery codes the player has left, or with 'recovery regenerate' replaces them
+func (c *RecoveryCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	if len(args) == 0 || args[0] == "" {
+		return fmt.Sprintf("You have %d unused recovery codes. Use 'recovery regenerate' to replace them if you have lost them.", c.CountFunc(player)), nil
+	}
+	if args[0] != "regenerate" || len(args) != 1 {
+		return "", fmt.Errorf("wrong arguments. Syntax: recovery [regenerate]")
+	}
+
+	codes, err := c.RegenerateFunc(player)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Your old recovery codes no longer work. Keep these somewhere safe, each one gets you back into your account once "+
+		"if you can't log in with any of your identities:\n\r    %s", strings.Join(codes, "\n\r    ")), nil
+}
@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cheracc/fortress-grpc"
+)
+
+// RoleInfo describes a role for the roles command
+type RoleInfo struct {
+	Name        string
+	Permissions []string
+	Builtin     bool
+}
+
+// GrantCommand represents a command an admin uses to give a role to a player
+type GrantCommand struct {
+	// FindPlayerFunc returns the player with the given name or id, or nil if there is none
+	FindPlayerFunc func(string) *fortress.Player
+	// GrantRoleFunc gives the role to the second player, on behalf of the first
+	GrantRoleFunc func(*fortress.Player, *fortress.Player, string) error
+}
+
+// Execute gives a role to a player. Syntax: grant <player> <role>
+func (c *GrantCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	if len(args) != 2 || args[0] == "" || args[1] == "" {
+		return "", fmt.Errorf("wrong number of arguments. Syntax: grant <player> <role>")
+	}
+	target := c.FindPlayerFunc(args[0])
+	if target == nil {
+		return "", fmt.Errorf("there is no player named %s", args[0])
+	}
+	if err := c.GrantRoleFunc(player, target, args[1]); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s now has the %s role.", target.GetName(), args[1]), nil
+}
+
+// RevokeCommand represents a command an admin uses to take a role away from a player
+type RevokeCommand struct {
+	// FindPlayerFunc returns the player with the given name or id, or nil if there is none
+	FindPlayerFunc func(string) *fortress.Player
+	// RevokeRoleFunc takes the role away from the second player, on behalf of the first
+	RevokeRoleFunc func(*fortress.Player, *fortress.Player, string) error
+}
+
+// Execute takes a role away from a player. Syntax: revoke <player> <role>
+func (c *RevokeCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	if len(args) != 2 || args[0] == "" || args[1] == "" {
+		return "", fmt.Errorf("wrong number of arguments. Syntax: revoke <player> <role>")
+	}
+	target := c.FindPlayerFunc(args[0])
+	if target == nil {
+		return "", fmt.Errorf("there is no player named %s", args[0])
+	}
+	if err := c.RevokeRoleFunc(player, target, args[1]); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s no longer has the %s role.", target.GetName(), args[1]), nil
+}
+
+// RolesCommand represents a command an admin uses to see and manage roles
+type RolesCommand struct {
+	// ListRolesFunc returns every role
+	ListRolesFunc func() []RoleInfo
+	// GetRolesFunc returns the roles of a player
+	GetRolesFunc func(*fortress.Player) []string
+	// FindPlayerFunc returns the player with the given name or id, or nil if there is none
+	FindPlayerFunc func(string) *fortress.Player
+	// CreateRoleFunc creates a custom role with the given name and permissions, on behalf of the player
+	CreateRoleFunc func(*fortress.Player, string, []string) error
+	// DeleteRoleFunc deletes a custom role, on behalf of the player
+	DeleteRoleFunc func(*fortress.Player, string) error
+}
+
+// Execute lists every role, or with arguments:
+//
+//	roles <player>                        lists the roles of a player
+//	roles create <name> <permission>...   creates a custom role
+//	roles delete <name>                   deletes a custom role
+func (c *RolesCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	if len(args) == 0 || args[0] == "" {
+		return c.listRoles(), nil
+	}
+
+	switch args[0] {
+	case "create":
+		if len(args) < 3 {
+			return "", fmt.Errorf("wrong number of arguments. Syntax: roles create <name> <permission> [permission...]")
+		}
+		if err := c.CreateRoleFunc(player, args[1], args[2:]); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Created the role %s.", args[1]), nil
+	case "delete":
+		if len(args) != 2 {
+			return "", fmt.Errorf("wrong number of arguments. Syntax: roles delete <name>")
+		}
+		if err := c.DeleteRoleFunc(player, args[1]); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Deleted the role %s.", args[1]), nil
+	}
+
+	if len(args) != 1 {
+		return "", fmt.Errorf("too many arguments. Syntax: roles [player]")
+	}
+	target := c.FindPlayerFunc(args[0])
+	if target == nil {
+		return "", fmt.Errorf("there is no player named %s", args[0])
+	}
+	return fmt.Sprintf("%s has the roles: %s", target.GetName(), strings.Join(c.GetRolesFunc(target), ", ")), nil
+}
+
+func (c *RolesCommand) listRoles() string {
+	var output strings.Builder
+	output.WriteString("Roles:\n\r")
+	for _, r := range c.ListRolesFunc() {
+		kind := "custom"
+		if r.Builtin {
+			kind = "built in"
+		}
+		output.WriteString(fmt.Sprintf("    %s (%s): %s\n\r", r.Name, kind, strings.Join(r.Permissions, ", ")))
+	}
+	output.WriteString("Use 'roles <player>' to see a player's roles, 'roles create <name> <permission>...' or 'roles delete <name>' to manage custom roles")
+	return output.String()
+}
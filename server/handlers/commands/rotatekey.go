@@ -0,0 +1,22 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/cheracc/fortress-grpc"
+)
+
+// RotateKeyCommand represents a command used to replace the key that signs session tokens
+type RotateKeyCommand struct {
+	// RotateKeyFunc generates and saves a new signing key, returning its id
+	RotateKeyFunc func() (string, error)
+}
+
+// Execute calls RotateKeyFunc. Tokens signed with the old key stay valid until they expire
+func (c *RotateKeyCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	kid, err := c.RotateKeyFunc()
+	if err != nil {
+		return "", fmt.Errorf("could not rotate signing key: %w", err)
+	}
+	return fmt.Sprintf("Session tokens are now signed with key %s", kid), nil
+}
@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cheracc/fortress-grpc"
+)
+
+// SessionInfo describes one of a player's sessions for the sessions command
+type SessionInfo struct {
+	Id        string
+	Device    string
+	IpAddress string
+	IssuedAt  time.Time
+	LastSeen  time.Time
+}
+
+// SessionsCommand represents a command a player uses to list their active sessions and revoke them
+type SessionsCommand struct {
+	// ListSessionsFunc returns the active sessions of the player with the given playerId
+	ListSessionsFunc func(string) []SessionInfo
+	// RevokeSessionFunc ends a session of the player, it is passed the playerId and the start of the session id
+	RevokeSessionFunc func(string, string) error
+}
+
+// Execute lists the player's sessions, or revokes one if the arguments are "revoke <session id>"
+func (c *SessionsCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	if len(args) > 0 && args[0] == "revoke" {
+		if len(args) != 2 || args[1] == "" {
+			return "", fmt.Errorf("wrong number of arguments. Syntax: sessions revoke <session id>")
+		}
+		if err := c.RevokeSessionFunc(player.GetPlayerId(), args[1]); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Session %s has been ended.", args[1]), nil
+	}
+
+	sessions := c.ListSessionsFunc(player.GetPlayerId())
+	if len(sessions) == 0 {
+		return "You have no active sessions.", nil
+	}
+
+	var output strings.Builder
+	output.WriteString("Active sessions:\n\r")
+	for _, s := range sessions {
+		output.WriteString(fmt.Sprintf("    %s  %s from %s, logged in %s, last seen %s\n\r", s.Id[:8], s.Device, s.IpAddress,
+			s.IssuedAt.Local().Format(time.DateTime), s.LastSeen.Local().Format(time.DateTime)))
+	}
+	output.WriteString("Use 'sessions revoke <id>' to end a session")
+	return output.String(), nil
+}
@@ -0,0 +1,25 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/cheracc/fortress-grpc"
+)
+
+// ReloadTermsCommand represents a command an admin uses to publish a new version of the terms of service
+type ReloadTermsCommand struct {
+	// ReloadTermsFunc reads the terms of service again and returns the current version, 0 if there are none
+	ReloadTermsFunc func(*fortress.Player) (int, error)
+}
+
+// Execute reloads the terms of service from the server's terms directory. Syntax: reloadterms
+func (c *ReloadTermsCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	version, err := c.ReloadTermsFunc(player)
+	if err != nil {
+		return "", err
+	}
+	if version == 0 {
+		return "There are no terms of service, players do not have to accept any.", nil
+	}
+	return fmt.Sprintf("Version %d of the terms of service is now current, players that have not accepted it must do so before they can play.", version), nil
+}
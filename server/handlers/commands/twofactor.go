@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cheracc/fortress-grpc"
+)
+
+// SecondFactorCommand represents a command a player uses to turn two-factor authentication on or off
+type SecondFactorCommand struct {
+	// StatusFunc returns whether the player has two-factor authentication on and how many backup codes they have left
+	StatusFunc func(*fortress.Player) (bool, int)
+	// SetupFunc gives the player a new secret for their authenticator app, and returns its otpauth uri and the secret
+	SetupFunc func(*fortress.Player) (string, string, error)
+	// EnableFunc turns on two-factor authentication with a code from the new authenticator, and returns the backup codes
+	EnableFunc func(*fortress.Player, string) ([]string, error)
+	// DisableFunc turns off two-factor authentication with a current code or backup code
+	DisableFunc func(*fortress.Player, string) error
+	// BackupCodesFunc replaces the backup codes after checking a current code or backup code, and returns the new ones
+	BackupCodesFunc func(*fortress.Player, string) ([]string, error)
+}
+
+// Execute shows whether two-factor authentication is on, or with arguments:
+//
+//	2fa setup                 shows a new secret to add to an authenticator app
+//	2fa enable <code>         turns on two-factor authentication once the app shows codes
+//	2fa disable <code>        turns off two-factor authentication
+//	2fa backupcodes <code>    replaces the backup codes
+func (c *SecondFactorCommand) Execute(player *fortress.Player, args []string) (string, error) {
+	if len(args) == 0 || args[0] == "" {
+		enabled, backupCodes := c.StatusFunc(player)
+		if !enabled {
+			return "Two-factor authentication is off. Use '2fa setup' to turn it on.", nil
+		}
+		return fmt.Sprintf("Two-factor authentication is on, you have %d unused backup codes.", backupCodes), nil
+	}
+
+	switch args[0] {
+	case "setup":
+		uri, secret, err := c.SetupFunc(player)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Scan or open this link with your authenticator app:\n\r    %s\n\rOr enter the secret by hand: %s\n\r"+
+			"Then use '2fa enable <code>' with the code your app shows.", uri, secret), nil
+	case "enable":
+		if len(args) != 2 {
+			return "", fmt.Errorf("wrong number of arguments. Syntax: 2fa enable <code>")
+		}
+		codes, err := c.EnableFunc(player, args[1])
+		if err != nil {
+			return "", err
+		}
+		return "Two-factor authentication is on. " + backupCodesMessage(codes), nil
+	case "disable":
+		if len(args) != 2 {
+			return "", fmt.Errorf("wrong number of arguments. Syntax: 2fa disable <code>")
+		}
+		if err := c.DisableFunc(player, args[1]); err != nil {
+			return "", err
+		}
+		return "Two-factor authentication is off.", nil
+	case "backupcodes":
+		if len(args) != 2 {
+			return "", fmt.Errorf("wrong number of arguments. Syntax: 2fa backupcodes <code>")
+		}
+		codes, err := c.BackupCodesFunc(player, args[1])
+		if err != nil {
+			return "", err
+		}
+		return "Your old backup codes no longer work. " + backupCodesMessage(codes), nil
+	}
+	return "", fmt.Errorf("unknown subcommand %s. Syntax: 2fa [setup|enable|disable|backupcodes]", args[0])
+}
+
+func backupCodesMessage(codes []string) string {
+	return fmt.Sprintf("Keep these backup codes somewhere safe, each one logs you in once if you lose your authenticator:\n\r    %s",
+		strings.Join(codes, "\n\r    "))
+}
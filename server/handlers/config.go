@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SessionPolicyMultiple lets a player be logged in on several devices at once
+	SessionPolicyMultiple = "multiple"
+	// SessionPolicyTakeover ends a player's other sessions when they log in
+	SessionPolicyTakeover = "takeover"
+
+	// ChatPolicyDrop drops the messages that do not fit in a slow chat stream's queue
+	ChatPolicyDrop = "drop"
+	// ChatPolicyDisconnect ends a chat stream once its queue is full, the client can open a new one and catch up from the history
+	ChatPolicyDisconnect = "disconnect"
+)
+
+// Config holds the server settings. It is read from environment variables when the server starts
+type Config struct {
+	// the address the http server (oauth callbacks) listens on
+	HttpAddr string
+	// the base url that users' browsers use to reach the http server, used to build callback urls
+	PublicURL string
+	// the identity provider used when a client does not ask for a specific one
+	DefaultProvider string
+	// the issuer (iss) and audience (aud) put on session tokens. Other services that verify tokens must expect the same values
+	TokenIssuer   string
+	TokenAudience string
+	// the file that holds the keys used to sign session tokens, it is created if it does not exist
+	KeyFile string
+	// how long a session lasts without being refreshed
+	RefreshTokenLifetime time.Duration
+	// what happens to a player's other sessions when they log in: SessionPolicyMultiple or SessionPolicyTakeover
+	SessionPolicy string
+	// with SessionPolicyMultiple, the most sessions a player can have at once. The oldest is ended to make room. 0 means no limit
+	MaxSessionsPerPlayer int
+	// how long a player has to finish signing in with an identity provider (or entering a device code)
+	PendingAuthLifetime time.Duration
+	// the most logins an ip address can start per minute, 0 means no limit
+	AuthAttemptsPerMinute int
+	// the most unfinished logins an ip address can have at once, 0 means no limit
+	MaxPendingAuthsPerIP int
+	// whether the browser must finish a login from the same ip address the client started it from. This stops login links from
+	// being used on another network, but breaks logins for players whose browser and game reach the server differently
+	BindLoginToIP bool
+	// whether players can log in as a guest without signing in, which is off unless it is turned on, and how long a guest that does
+	// not link a real identity is kept after they were last seen
+	AllowGuests   bool
+	GuestLifetime time.Duration
+	// whether only players on the whitelist can log in. Other players join by logging in with an invite code, which adds them to it
+	WhitelistOnly bool
+	// the directory that holds the versions of the terms of service, as files named after their version such as 1.txt
+	TermsDir string
+	// the chat channels that always exist. Players are put in all of them when they open chat, and chat messages that do not name
+	// a channel go to the first one
+	ChatChannels []string
+	// how many of a channel's recent messages a chat stream is sent when it joins the channel, 0 sends none
+	ChatBackfill int
+	// the most messages kept in each channel's history, 0 means no limit. ChatHistoryLimits overrides it for the channels it names
+	ChatHistoryLimit  int
+	ChatHistoryLimits map[string]int
+	// how long chat messages are kept, 0 keeps them until the channel's limit pushes them out
+	ChatHistoryLifetime time.Duration
+	// how many messages can wait to be sent to each chat stream, and what happens when a stream falls that far behind:
+	// ChatPolicyDrop or ChatPolicyDisconnect. The queue is made larger if it has no room for the backfill of every default channel
+	ChatQueueSize        int
+	ChatSlowStreamPolicy string
+	// the chat filters every message goes through, in order. See the ChatFilter constants for their names
+	ChatFilters []string
+	// the longest chat message, in characters, that the length filter lets through. 0 means no limit
+	ChatMaxLength int
+	// the words that the blocklist filter masks
+	ChatBlockedWords []string
+	// how long security events are kept in the audit log, 0 keeps them forever
+	SecurityEventLifetime time.Duration
+	// players that are always admins, as player ids or provider:subject identities (such as local:alice or google:1234)
+	Admins []string
+
+	// Google oauth client credentials. Google logins are disabled if GoogleClientID is empty
+	GoogleClientID     string
+	GoogleClientSecret string
+
+	// a generic OpenID Connect provider (such as a studio SSO). It is disabled if OidcIssuer is empty
+	OidcName         string
+	OidcIssuer       string
+	OidcClientID     string
+	OidcClientSecret string
+	OidcScopes       []string
+}
+
+// LoadConfig reads the server configuration from the environment, using defaults for anything that is not set
+func LoadConfig() *Config {
+	config := &Config{
+		HttpAddr:        envString("FORTRESS_HTTP_ADDR", "localhost:8000"),
+		PublicURL:       strings.TrimRight(envString("FORTRESS_PUBLIC_URL", "http://localhost:8000"), "/"),
+		DefaultProvider: envString("FORTRESS_DEFAULT_PROVIDER", "google"),
+		KeyFile:         envString("FORTRESS_KEY_FILE", "signing_keys.pem"),
+		TokenAudience:   envString("FORTRESS_TOKEN_AUDIENCE", "fortress"),
+
+		RefreshTokenLifetime: envDuration("FORTRESS_REFRESH_TOKEN_LIFETIME", 30*24*time.Hour),
+		SessionPolicy:        envString("FORTRESS_SESSION_POLICY", SessionPolicyMultiple),
+		MaxSessionsPerPlayer: envInt("FORTRESS_MAX_SESSIONS_PER_PLAYER", 5),
+		Admins:               envList("FORTRESS_ADMINS", nil),
+		AllowGuests:          envBool("FORTRESS_ALLOW_GUESTS", false),
+		GuestLifetime:        envDuration("FORTRESS_GUEST_LIFETIME", 7*24*time.Hour),
+		WhitelistOnly:        envBool("FORTRESS_WHITELIST_ONLY", false),
+		TermsDir:             envString("FORTRESS_TERMS_DIR", "terms"),
+		ChatChannels:         envList("FORTRESS_CHAT_CHANNELS", []string{"global", "trade", "help"}),
+		ChatBackfill:         envInt("FORTRESS_CHAT_BACKFILL", 20),
+		ChatHistoryLimit:     envInt("FORTRESS_CHAT_HISTORY_LIMIT", 1000),
+		ChatHistoryLimits:    envIntMap("FORTRESS_CHAT_HISTORY_LIMITS"),
+		ChatHistoryLifetime:  envDuration("FORTRESS_CHAT_HISTORY_LIFETIME", 30*24*time.Hour),
+		ChatQueueSize:        envInt("FORTRESS_CHAT_QUEUE_SIZE", 256),
+		ChatSlowStreamPolicy: envString("FORTRESS_CHAT_SLOW_STREAM_POLICY", ChatPolicyDrop),
+		ChatFilters:          envList("FORTRESS_CHAT_FILTERS", []string{ChatFilterControls, ChatFilterLength, ChatFilterBlocklist, ChatFilterLinks}),
+		ChatMaxLength:        envInt("FORTRESS_CHAT_MAX_LENGTH", 500),
+		ChatBlockedWords:     envList("FORTRESS_CHAT_BLOCKED_WORDS", nil),
+
+		PendingAuthLifetime:   envDuration("FORTRESS_PENDING_AUTH_LIFETIME", 10*time.Minute),
+		AuthAttemptsPerMinute: envInt("FORTRESS_AUTH_ATTEMPTS_PER_MINUTE", 10),
+		MaxPendingAuthsPerIP:  envInt("FORTRESS_MAX_PENDING_AUTHS_PER_IP", 5),
+		BindLoginToIP:         envBool("FORTRESS_BIND_LOGIN_IP", false),
+		SecurityEventLifetime: envDuration("FORTRESS_SECURITY_EVENT_LIFETIME", 90*24*time.Hour),
+
+		GoogleClientID:     os.Getenv("GOOGLE_OAUTH_CLIENT_ID"),
+		GoogleClientSecret: os.Getenv("GOOGLE_OAUTH_CLIENT_SECRET"),
+
+		OidcName:         envString("FORTRESS_OIDC_NAME", "oidc"),
+		OidcIssuer:       os.Getenv("FORTRESS_OIDC_ISSUER"),
+		OidcClientID:     os.Getenv("FORTRESS_OIDC_CLIENT_ID"),
+		OidcClientSecret: os.Getenv("FORTRESS_OIDC_CLIENT_SECRET"),
+		OidcScopes:       envList("FORTRESS_OIDC_SCOPES", []string{"openid", "profile"}),
+	}
+	config.TokenIssuer = envString("FORTRESS_TOKEN_ISSUER", config.PublicURL)
+	return config
+}
+
+// JWKSURL returns the url that the keys which verify session tokens are published at
+func (c *Config) JWKSURL() string {
+	return c.PublicURL + jwksPath
+}
+
+// CallbackURL returns the url that the named identity provider redirects back to after a login
+func (c *Config) CallbackURL(provider string) string {
+	return c.PublicURL + "/auth/" + provider + "/callback"
+}
+
+// HistoryLimit returns the most messages that are kept in the named channel's history, 0 means no limit
+func (c *Config) HistoryLimit(channel string) int {
+	if limit, ok := c.ChatHistoryLimits[channel]; ok {
+		return limit
+	}
+	return c.ChatHistoryLimit
+}
+
+// envString returns the value of the environment variable key, or def if it is not set
+func envString(key string, def string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return def
+}
+
+// envInt returns the value of the environment variable key as an integer, or def if it is not set or invalid
+func envInt(key string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// envBool returns the value of the environment variable key as a boolean (such as true, 1, false or 0), or def if it is not set or invalid
+func envBool(key string, def bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// envDuration returns the value of the environment variable key parsed as a duration (such as 90m or 720h), or def if it is not set or invalid
+func envDuration(key string, def time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// envList returns the comma separated values of the environment variable key, or def if it is not set
+func envList(key string, def []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return def
+	}
+
+	list := make([]string, 0)
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// envIntMap returns the comma separated name=number pairs of the environment variable key (such as trade=200,help=5000). Pairs that
+// are not valid are skipped
+func envIntMap(key string) map[string]int {
+	values := make(map[string]int)
+	for _, pair := range envList(key, nil) {
+		name, number, _ := strings.Cut(pair, "=")
+		value, err := strconv.Atoi(strings.TrimSpace(number))
+		if err != nil {
+			continue
+		}
+		values[strings.TrimSpace(name)] = value
+	}
+	return values
+}
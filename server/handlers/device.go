@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	devicePollInterval     = 5 * time.Second
+	devicePollSlowDownStep = 5 * time.Second
+
+	// user codes leave out vowels (so they can't spell words) and characters that are easy to mix up
+	userCodeCharacters = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength     = 8
+
+	// how long a device is still told that its device code expired after the login was forgotten, rather than being sent a new one
+	expiredDeviceCodeLifetime = 1 * time.Hour
+
+	// the errors sent in AuthInfo.error while a device polls, as in RFC 8628
+	deviceErrorPending  = "authorization_pending"
+	deviceErrorSlowDown = "slow_down"
+	deviceErrorExpired  = "expired_token"
+)
+
+// startDeviceAuth begins a device login. Instead of a login link the client gets a short user code to enter at the verification url
+// in any browser, and a secret device code that it polls Authorize with until the user has signed in
+func (h *AuthHandler) startDeviceAuth(authInfo *fgrpc.AuthInfo, playerInfo *fgrpc.PlayerInfo, ipAddress string) (*fgrpc.AuthInfo, error) {
+	providerName := playerInfo.GetProvider()
+	if providerName == "" {
+		providerName = h.config.DefaultProvider
+	}
+
+	url, auth, err := h.OauthHandler.GenerateLoginURL(providerName)
+	if err != nil {
+		return nil, h.Errorf("could not start device login for %s: %s", ipAddress, err)
+	}
+
+	auth.ipAddress = ipAddress
+	auth.device = playerInfo.GetDevice()
+	auth.inviteCode = playerInfo.GetInviteCode()
+	auth.loginURL = url
+	auth.deviceCode = generateRandomState()
+	auth.userCode = h.generateUserCode()
+	auth.pollInterval = devicePollInterval
+	auth.lastPoll = time.Now()
+	if err := h.addPendingAuth(auth); err != nil {
+		return nil, err
+	}
+
+	authInfo.SessionToken = auth.deviceCode
+	authInfo.UserCode = formatUserCode(auth.userCode)
+	authInfo.VerificationURL = h.config.PublicURL + "/device"
+	authInfo.Interval = int32(devicePollInterval.Seconds())
+	h.Logf("Started device login %s for %s", authInfo.UserCode, ipAddress)
+	return authInfo, nil
+}
+
+// pollDeviceAuth answers a device that is polling Authorize with its device code. Devices that poll faster than their interval
+// are told to slow down, and their interval grows
+func (h *AuthHandler) pollDeviceAuth(auth *Auth, authInfo *fgrpc.AuthInfo) (*fgrpc.AuthInfo, error) {
+	authInfo.SessionToken = auth.deviceCode
+
+	if auth.isExpired() {
+		h.expireDeviceAuth(auth)
+		return expiredDeviceAuth(authInfo), nil
+	}
+	if auth.failure != "" {
+		h.removeAuth(auth)
+		return nil, status.Error(codes.PermissionDenied, auth.failure)
+	}
+	if auth.isComplete() {
+		return h.completeAuth(auth, authInfo), nil
+	}
+
+	interval, tooSoon := h.recordPoll(auth)
+	if tooSoon {
+		authInfo.Error = deviceErrorSlowDown
+	} else {
+		authInfo.Error = deviceErrorPending
+	}
+	authInfo.Interval = int32(interval.Seconds())
+	return authInfo, nil
+}
+
+// expiredDeviceAuth tells a polling device that its login expired, so it stops polling and asks the user to start again
+func expiredDeviceAuth(authInfo *fgrpc.AuthInfo) *fgrpc.AuthInfo {
+	authInfo.SessionToken = ""
+	authInfo.Error = deviceErrorExpired
+	return authInfo
+}
+
+// expireDeviceAuth forgets an expired device login but remembers its device code for expiredDeviceCodeLifetime, so a device that
+// polls it again gets expired_token instead of a new login it never asked for
+func (a *AuthenticatingPlayers) expireDeviceAuth(auth *Auth) {
+	a.Lock()
+	delete(a.auths, auth.oauthState)
+	delete(a.auths, auth.deviceCode)
+	a.expiredDeviceCodes[auth.deviceCode] = time.Now()
+	a.Unlock()
+}
+
+// isExpiredDeviceCode returns whether the token is the device code of a device login that expired
+func (a *AuthenticatingPlayers) isExpiredDeviceCode(token string) bool {
+	a.RLock()
+	defer a.RUnlock()
+	_, ok := a.expiredDeviceCodes[token]
+	return ok
+}
+
+// DeviceVerification serves the page where users enter the code shown by their device. A valid code redirects them to
+// sign in with the identity provider that the device asked for
+func (h *OauthHandler) DeviceVerification(w http.ResponseWriter, r *http.Request) {
+	entered := r.FormValue("user_code")
+	if entered == "" {
+		writeDeviceForm(w, "")
+		return
+	}
+	if err := h.recordAuthAttempt(r.RemoteAddr); err != nil { // user codes are short, so guessing them must be slow
+		w.WriteHeader(http.StatusTooManyRequests)
+		writeDeviceForm(w, "Too many attempts from your address, please wait a minute and try again.")
+		return
+	}
+
+	auth := h.GetAuthByUserCode(normalizeUserCode(entered))
+	if auth == nil || auth.callbackUsed || auth.isComplete() || auth.isExpired() {
+		w.WriteHeader(http.StatusNotFound)
+		writeDeviceForm(w, "That code is not valid or has expired. Check your device for the current code.")
+		return
+	}
+
+	h.Logf("Device login %s was opened in a browser, redirecting to %s", formatUserCode(auth.userCode), auth.provider)
+	http.Redirect(w, r, auth.loginURL, http.StatusFound)
+}
+
+func writeDeviceForm(w http.ResponseWriter, message string) {
+	fmt.Fprintf(w, "<html><body><h1>Log in a device</h1><p>%s</p>"+
+		"<form method=\"post\" action=\"/device\">Enter the code shown on your device: <input name=\"user_code\" autofocus autocomplete=\"off\"> "+
+		"<input type=\"submit\" value=\"Continue\"></form></body></html>", html.EscapeString(message))
+}
+
+// generateUserCode returns a random user code that no pending login is using
+func (h *AuthHandler) generateUserCode() string {
+	for {
+		code := randomCode(userCodeCharacters, userCodeLength)
+		if h.GetAuthByUserCode(code) == nil {
+			return code
+		}
+	}
+}
+
+// formatUserCode splits a user code in two halves to make it easier to read, such as BCDF-GHJK
+func formatUserCode(code string) string {
+	return code[:len(code)/2] + "-" + code[len(code)/2:]
+}
+
+// normalizeUserCode undoes formatUserCode and anything else a user might type around the code
+func normalizeUserCode(entered string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(entered)))
+}
@@ -15,14 +15,16 @@ type GrpcServer struct {
 	*fortress.Logger
 }
 
-// NewGrpcServer constructs a new GrpcServer with the given logger
-func NewGrpcServer(logger *fortress.Logger) GrpcServer {
+// NewGrpcServer constructs a new GrpcServer with the given logger. Every call is authenticated by the AuthHandler's interceptors
+func NewGrpcServer(auth *AuthHandler, logger *fortress.Logger) GrpcServer {
 	listener, err := net.Listen("tcp", ":50051")
 	if err != nil {
 		logger.Fatalf("failed to start a tcp listener on port 50051: %v", err)
 	}
 
-	server := grpc.NewServer()
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(auth.UnaryAuthInterceptor),
+		grpc.ChainStreamInterceptor(auth.StreamAuthInterceptor))
 
 	return GrpcServer{server, listener, logger}
 }
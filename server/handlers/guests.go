@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/cheracc/fortress-grpc"
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	guestNamePrefix    = "Guest-"
+	guestNameAttempts  = 10
+	guestSweepInterval = 1 * time.Hour
+)
+
+// authorizeGuest logs the client in as a new guest player with a generated name. Guests have the limited permissions of RoleGuest
+// until they link a real identity, which turns the same player into a full account (see upgradeGuest)
+func (h *AuthHandler) authorizeGuest(authInfo *fgrpc.AuthInfo, playerInfo *fgrpc.PlayerInfo, ipAddress string) (*fgrpc.AuthInfo, error) {
+	if !h.config.AllowGuests || h.config.WhitelistOnly {
+		return nil, status.Error(codes.FailedPrecondition, "this server does not allow guests, please log in with an identity provider")
+	}
+	if err := h.checkAuthLimits(ipAddress); err != nil {
+		return nil, err
+	}
+
+	name := h.generateGuestName()
+	if name == "" {
+		return nil, status.Error(codes.Unavailable, "could not find a free guest name, please try again")
+	}
+	guest := fortress.NewPlayer() // never take the id the client sent, a guest is always a new player
+	guest.SetIdentity(fortress.GuestProvider, guest.GetPlayerId())
+	guest.SetName(name)
+	h.registerNewPlayer(guest)
+
+	refreshToken, err := h.authorizeSession(guest, playerInfo.GetDevice(), ipAddress, "", "")
+	if err != nil {
+		return nil, err
+	}
+	h.Logf("Guest %s(%s) logged in from %s", guest.GetName(), guest.GetPlayerId(), ipAddress)
+
+	authInfo.PlayerID = guest.GetPlayerId()
+	authInfo.SessionToken = guest.GetSessionToken()
+	authInfo.RefreshToken = refreshToken
+	authInfo.LoginURL = ""
+	return authInfo, nil
+}
+
+// generateGuestName returns an unused name such as Guest-123456, or "" if it could not find one
+func (h *AuthHandler) generateGuestName() string {
+	for range guestNameAttempts {
+		n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+		if err != nil {
+			return ""
+		}
+		name := fmt.Sprintf("%s%06d", guestNamePrefix, n)
+		if h.SqliteHandler.LookupPlayerIdByName(name) == "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// upgradeGuest turns a guest into a full account once they have linked a real identity. The player keeps their id and everything
+// that belongs to it, only their identity changes
+func (h *AuthHandler) upgradeGuest(guest *fortress.Player, identity *Identity) {
+	guest.SetIdentity(identity.Provider, identity.Subject)
+	if identity.Provider == "google" {
+		guest.SetGoogleId(identity.Subject)
+	}
+	h.SqliteHandler.UpdatePlayerToDb(guest)
+	h.Logf("Guest %s(%s) linked a %s identity and is now a full account", guest.GetName(), guest.GetPlayerId(), identity.Provider)
+}
+
+// startGuestSweeper periodically deletes guests that have not been seen for longer than config.GuestLifetime
+func (h *AuthHandler) startGuestSweeper() {
+	go func() {
+		for {
+			time.Sleep(guestSweepInterval)
+			if removed := h.purgeInactiveGuests(); removed > 0 {
+				h.Logf("Removed %d inactive guests", removed)
+			}
+		}
+	}()
+}
+
+// purgeInactiveGuests deletes every guest that has not used any of their sessions within config.GuestLifetime, and returns how many
+func (h *AuthHandler) purgeInactiveGuests() int {
+	removed := 0
+	for _, guestId := range h.SqliteHandler.LookupInactiveGuestIds(time.Now().UTC().Add(-h.config.GuestLifetime)) {
+		if h.IsOnline(PlayerFilter{playerId: guestId}) {
+			continue
+		}
+		h.sessions.RevokeAll(guestId, "", "the guest account was removed for inactivity")
+		h.SqliteHandler.DeleteGuestDbRecords(guestId)
+		removed++
+	}
+	return removed
+}
+
+// LookupInactiveGuestIds returns the ids of guests that were created before the cutoff and have not used a session since
+func (h *SqliteHandler) LookupInactiveGuestIds(cutoff time.Time) []string {
+	rows, err := h.db.Query("SELECT player_id FROM players p WHERE provider = ? AND created_at < ? "+
+		"AND NOT EXISTS (SELECT 1 FROM sessions s WHERE s.player_id = p.player_id AND s.last_seen >= ?)",
+		fortress.GuestProvider, cutoff.Unix(), cutoff.Unix())
+	if err != nil {
+		h.Errorf("SQL: could not look up inactive guests: %s", err)
+		return nil
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			h.Errorf("SQL: %s", err)
+			return nil
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// DeleteGuestDbRecords deletes the guest's player record along with their sessions and roles. It does nothing if the player
+// is no longer a guest
+func (h *SqliteHandler) DeleteGuestDbRecords(guestId string) {
+	result, err := h.db.Exec("DELETE FROM players WHERE player_id = ? AND provider = ?", guestId, fortress.GuestProvider)
+	if err != nil {
+		h.Errorf("SQL: could not delete guest %s: %s", guestId, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return
+	}
+	if _, err := h.db.Exec("DELETE FROM sessions WHERE player_id = ?", guestId); err != nil {
+		h.Errorf("SQL: could not delete sessions of guest %s: %s", guestId, err)
+	}
+	if _, err := h.db.Exec("DELETE FROM player_roles WHERE player_id = ?", guestId); err != nil {
+		h.Errorf("SQL: could not delete roles of guest %s: %s", guestId, err)
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cheracc/fortress-grpc"
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// loginGuest logs in as a new guest and returns the guest, or the error
+func (s *testServer) loginGuest(clientId string) (*fortress.Player, error) {
+	authInfo, err := s.auth.Authorize(context.Background(), &fgrpc.PlayerInfo{Id: clientId, Provider: fortress.GuestProvider})
+	if err != nil {
+		return nil, err
+	}
+	return s.auth.FindPlayer(authInfo.GetPlayerID()), nil
+}
+
+func TestGuestLogin(t *testing.T) {
+	tests := []struct {
+		name          string
+		allowGuests   bool
+		whitelistOnly bool
+		want          codes.Code
+	}{
+		{"guests are off", false, false, codes.FailedPrecondition},
+		{"guests are on", true, false, codes.OK},
+		{"guests are on, whitelist only", true, true, codes.FailedPrecondition},
+	}
+	for _, test := range tests {
+		s := newTestServer(t, func(config *Config) {
+			config.AllowGuests = test.allowGuests
+			config.WhitelistOnly = test.whitelistOnly
+		})
+		clientId := uuid.NewString()
+		guest, err := s.loginGuest(clientId)
+		if status.Code(err) != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.want)
+		}
+		if guest == nil {
+			continue
+		}
+		if !guest.IsGuest() || !strings.HasPrefix(guest.GetName(), guestNamePrefix) {
+			t.Errorf("%s: logged in as %s with provider %s, want a guest", test.name, guest.GetName(), guest.GetProvider())
+		}
+		if guest.GetPlayerId() == clientId {
+			t.Errorf("%s: the guest took the player id the client sent", test.name)
+		}
+	}
+}
+
+// TestGuestUpgrade checks that a guest can only read chat until they link an identity, which keeps their player
+func TestGuestUpgrade(t *testing.T) {
+	s := newTestServer(t, func(config *Config) { config.AllowGuests = true })
+	guest, err := s.loginGuest("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := s.callContext(t, guest.GetSessionToken())
+	if _, _, err := s.chat.checkCanChat(ctx); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("a guest could chat: %v", err)
+	}
+
+	s.auth.OauthHandler.completeLink(httptest.NewRecorder(), &Auth{linkPlayerId: guest.GetPlayerId()}, &Identity{Provider: "oidc", Subject: "g1"})
+	if guest.IsGuest() || guest.GetProvider() != "oidc" {
+		t.Fatalf("the guest is still a guest after linking an identity")
+	}
+	if player, _ := s.auth.getPlayerByIdentity("oidc", "g1"); player.GetPlayerId() != guest.GetPlayerId() {
+		t.Errorf("the linked identity logs in to another player")
+	}
+	if _, _, err := s.chat.checkCanChat(ctx); err != nil {
+		t.Errorf("an upgraded guest could not chat: %v", err)
+	}
+}
+
+func TestPurgeInactiveGuests(t *testing.T) {
+	s := newTestServer(t, func(config *Config) { config.AllowGuests = true })
+	tests := []struct {
+		name     string
+		online   bool
+		upgrade  bool
+		inactive bool
+		removed  bool
+	}{
+		{"inactive guest", false, false, true, true},
+		{"active guest", false, false, false, false},
+		{"inactive guest that is online", true, false, true, false},
+		{"inactive guest that linked an identity", false, true, true, false},
+	}
+	guests := make([]*fortress.Player, len(tests))
+	for i, test := range tests {
+		guest, err := s.loginGuest("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.upgrade {
+			s.auth.upgradeGuest(guest, &Identity{Provider: "oidc", Subject: guest.GetPlayerId()})
+		}
+		if test.inactive {
+			db := s.auth.SqliteHandler.db
+			db.Exec("UPDATE players SET created_at = 0 WHERE player_id = ?", guest.GetPlayerId())
+			db.Exec("UPDATE sessions SET last_seen = 0 WHERE player_id = ?", guest.GetPlayerId())
+		}
+		if !test.online {
+			s.auth.RemoveOnlinePlayer(guest.GetPlayerId())
+		}
+		guests[i] = guest
+	}
+
+	if removed := s.auth.purgeInactiveGuests(); removed != 1 {
+		t.Errorf("%d guests were removed, want 1", removed)
+	}
+	for i, test := range tests {
+		exists := s.auth.SqliteHandler.LookupPlayerFromDb(PlayerFilter{playerId: guests[i].GetPlayerId()}) != nil
+		if exists == test.removed {
+			t.Errorf("%s: the player still exists %v, want %v", test.name, exists, !test.removed)
+		}
+	}
+}
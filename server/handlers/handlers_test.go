@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/cheracc/fortress-grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// testLogger is shared by every test server, fortress.NewLogger writes log.txt in the directory TestMain moves into
+var testLogger = sync.OnceValue(fortress.NewLogger)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "fortress-handlers")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testServer holds the handlers of a server with its own database, wired together like in main but without the listeners
+type testServer struct {
+	auth       *AuthHandler
+	roles      *RoleHandler
+	moderation *ModerationHandler
+	bots       *BotHandler
+	invites    *InviteHandler
+	chat       *ChatHandler
+}
+
+// newTestServer returns a server with an empty database and the default config, which configure (if it is not nil) can change first
+func newTestServer(t *testing.T, configure func(*Config)) *testServer {
+	t.Helper()
+	config := LoadConfig()
+	config.KeyFile = filepath.Join(t.TempDir(), "signing_keys.pem")
+	config.TermsDir = t.TempDir()
+	config.AuthAttemptsPerMinute = 0
+	if configure != nil {
+		configure(config)
+	}
+
+	logger := testLogger()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite := &SqliteHandler{db, logger}
+	t.Cleanup(sqlite.CloseDb)
+	sqlite.InitializeDatabase()
+
+	players := NewPlayerHandler(sqlite, logger)
+	auth := newAuthHandler(players, config, logger)
+	players.SetAuthHandler(auth)
+	roles := NewRoleHandler(sqlite, config, logger)
+	return &testServer{auth, roles, NewModerationHandler(auth, roles, logger), NewBotHandler(auth, roles, logger), NewInviteHandler(auth, logger),
+		NewChatHandler(logger, auth, roles)}
+}
+
+// newPlayer returns a new player with the identity local:name
+func (s *testServer) newPlayer(t *testing.T, name string) *fortress.Player {
+	t.Helper()
+	player, isNew := s.auth.getPlayerByIdentity(localProvider, name)
+	if !isNew {
+		t.Fatalf("the player %s already exists", name)
+	}
+	player.SetName(name)
+	s.auth.SqliteHandler.UpdatePlayerToDb(player)
+	return player
+}
+
+// login starts a session for the player and returns its refresh token. The player's session token is set on them
+func (s *testServer) login(t *testing.T, player *fortress.Player) string {
+	t.Helper()
+	refreshToken, err := s.auth.AuthorizePlayer(player, "test", "", "")
+	if err != nil {
+		t.Fatalf("%s could not log in: %v", player.GetName(), err)
+	}
+	return refreshToken
+}
+
+// callContext returns the context of a call made with the session token, as the auth interceptor passes it to the handlers
+func (s *testServer) callContext(t *testing.T, sessionToken string) context.Context {
+	t.Helper()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(authorizationMetadataKey, "Bearer "+sessionToken))
+	ctx, err := s.auth.authenticate(ctx, nil)
+	if err != nil {
+		t.Fatalf("the session token was not accepted: %v", err)
+	}
+	return ctx
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cheracc/fortress-grpc"
+	"github.com/cheracc/fortress-grpc/server/handlers/commands"
+)
+
+// how recently a player must have logged in to use commands with RequiresRecentLogin, such as linking identities
+const recentLoginWindow = 10 * time.Minute
+
+// A LinkedIdentity is an account with an identity provider that a player can log in with. A player can have several, but each
+// identity belongs to only one player
+type LinkedIdentity struct {
+	provider string
+	subject  string
+	playerId string
+	linkedAt time.Time
+}
+
+// getPlayerByIdentity returns the player the identity is linked to. If no player has the identity yet, a new player is created
+// with it. It returns whether the player is new
+func (h *AuthHandler) getPlayerByIdentity(provider string, subject string) (*fortress.Player, bool) {
+	if playerId := h.SqliteHandler.LookupIdentityOwner(provider, subject); playerId != "" {
+		return h.GetPlayer(PlayerFilter{playerId: playerId}, true)
+	}
+
+	player, isNew := h.GetPlayer(PlayerFilter{provider: provider, subject: subject}, true)
+	if isNew {
+		player.SetIdentity(provider, subject)
+		if provider == "google" {
+			player.SetGoogleId(subject)
+		}
+		h.SqliteHandler.UpdatePlayerToDb(player)
+	}
+	h.SqliteHandler.LinkIdentity(&LinkedIdentity{provider: provider, subject: subject, playerId: player.GetPlayerId(), linkedAt: time.Now().UTC()})
+	return player, isNew
+}
+
+// StartLink begins linking another identity to the player. It returns a login url for the provider, once the player signs in
+// there the identity is linked to them. This error gets passed back to the user/client
+func (h *AuthHandler) StartLink(player *fortress.Player, providerName string) (string, error) {
+	if player.IsBot() {
+		return "", fmt.Errorf("bots can not link identities")
+	}
+	if h.OauthHandler.GetProvider(providerName) == nil {
+		return "", fmt.Errorf("there is no identity provider named %s", providerName)
+	}
+
+	url, auth, err := h.OauthHandler.GenerateLoginURL(providerName)
+	if err != nil {
+		return "", h.Errorf("could not start linking %s for player %s: %s", providerName, player.GetPlayerId(), err)
+	}
+	auth.linkPlayerId = player.GetPlayerId()
+	auth.createdAt = time.Now()
+	auth.expiresAt = auth.createdAt.Add(h.config.PendingAuthLifetime)
+	h.AddAuth(auth.oauthState, auth)
+	h.Logf("Player %s(%s) started linking an identity from %s", player.GetName(), player.GetPlayerId(), providerName)
+	return url, nil
+}
+
+// completeLink links the identity the player signed in with to the player that started the link, unless it already belongs to someone
+func (h *OauthHandler) completeLink(w http.ResponseWriter, auth *Auth, identity *Identity) {
+	h.removeAuth(auth)
+
+	owner := h.SqliteHandler.LookupIdentityOwner(identity.Provider, identity.Subject)
+	if owner == auth.linkPlayerId {
+		writePage(w, "This account is already linked to you.", "You may close this tab")
+		return
+	}
+	if owner != "" {
+		h.Logf("Player %s tried to link %s identity %s, which belongs to player %s", auth.linkPlayerId, identity.Provider, identity.Subject, owner)
+		writeErrorPage(w, http.StatusConflict, "This account is linked to another player.", "Unlink it from the other player first.")
+		return
+	}
+
+	h.SqliteHandler.LinkIdentity(&LinkedIdentity{provider: identity.Provider, subject: identity.Subject, playerId: auth.linkPlayerId, linkedAt: time.Now().UTC()})
+	h.Logf("Linked %s identity %s to player %s", identity.Provider, identity.Subject, auth.linkPlayerId)
+	if player := h.FindPlayer(auth.linkPlayerId); player != nil && player.IsGuest() {
+		h.upgradeGuest(player, identity)
+		writePage(w, "Your "+identity.Provider+" account is now linked.", "You are no longer a guest, you may close this tab")
+		return
+	}
+	writePage(w, "Your "+identity.Provider+" account is now linked.", "You may close this tab")
+}
+
+// Unlink removes one of the player's identities. The identity is given as provider, or as provider:subject when the player has more
+// than one identity from the provider. The last identity can never be unlinked. This error gets passed back to the user/client
+func (h *AuthHandler) Unlink(player *fortress.Player, which string) error {
+	identities := h.SqliteHandler.LookupIdentities(player.GetPlayerId())
+	provider, subject, bySubject := strings.Cut(which, ":")
+
+	matches := make([]*LinkedIdentity, 0)
+	for _, identity := range identities {
+		if identity.provider == provider && (!bySubject || identity.subject == subject) {
+			matches = append(matches, identity)
+		}
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("you have no identity %s linked, use 'identities' to see them", which)
+	}
+	if len(matches) > 1 {
+		return fmt.Errorf("you have more than one %s identity linked, use 'unlink %s:<subject>' to choose one", provider, provider)
+	}
+	if len(identities) <= 1 {
+		return fmt.Errorf("you can not unlink your only identity, you would not be able to log in")
+	}
+
+	unlinked := matches[0]
+	h.SqliteHandler.UnlinkIdentity(unlinked.provider, unlinked.subject)
+	if player.GetProvider() == unlinked.provider && player.GetSubject() == unlinked.subject {
+		// the player record still pointed at the identity, so move it to the oldest one they have left
+		for _, identity := range identities {
+			if identity != unlinked {
+				player.SetIdentity(identity.provider, identity.subject)
+				break
+			}
+		}
+		if unlinked.provider == "google" {
+			player.SetGoogleId("")
+		}
+		h.SqliteHandler.UpdatePlayerToDb(player)
+	}
+	if unlinked.provider == localProvider {
+		h.SqliteHandler.DeleteLocalAccount(unlinked.subject)
+	}
+
+	h.Logf("Player %s(%s) unlinked %s identity %s", player.GetName(), player.GetPlayerId(), unlinked.provider, unlinked.subject)
+	return nil
+}
+
+// ListIdentities describes the identities linked to the player
+func (h *AuthHandler) ListIdentities(player *fortress.Player) []commands.IdentityInfo {
+	infos := make([]commands.IdentityInfo, 0)
+	for _, identity := range h.SqliteHandler.LookupIdentities(player.GetPlayerId()) {
+		infos = append(infos, commands.IdentityInfo{Provider: identity.provider, Subject: identity.subject, LinkedAt: identity.linkedAt})
+	}
+	return infos
+}
+
+// isRecentLogin returns whether the session was logged into within the recentLoginWindow. Refreshing a session does not count
+func (h *AuthHandler) isRecentLogin(sessionId string) bool {
+	session := h.sessions.GetSession(sessionId)
+	return session != nil && time.Since(session.GetIssuedAt()) < recentLoginWindow
+}
+
+func (h *SqliteHandler) initializeIdentitiesTable() {
+	_, err := h.db.Exec("CREATE TABLE IF NOT EXISTS identities (" +
+		"provider TEXT, " +
+		"subject TEXT, " +
+		"player_id TEXT, " +
+		"linked_at INTEGER, " +
+		"PRIMARY KEY (provider, subject))")
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+
+	// players from before identities could be linked have theirs in the players table
+	result, err := h.db.Exec("INSERT OR IGNORE INTO identities (provider, subject, player_id, linked_at) "+
+		"SELECT provider, subject, player_id, created_at FROM players WHERE provider != '' AND subject != '' AND provider NOT IN (?, ?)", fortress.BotProvider, fortress.GuestProvider)
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		h.Logf("migrated %d player identities to the identities table", n)
+	}
+}
+
+func (h *SqliteHandler) LinkIdentity(identity *LinkedIdentity) {
+	_, err := h.db.Exec("INSERT OR IGNORE INTO identities (provider, subject, player_id, linked_at) VALUES (?, ?, ?, ?)",
+		identity.provider, identity.subject, identity.playerId, identity.linkedAt.Unix())
+	if err != nil {
+		h.Errorf("SQL: could not link %s identity %s to player %s: %s", identity.provider, identity.subject, identity.playerId, err)
+	}
+}
+
+func (h *SqliteHandler) UnlinkIdentity(provider string, subject string) {
+	if _, err := h.db.Exec("DELETE FROM identities WHERE provider = ? AND subject = ?", provider, subject); err != nil {
+		h.Errorf("SQL: could not unlink %s identity %s: %s", provider, subject, err)
+	}
+}
+
+// LookupIdentityOwner returns the id of the player the identity is linked to, or "" if it is not linked to anyone
+func (h *SqliteHandler) LookupIdentityOwner(provider string, subject string) string {
+	var playerId string
+	err := h.db.QueryRow("SELECT player_id FROM identities WHERE provider = ? AND subject = ?", provider, subject).Scan(&playerId)
+	if err != nil && err != sql.ErrNoRows {
+		h.Errorf("SQL: could not look up %s identity %s: %s", provider, subject, err)
+	}
+	return playerId
+}
+
+// LookupIdentities returns the identities linked to the player, oldest first
+func (h *SqliteHandler) LookupIdentities(playerId string) []*LinkedIdentity {
+	rows, err := h.db.Query("SELECT provider, subject, player_id, linked_at FROM identities WHERE player_id = ? ORDER BY linked_at, provider", playerId)
+	if err != nil {
+		h.Errorf("SQL: could not look up identities of player %s: %s", playerId, err)
+		return nil
+	}
+	defer rows.Close()
+
+	identities := make([]*LinkedIdentity, 0)
+	for rows.Next() {
+		identity := &LinkedIdentity{}
+		var linked int64
+		if err := rows.Scan(&identity.provider, &identity.subject, &identity.playerId, &linked); err != nil {
+			h.Errorf("SQL: %s", err)
+			return nil
+		}
+		identity.linkedAt = time.Unix(linked, 0)
+		identities = append(identities, identity)
+	}
+	return identities
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLinkIdentity(t *testing.T) {
+	s := newTestServer(t, nil)
+	alice, bob := s.newPlayer(t, "alice"), s.newPlayer(t, "bob")
+	tests := []struct {
+		name     string
+		linkedBy string
+		identity *Identity
+		status   int
+		owner    string
+	}{
+		{"new identity", alice.GetPlayerId(), &Identity{Provider: "oidc", Subject: "a1"}, http.StatusOK, alice.GetPlayerId()},
+		{"identity already linked to the player", alice.GetPlayerId(), &Identity{Provider: "oidc", Subject: "a1"}, http.StatusOK, alice.GetPlayerId()},
+		{"identity linked to another player", bob.GetPlayerId(), &Identity{Provider: "oidc", Subject: "a1"}, http.StatusConflict, alice.GetPlayerId()},
+		{"second identity from the same provider", alice.GetPlayerId(), &Identity{Provider: "oidc", Subject: "a2"}, http.StatusOK, alice.GetPlayerId()},
+		{"local account of another player", alice.GetPlayerId(), &Identity{Provider: localProvider, Subject: "bob"}, http.StatusConflict, bob.GetPlayerId()},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		s.auth.OauthHandler.completeLink(w, &Auth{linkPlayerId: test.linkedBy}, test.identity)
+		if w.Code != test.status {
+			t.Errorf("%s: got status %d, want %d", test.name, w.Code, test.status)
+		}
+		if owner := s.auth.SqliteHandler.LookupIdentityOwner(test.identity.Provider, test.identity.Subject); owner != test.owner {
+			t.Errorf("%s: the identity belongs to %s, want %s", test.name, owner, test.owner)
+		}
+	}
+
+	if player, isNew := s.auth.getPlayerByIdentity("oidc", "a2"); isNew || player.GetPlayerId() != alice.GetPlayerId() {
+		t.Errorf("logging in with a linked identity did not find the player it is linked to")
+	}
+}
+
+func TestUnlinkIdentity(t *testing.T) {
+	s := newTestServer(t, nil)
+	alice := s.newPlayer(t, "alice")
+	s.newPlayer(t, "bob")
+	for _, subject := range []string{"a1", "a2"} {
+		s.auth.OauthHandler.completeLink(httptest.NewRecorder(), &Auth{linkPlayerId: alice.GetPlayerId()}, &Identity{Provider: "oidc", Subject: subject})
+	}
+
+	tests := []struct {
+		name   string
+		player string
+		which  string
+		ok     bool
+	}{
+		{"identity the player does not have", "alice", "google", false},
+		{"provider with two identities", "alice", "oidc", false},
+		{"identity by subject", "alice", "oidc:a1", true},
+		{"identity that was unlinked", "alice", "oidc:a1", false},
+		{"identity of another player", "alice", "local:bob", false},
+		{"identity the player logs in with", "alice", "local", true},
+		{"last identity", "alice", "oidc", false},
+		{"only identity", "bob", "local", false},
+	}
+	for _, test := range tests {
+		err := s.auth.Unlink(s.auth.FindPlayer(test.player), test.which)
+		if (err == nil) != test.ok {
+			t.Errorf("%s: got %v, want ok %v", test.name, err, test.ok)
+		}
+	}
+
+	identities := s.auth.SqliteHandler.LookupIdentities(alice.GetPlayerId())
+	if len(identities) != 1 || identities[0].subject != "a2" {
+		t.Errorf("alice has %d identities left, want only oidc:a2", len(identities))
+	}
+	if player := s.auth.FindPlayer("alice"); player.GetProvider() != "oidc" || player.GetSubject() != "a2" {
+		t.Errorf("alice logs in with %s:%s, want the identity that is left", player.GetProvider(), player.GetSubject())
+	}
+}
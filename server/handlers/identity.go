@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const oauthGoogleUrlAPI = "https://www.googleapis.com/oauth2/v2/userinfo?access_token="
+
+// An IdentityProvider is an external service that players sign in with. The OauthHandler sends players to
+// the provider's login page and hands the code from the callback back to the provider to find out who they are
+type IdentityProvider interface {
+	// Name returns the unique name of this provider. It is part of the callback url and is stored with each identity
+	Name() string
+	// LoginURL builds the url a player visits to sign in. The state is returned to the callback unchanged, only the PKCE challenge
+	// of the verifier is sent, and the nonce comes back in the provider's id token
+	LoginURL(state string, verifier string, nonce string) string
+	// Identify exchanges the code received by the callback for the identity of the player that signed in. The verifier and nonce
+	// must be the ones the login url was built with. It returns an error if the provider does not say who the player is
+	Identify(ctx context.Context, code string, verifier string, nonce string) (*Identity, error)
+}
+
+// An Identity is the result of a successful login with an IdentityProvider
+type Identity struct {
+	// the name of the provider that issued this identity
+	Provider string
+	// the provider's unique and stable id for the user
+	Subject string
+	// a url of the user's picture, if the provider has one
+	AvatarUrl string
+}
+
+// GoogleProvider signs players in with their Google account
+type GoogleProvider struct {
+	config *oauth2.Config
+}
+
+type googleJson struct {
+	Id      string `json:"id"`
+	Picture string `json:"picture"`
+}
+
+// NewGoogleProvider constructs a GoogleProvider using the given oauth client credentials
+func NewGoogleProvider(clientId string, clientSecret string, redirectURL string) *GoogleProvider {
+	return &GoogleProvider{&oauth2.Config{
+		RedirectURL:  redirectURL,
+		ClientID:     clientId,
+		ClientSecret: clientSecret,
+		Scopes:       []string{"openid"},
+		Endpoint:     google.Endpoint,
+	}}
+}
+
+func (p *GoogleProvider) Name() string {
+	return "google"
+}
+
+func (p *GoogleProvider) LoginURL(state string, verifier string, nonce string) string {
+	return authCodeURL(p.config, state, verifier, nonce)
+}
+
+func (p *GoogleProvider) Identify(ctx context.Context, code string, verifier string, nonce string) (*Identity, error) {
+	token, err := exchangeCode(ctx, p.config, code, verifier, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	data := googleJson{}
+	if err := getJson(ctx, oauthGoogleUrlAPI+token.AccessToken, "", &data); err != nil {
+		return nil, fmt.Errorf("failed getting user info: %w", err)
+	}
+
+	if data.Id == "" {
+		return nil, fmt.Errorf("user info from %s has no id", p.Name())
+	}
+	return &Identity{Provider: p.Name(), Subject: data.Id, AvatarUrl: data.Picture}, nil
+}
+
+// OidcProvider signs players in with any OpenID Connect provider. Its endpoints are found through the issuer's discovery document
+type OidcProvider struct {
+	name        string
+	config      *oauth2.Config
+	userInfoURL string
+}
+
+type oidcDiscoveryJson struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+type oidcUserInfoJson struct {
+	Subject string `json:"sub"`
+	Picture string `json:"picture"`
+}
+
+// NewOidcProvider constructs an OidcProvider for the given issuer. It fetches the issuer's discovery document, so
+// it returns an error if the issuer can not be reached or does not publish the endpoints we need
+func NewOidcProvider(ctx context.Context, name string, issuer string, clientId string, clientSecret string, redirectURL string, scopes []string) (*OidcProvider, error) {
+	discovery := oidcDiscoveryJson{}
+	wellKnown := strings.TrimRight(issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJson(ctx, wellKnown, "", &discovery); err != nil {
+		return nil, fmt.Errorf("could not load discovery document for %s: %w", issuer, err)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.UserInfoEndpoint == "" {
+		return nil, fmt.Errorf("discovery document for %s is missing a required endpoint", issuer)
+	}
+
+	return &OidcProvider{
+		name: name,
+		config: &oauth2.Config{
+			RedirectURL:  redirectURL,
+			ClientID:     clientId,
+			ClientSecret: clientSecret,
+			Scopes:       scopes,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  discovery.AuthorizationEndpoint,
+				TokenURL: discovery.TokenEndpoint,
+			},
+		},
+		userInfoURL: discovery.UserInfoEndpoint,
+	}, nil
+}
+
+func (p *OidcProvider) Name() string {
+	return p.name
+}
+
+func (p *OidcProvider) LoginURL(state string, verifier string, nonce string) string {
+	return authCodeURL(p.config, state, verifier, nonce)
+}
+
+func (p *OidcProvider) Identify(ctx context.Context, code string, verifier string, nonce string) (*Identity, error) {
+	token, err := exchangeCode(ctx, p.config, code, verifier, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	data := oidcUserInfoJson{}
+	if err := getJson(ctx, p.userInfoURL, token.AccessToken, &data); err != nil {
+		return nil, fmt.Errorf("failed getting user info: %w", err)
+	}
+
+	if data.Subject == "" {
+		return nil, fmt.Errorf("user info from %s has no subject", p.Name())
+	}
+	return &Identity{Provider: p.Name(), Subject: data.Subject, AvatarUrl: data.Picture}, nil
+}
+
+// authCodeURL builds a login url that carries the PKCE challenge of the verifier and the nonce
+func authCodeURL(config *oauth2.Config, state string, verifier string, nonce string) string {
+	return config.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier), oauth2.SetAuthURLParam("nonce", nonce))
+}
+
+// exchangeCode exchanges the code from the callback for a token, proving with the PKCE verifier that we are the ones who started the
+// login. If the provider sends an id token its nonce must be the one we sent, so a code from another login can't be slipped in
+func exchangeCode(ctx context.Context, config *oauth2.Config, code string, verifier string, nonce string) (*oauth2.Token, error) {
+	if code == "" {
+		return nil, fmt.Errorf("the callback did not include a code")
+	}
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange wrong: %w", err)
+	}
+
+	idToken, _ := token.Extra("id_token").(string)
+	if idToken == "" {
+		return token, nil
+	}
+	// the id token came straight from the token endpoint over https, so its signature does not need to be checked
+	// (OpenID Connect Core 3.1.3.7), only that it belongs to this login
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(idToken, claims); err != nil {
+		return nil, fmt.Errorf("could not read id token: %w", err)
+	}
+	if claimedNonce, _ := claims["nonce"].(string); claimedNonce != nonce {
+		return nil, fmt.Errorf("the id token was not issued for this login (nonce does not match)")
+	}
+	return token, nil
+}
+
+// getJson fetches url and decodes the json response into v. If bearerToken is not empty it is sent in the Authorization header
+func getJson(ctx context.Context, url string, bearerToken string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
+	}
+
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	contents, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("failed read response: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, response.Status)
+	}
+	return json.Unmarshal(contents, v)
+}
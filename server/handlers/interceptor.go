@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cheracc/fortress-grpc"
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// the metadata key that clients send their session token in, as "Bearer <token>"
+const authorizationMetadataKey = "authorization"
+
+// unauthenticatedMethods can be called without a session token, since they are how a client gets one
+var unauthenticatedMethods = map[string]bool{
+	fgrpc.Auth_Authorize_FullMethodName:          true,
+	fgrpc.Auth_Register_FullMethodName:           true,
+	fgrpc.Auth_Login_FullMethodName:              true,
+	fgrpc.Auth_Refresh_FullMethodName:            true,
+	fgrpc.Auth_AuthorizeApiKey_FullMethodName:    true,
+	fgrpc.Auth_VerifySecondFactor_FullMethodName: true,
+	fgrpc.Auth_Recover_FullMethodName:            true,
+}
+
+type claimsContextKey struct{}
+
+// tokenMessage is implemented by request messages that still carry the deprecated sessionToken field
+type tokenMessage interface {
+	GetSessionToken() string
+}
+
+// UnaryAuthInterceptor verifies the session token of every unary call (except those in unauthenticatedMethods) and
+// attaches the session token, the token's claims and the *fortress.Player to the context passed to the handler
+func (h *AuthHandler) UnaryAuthInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if unauthenticatedMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
+	ctx, err := h.authenticate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamAuthInterceptor does the same as UnaryAuthInterceptor for streaming calls
+func (h *AuthHandler) StreamAuthInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if unauthenticatedMethods[info.FullMethod] {
+		return handler(srv, ss)
+	}
+
+	stream := &authenticatedStream{ServerStream: ss, auth: h}
+	if bearerToken(ss.Context()) != "" {
+		ctx, err := h.authenticate(ss.Context(), nil)
+		if err != nil {
+			return err
+		}
+		stream.ctx = ctx
+	}
+	return handler(srv, stream)
+}
+
+// authenticate verifies the session token sent with the call and returns a context carrying the player it belongs to.
+// The token is read from the metadata, or from req for older clients that still send it inside the message
+func (h *AuthHandler) authenticate(ctx context.Context, req any) (context.Context, error) {
+	tokenString := bearerToken(ctx)
+	if tokenString == "" {
+		tokenString = messageToken(req)
+	}
+	if tokenString == "" {
+		return nil, status.Error(codes.Unauthenticated, "no session token was sent")
+	}
+
+	token, claims := h.getTokenFromString(tokenString)
+	if token == nil {
+		h.recordThrottled(&SecurityEvent{kind: EventTokenInvalid, ipAddress: peerAddress(ctx), detail: "invalid or expired session token"})
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired session token")
+	}
+
+	player, _ := h.GetPlayer(PlayerFilter{playerId: claims.PlayerID}, true)
+	ctx = fortress.NewContextWithSessionToken(ctx, tokenString)
+	ctx = fortress.NewContextWithPlayer(ctx, player)
+	return context.WithValue(ctx, claimsContextKey{}, claims), nil
+}
+
+// claimsFromContext returns the claims of the session token that authenticated the call, or nil if there are none
+func claimsFromContext(ctx context.Context) *JwtTokenClaims {
+	claims, ok := ctx.Value(claimsContextKey{}).(*JwtTokenClaims)
+	if !ok {
+		return nil
+	}
+	return claims
+}
+
+// bearerToken returns the token from the call's "authorization: Bearer <token>" metadata, or "" if there is none
+func bearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, value := range md.Get(authorizationMetadataKey) {
+		if token, found := strings.CutPrefix(value, "Bearer "); found {
+			return token
+		}
+	}
+	return ""
+}
+
+// messageToken returns the deprecated sessionToken field of a request message, or "" if it has none
+func messageToken(req any) string {
+	if commandInfo, ok := req.(*fgrpc.CommandInfo); ok {
+		return commandInfo.GetPlayerInfo().GetSessionToken()
+	}
+	if message, ok := req.(tokenMessage); ok {
+		return message.GetSessionToken()
+	}
+	return ""
+}
+
+// authenticatedStream is a ServerStream whose context carries the authenticated player. If the client did not send its token in
+// the metadata, the stream is authenticated with the token in the first message it receives
+type authenticatedStream struct {
+	grpc.ServerStream
+	auth *AuthHandler
+	ctx  context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	if s.ctx == nil {
+		return s.ServerStream.Context()
+	}
+	return s.ctx
+}
+
+func (s *authenticatedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.ctx != nil {
+		return nil
+	}
+
+	ctx, err := s.auth.authenticate(s.ServerStream.Context(), m)
+	if err != nil {
+		return err
+	}
+	s.ctx = ctx
+	return nil
+}
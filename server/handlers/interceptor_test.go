@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cheracc/fortress-grpc"
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryAuthInterceptor(t *testing.T) {
+	s := newTestServer(t, nil)
+	player := s.newPlayer(t, "alice")
+	s.login(t, player)
+	token := player.GetSessionToken()
+	s.login(t, player)
+	revoked := player.GetSessionToken()
+	_, claims := s.auth.getTokenFromString(revoked)
+	s.auth.sessions.Revoke(claims.SessionID, "test")
+
+	withToken := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(authorizationMetadataKey, "Bearer "+token))
+	}
+	tests := []struct {
+		name   string
+		method string
+		ctx    context.Context
+		req    any
+		want   codes.Code
+		player bool // whether the handler should get the player in its context
+	}{
+		{"Authorize without a token", fgrpc.Auth_Authorize_FullMethodName, context.Background(), nil, codes.OK, false},
+		{"Register without a token", fgrpc.Auth_Register_FullMethodName, context.Background(), nil, codes.OK, false},
+		{"Login without a token", fgrpc.Auth_Login_FullMethodName, context.Background(), nil, codes.OK, false},
+		{"Refresh without a token", fgrpc.Auth_Refresh_FullMethodName, context.Background(), nil, codes.OK, false},
+		{"AuthorizeApiKey without a token", fgrpc.Auth_AuthorizeApiKey_FullMethodName, context.Background(), nil, codes.OK, false},
+		{"VerifySecondFactor without a token", fgrpc.Auth_VerifySecondFactor_FullMethodName, context.Background(), nil, codes.OK, false},
+		{"Recover without a token", fgrpc.Auth_Recover_FullMethodName, context.Background(), nil, codes.OK, false},
+		{"Logout without a token", fgrpc.Auth_Logout_FullMethodName, context.Background(), nil, codes.Unauthenticated, false},
+		{"Command without a token", fgrpc.Command_Command_FullMethodName, context.Background(), nil, codes.Unauthenticated, false},
+		{"GetPlayerData without a token", fgrpc.Player_GetPlayerData_FullMethodName, context.Background(), nil, codes.Unauthenticated, false},
+		{"SendMessage without a token", fgrpc.Chat_SendMessage_FullMethodName, context.Background(), nil, codes.Unauthenticated, false},
+		{"method that is not known", "/fortress.Unknown/Method", context.Background(), nil, codes.Unauthenticated, false},
+		{"Command with a token", fgrpc.Command_Command_FullMethodName, withToken(token), nil, codes.OK, true},
+		{"Command with a token in the message", fgrpc.Command_Command_FullMethodName, context.Background(),
+			&fgrpc.CommandInfo{PlayerInfo: &fgrpc.PlayerInfo{SessionToken: token}}, codes.OK, true},
+		{"Command with a token of a revoked session", fgrpc.Command_Command_FullMethodName, withToken(revoked), nil, codes.Unauthenticated, false},
+		{"Command with a token that is not signed", fgrpc.Command_Command_FullMethodName, withToken("not.a.token"), nil, codes.Unauthenticated, false},
+	}
+	for _, test := range tests {
+		var got *fortress.Player
+		handler := func(ctx context.Context, req any) (any, error) {
+			got = fortress.PlayerFromContext(ctx)
+			return nil, nil
+		}
+		_, err := s.auth.UnaryAuthInterceptor(test.ctx, test.req, &grpc.UnaryServerInfo{FullMethod: test.method}, handler)
+		if status.Code(err) != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.want)
+		}
+		if (got != nil) != test.player || (got != nil && got.GetPlayerId() != player.GetPlayerId()) {
+			t.Errorf("%s: the handler got player %v", test.name, got)
+		}
+	}
+}
@@ -0,0 +1,430 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cheracc/fortress-grpc"
+	"github.com/cheracc/fortress-grpc/server/handlers/commands"
+)
+
+const (
+	inviteCodeLength = 12
+	maxInviteUses    = 1000
+	// how many redemptions the invite command shows at once
+	redemptionListLimit = 50
+)
+
+// An Invite is a code that lets players join a whitelist-only server. Each player that logs in with it is added to the whitelist,
+// until it runs out of uses, expires or is revoked
+type Invite struct {
+	code      string
+	createdBy string
+	createdAt time.Time
+	expiresAt time.Time // zero for an invite that never expires
+	revokedAt time.Time
+	maxUses   int
+	uses      int
+}
+
+// isUsable returns whether the invite can still be redeemed
+func (i *Invite) isUsable() bool {
+	return i.revokedAt.IsZero() && i.uses < i.maxUses && (i.expiresAt.IsZero() || time.Now().Before(i.expiresAt))
+}
+
+// A Redemption records a player joining with an invite code, and who made the code
+type Redemption struct {
+	code       string
+	playerId   string
+	invitedBy  string
+	redeemedAt time.Time
+}
+
+// checkAdmitted returns an error if the server is whitelist-only and the player is not on the whitelist. A player that is not on it
+// is added if they brought a valid invite code. Bots are let in when their owner is, guests never are.
+// This error gets passed back to the user/client
+func (h *AuthHandler) checkAdmitted(player *fortress.Player, inviteCode string) error {
+	if !h.config.WhitelistOnly {
+		return nil
+	}
+	if player.IsBot() {
+		owner := h.FindPlayer(h.SqliteHandler.LookupBotOwner(player.GetPlayerId()))
+		if owner == nil || !h.isWhitelisted(owner) {
+			return fmt.Errorf("this server is invite only and the owner of this bot is not on the whitelist")
+		}
+		return nil
+	}
+	if player.IsGuest() {
+		return fmt.Errorf("this server is invite only, guests can not join")
+	}
+	if h.isWhitelisted(player) {
+		return nil
+	}
+	if inviteCode == "" {
+		h.Logf("Refused login of player %s(%s): not on the whitelist", player.GetName(), player.GetPlayerId())
+		return fmt.Errorf("this server is invite only, log in with an invite code to join")
+	}
+	return h.redeemInvite(player, inviteCode)
+}
+
+// checkNewPlayerAdmitted returns an error if the server is whitelist-only and the identity belongs to nobody yet, unless it is a
+// listed admin or brought an invite code that can still be used. It runs before the new player or their account is saved, the
+// invite is used up later by checkAdmitted. This error gets passed back to the user/client
+func (h *AuthHandler) checkNewPlayerAdmitted(provider string, subject string, inviteCode string) error {
+	if !h.config.WhitelistOnly || isListedIdentity(h.config.Admins, provider, subject) {
+		return nil
+	}
+	if h.SqliteHandler.LookupIdentityOwner(provider, subject) != "" || h.SqliteHandler.LookupPlayerFromDb(PlayerFilter{provider: provider, subject: subject}) != nil {
+		return nil // not new, checkAdmitted decides
+	}
+	if inviteCode == "" {
+		h.Logf("Refused new player %s:%s: not on the whitelist", provider, subject)
+		return fmt.Errorf("this server is invite only, log in with an invite code to join")
+	}
+	if invite := h.SqliteHandler.LookupInvite(normalizeUserCode(inviteCode)); invite == nil || !invite.isUsable() {
+		h.Logf("New player %s:%s tried to join with invalid invite code %s", provider, subject, normalizeUserCode(inviteCode))
+		return fmt.Errorf("the invite code %s is not valid, has expired or has been used up", inviteCode)
+	}
+	return nil
+}
+
+// RevokeUnadmittedSessions ends the sessions of the players that are not admitted (see checkAdmitted), so players that are not on the
+// whitelist do not stay logged in when a server is made whitelist-only. It is called once the database is ready
+func (h *AuthHandler) RevokeUnadmittedSessions() {
+	if !h.config.WhitelistOnly {
+		return
+	}
+	for _, playerId := range h.SqliteHandler.LookupPlayersWithActiveSessions() {
+		player := h.FindPlayer(playerId)
+		if player == nil {
+			continue
+		}
+		if err := h.checkAdmitted(player, ""); err != nil {
+			h.sessions.RevokeAll(playerId, "", err.Error())
+			h.RemoveOnlinePlayer(playerId)
+		}
+	}
+}
+
+// isWhitelisted returns whether the player is on the whitelist. Admins listed in config.Admins always are
+func (h *AuthHandler) isWhitelisted(player *fortress.Player) bool {
+	return h.SqliteHandler.isListedAdmin(h.config.Admins, player) || h.SqliteHandler.IsWhitelisted(player.GetPlayerId())
+}
+
+// redeemInvite uses up one use of the invite code and adds the player to the whitelist. This error gets passed back to the user/client
+func (h *AuthHandler) redeemInvite(player *fortress.Player, entered string) error {
+	code := normalizeUserCode(entered)
+	invite := h.SqliteHandler.LookupInvite(code)
+	if invite == nil || !invite.isUsable() || !h.SqliteHandler.UseInvite(code) {
+		h.Logf("Player %s(%s) tried to join with invalid invite code %s", player.GetName(), player.GetPlayerId(), code)
+		return fmt.Errorf("the invite code %s is not valid, has expired or has been used up", entered)
+	}
+
+	h.SqliteHandler.CreateRedemptionDbRecord(&Redemption{code: code, playerId: player.GetPlayerId(), invitedBy: invite.createdBy, redeemedAt: time.Now().UTC()})
+	h.SqliteHandler.AddToWhitelist(player.GetPlayerId(), invite.createdBy, code)
+	h.Logf("Player %s(%s) joined with invite code %s from %s", player.GetName(), player.GetPlayerId(), code, invite.createdBy)
+	return nil
+}
+
+// An InviteHandler manages the whitelist and the invite codes that admins hand out. The whitelist itself is enforced by the
+// AuthHandler, see checkAdmitted
+type InviteHandler struct {
+	*AuthHandler
+	*fortress.Logger
+}
+
+// NewInviteHandler constructs a new InviteHandler
+func NewInviteHandler(auth *AuthHandler, logger *fortress.Logger) *InviteHandler {
+	return &InviteHandler{auth, logger}
+}
+
+// CreateInvite makes a new invite code that can be redeemed uses times, until it expires after lifetime (0 never expires).
+// This error gets passed back to the user/client
+func (h *InviteHandler) CreateInvite(creator *fortress.Player, uses int, lifetime time.Duration) (string, error) {
+	if uses < 1 || uses > maxInviteUses {
+		return "", fmt.Errorf("an invite can be used 1 to %d times", maxInviteUses)
+	}
+
+	invite := &Invite{code: generateInviteCode(), createdBy: creator.GetPlayerId(), createdAt: time.Now().UTC(), maxUses: uses}
+	if lifetime > 0 {
+		invite.expiresAt = invite.createdAt.Add(lifetime)
+	}
+	if err := h.SqliteHandler.CreateInviteDbRecord(invite); err != nil {
+		return "", fmt.Errorf("could not create the invite, please try again")
+	}
+	h.Logf("%s(%s) created invite code %s with %d uses", creator.GetName(), creator.GetPlayerId(), invite.code, uses)
+	return formatUserCode(invite.code), nil
+}
+
+// RevokeInvite stops an invite code from being redeemed. Players that already joined with it stay on the whitelist.
+// This error gets passed back to the user/client
+func (h *InviteHandler) RevokeInvite(revoker *fortress.Player, entered string) error {
+	code := normalizeUserCode(entered)
+	if !h.SqliteHandler.RevokeInvite(code) {
+		return fmt.Errorf("there is no usable invite code %s", entered)
+	}
+	h.Logf("%s(%s) revoked invite code %s", revoker.GetName(), revoker.GetPlayerId(), code)
+	return nil
+}
+
+// ListInvites describes every invite code, newest first
+func (h *InviteHandler) ListInvites() []commands.InviteInfo {
+	infos := make([]commands.InviteInfo, 0)
+	for _, i := range h.SqliteHandler.LookupInvites() {
+		infos = append(infos, commands.InviteInfo{Code: formatUserCode(i.code), CreatedBy: h.playerName(i.createdBy), CreatedAt: i.createdAt,
+			ExpiresAt: i.expiresAt, Uses: i.uses, MaxUses: i.maxUses, Usable: i.isUsable(), Revoked: !i.revokedAt.IsZero()})
+	}
+	return infos
+}
+
+// ListRedemptions describes who joined with the invite code, or with any code if it is "", newest first
+func (h *InviteHandler) ListRedemptions(code string) []commands.RedemptionInfo {
+	infos := make([]commands.RedemptionInfo, 0)
+	for _, r := range h.SqliteHandler.LookupRedemptions(normalizeUserCode(code), redemptionListLimit) {
+		infos = append(infos, commands.RedemptionInfo{Code: formatUserCode(r.code), Player: h.playerName(r.playerId),
+			InvitedBy: h.playerName(r.invitedBy), RedeemedAt: r.redeemedAt})
+	}
+	return infos
+}
+
+// ListWhitelist returns the names of the players on the whitelist
+func (h *InviteHandler) ListWhitelist() []string {
+	names := make([]string, 0)
+	for _, id := range h.SqliteHandler.LookupWhitelist() {
+		names = append(names, h.playerName(id))
+	}
+	return names
+}
+
+// AddToWhitelist lets the player log in to a whitelist-only server. This error gets passed back to the user/client
+func (h *InviteHandler) AddToWhitelist(adder *fortress.Player, player *fortress.Player) error {
+	if player.IsBot() || player.IsGuest() {
+		return fmt.Errorf("only players can be added to the whitelist, bots are let in when their owner is")
+	}
+	if h.SqliteHandler.IsWhitelisted(player.GetPlayerId()) {
+		return fmt.Errorf("%s is already on the whitelist", player.GetName())
+	}
+	h.SqliteHandler.AddToWhitelist(player.GetPlayerId(), adder.GetPlayerId(), "")
+	h.Logf("%s(%s) added %s(%s) to the whitelist", adder.GetName(), adder.GetPlayerId(), player.GetName(), player.GetPlayerId())
+	return nil
+}
+
+// RemoveFromWhitelist takes the player off the whitelist. If the server is whitelist-only their sessions, and those of their bots,
+// are ended too. This error gets passed back to the user/client
+func (h *InviteHandler) RemoveFromWhitelist(remover *fortress.Player, player *fortress.Player) error {
+	if !h.SqliteHandler.RemoveFromWhitelist(player.GetPlayerId()) {
+		return fmt.Errorf("%s is not on the whitelist", player.GetName())
+	}
+	if h.config.WhitelistOnly {
+		h.endSessions(player, "you were removed from the whitelist")
+	}
+	h.Logf("%s(%s) removed %s(%s) from the whitelist", remover.GetName(), remover.GetPlayerId(), player.GetName(), player.GetPlayerId())
+	return nil
+}
+
+// playerName returns the name of the player with the id, or the id if there is no such player
+func (h *InviteHandler) playerName(playerId string) string {
+	if p := h.FindPlayer(playerId); p != nil {
+		return p.GetName()
+	}
+	return playerId
+}
+
+// generateInviteCode returns a random code made of the same easy to read characters as device login codes
+func generateInviteCode() string {
+	return randomCode(userCodeCharacters, inviteCodeLength)
+}
+
+func (h *SqliteHandler) initializeInvitesTables() {
+	_, err := h.db.Exec("CREATE TABLE IF NOT EXISTS invites (" +
+		"code TEXT PRIMARY KEY, " +
+		"created_by TEXT, " +
+		"created_at INTEGER, " +
+		"expires_at INTEGER, " +
+		"max_uses INTEGER, " +
+		"uses INTEGER DEFAULT 0, " +
+		"revoked_at INTEGER DEFAULT 0)")
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+
+	_, err = h.db.Exec("CREATE TABLE IF NOT EXISTS invite_redemptions (" +
+		"code TEXT, " +
+		"player_id TEXT, " +
+		"invited_by TEXT, " +
+		"redeemed_at INTEGER)")
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+
+	_, err = h.db.Exec("CREATE TABLE IF NOT EXISTS whitelist (" +
+		"player_id TEXT PRIMARY KEY, " +
+		"added_by TEXT, " +
+		"invite_code TEXT, " +
+		"added_at INTEGER)")
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+}
+
+func (h *SqliteHandler) CreateInviteDbRecord(i *Invite) error {
+	_, err := h.db.Exec("INSERT INTO invites (code, created_by, created_at, expires_at, max_uses, uses, revoked_at) VALUES (?, ?, ?, ?, ?, 0, 0)",
+		i.code, i.createdBy, i.createdAt.Unix(), unixOrZero(i.expiresAt), i.maxUses)
+	if err != nil {
+		h.Errorf("SQL: could not create invite %s: %s", i.code, err)
+	}
+	return err
+}
+
+// UseInvite takes one use from the invite and returns whether it could be used. It is done in one statement so that two players
+// can't both take the last use
+func (h *SqliteHandler) UseInvite(code string) bool {
+	now := time.Now().UTC().Unix()
+	res, err := h.db.Exec("UPDATE invites SET uses = uses + 1 WHERE code = ? AND revoked_at = 0 AND uses < max_uses AND (expires_at = 0 OR expires_at > ?)", code, now)
+	if err != nil {
+		h.Errorf("SQL: could not use invite %s: %s", code, err)
+		return false
+	}
+	rows, _ := res.RowsAffected()
+	return rows == 1
+}
+
+// RevokeInvite revokes the invite and returns whether there was an unrevoked invite with the code
+func (h *SqliteHandler) RevokeInvite(code string) bool {
+	res, err := h.db.Exec("UPDATE invites SET revoked_at = ? WHERE code = ? AND revoked_at = 0", time.Now().UTC().Unix(), code)
+	if err != nil {
+		h.Errorf("SQL: could not revoke invite %s: %s", code, err)
+		return false
+	}
+	rows, _ := res.RowsAffected()
+	return rows > 0
+}
+
+// LookupInvite returns the invite with the code, or nil if there is none
+func (h *SqliteHandler) LookupInvite(code string) *Invite {
+	row := h.db.QueryRow("SELECT code, created_by, created_at, expires_at, max_uses, uses, revoked_at FROM invites WHERE code = ?", code)
+	i, err := scanInvite(row)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		h.Errorf("SQL: could not load invite %s: %s", code, err)
+		return nil
+	}
+	return i
+}
+
+// LookupInvites returns every invite, newest first
+func (h *SqliteHandler) LookupInvites() []*Invite {
+	rows, err := h.db.Query("SELECT code, created_by, created_at, expires_at, max_uses, uses, revoked_at FROM invites ORDER BY created_at DESC")
+	if err != nil {
+		h.Errorf("SQL: could not look up invites: %s", err)
+		return nil
+	}
+	defer rows.Close()
+
+	invites := make([]*Invite, 0)
+	for rows.Next() {
+		i, err := scanInvite(rows)
+		if err != nil {
+			h.Errorf("SQL: %s", err)
+			return nil
+		}
+		invites = append(invites, i)
+	}
+	return invites
+}
+
+func scanInvite(row interface{ Scan(...any) error }) (*Invite, error) {
+	i := &Invite{}
+	var created, expires, revoked int64
+	if err := row.Scan(&i.code, &i.createdBy, &created, &expires, &i.maxUses, &i.uses, &revoked); err != nil {
+		return nil, err
+	}
+	i.createdAt = time.Unix(created, 0)
+	i.expiresAt = timeOrZero(expires)
+	i.revokedAt = timeOrZero(revoked)
+	return i, nil
+}
+
+func (h *SqliteHandler) CreateRedemptionDbRecord(r *Redemption) {
+	_, err := h.db.Exec("INSERT INTO invite_redemptions (code, player_id, invited_by, redeemed_at) VALUES (?, ?, ?, ?)",
+		r.code, r.playerId, r.invitedBy, r.redeemedAt.Unix())
+	if err != nil {
+		h.Errorf("SQL: could not record redemption of invite %s by player %s: %s", r.code, r.playerId, err)
+	}
+}
+
+// LookupRedemptions returns up to limit redemptions of the invite code, or of every code if it is "", newest first
+func (h *SqliteHandler) LookupRedemptions(code string, limit int) []*Redemption {
+	rows, err := h.db.Query("SELECT code, player_id, invited_by, redeemed_at FROM invite_redemptions WHERE ? = '' OR code = ? ORDER BY redeemed_at DESC LIMIT ?",
+		code, code, limit)
+	if err != nil {
+		h.Errorf("SQL: could not look up invite redemptions: %s", err)
+		return nil
+	}
+	defer rows.Close()
+
+	redemptions := make([]*Redemption, 0)
+	for rows.Next() {
+		r := &Redemption{}
+		var redeemed int64
+		if err := rows.Scan(&r.code, &r.playerId, &r.invitedBy, &redeemed); err != nil {
+			h.Errorf("SQL: %s", err)
+			return nil
+		}
+		r.redeemedAt = time.Unix(redeemed, 0)
+		redemptions = append(redemptions, r)
+	}
+	return redemptions
+}
+
+func (h *SqliteHandler) AddToWhitelist(playerId string, addedBy string, inviteCode string) {
+	_, err := h.db.Exec("INSERT OR IGNORE INTO whitelist (player_id, added_by, invite_code, added_at) VALUES (?, ?, ?, ?)",
+		playerId, addedBy, inviteCode, time.Now().UTC().Unix())
+	if err != nil {
+		h.Errorf("SQL: could not add player %s to the whitelist: %s", playerId, err)
+	}
+}
+
+// RemoveFromWhitelist takes the player off the whitelist and returns whether they were on it
+func (h *SqliteHandler) RemoveFromWhitelist(playerId string) bool {
+	res, err := h.db.Exec("DELETE FROM whitelist WHERE player_id = ?", playerId)
+	if err != nil {
+		h.Errorf("SQL: could not remove player %s from the whitelist: %s", playerId, err)
+		return false
+	}
+	rows, _ := res.RowsAffected()
+	return rows > 0
+}
+
+func (h *SqliteHandler) IsWhitelisted(playerId string) bool {
+	var n int
+	if err := h.db.QueryRow("SELECT COUNT(*) FROM whitelist WHERE player_id = ?", playerId).Scan(&n); err != nil {
+		h.Errorf("SQL: could not check the whitelist for player %s: %s", playerId, err)
+		return false
+	}
+	return n > 0
+}
+
+// LookupWhitelist returns the ids of the players on the whitelist, in the order they were added
+func (h *SqliteHandler) LookupWhitelist() []string {
+	rows, err := h.db.Query("SELECT player_id FROM whitelist ORDER BY added_at")
+	if err != nil {
+		h.Errorf("SQL: could not look up the whitelist: %s", err)
+		return nil
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			h.Errorf("SQL: %s", err)
+			return nil
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func whitelistOnly(config *Config) {
+	config.WhitelistOnly = true
+	config.Admins = []string{"local:root", "local:admin"}
+}
+
+// TestExistingSessionsNeedAdmission checks that Authorize and Refresh refuse the sessions of a player that is no longer on the whitelist
+func TestExistingSessionsNeedAdmission(t *testing.T) {
+	s := newTestServer(t, whitelistOnly)
+	root, alice := s.newPlayer(t, "root"), s.newPlayer(t, "alice")
+	if err := s.invites.AddToWhitelist(root, alice); err != nil {
+		t.Fatal(err)
+	}
+	s.login(t, alice)
+	sessionToken := alice.GetSessionToken()
+	ctx := context.Background()
+
+	if _, err := s.auth.Authorize(ctx, &fgrpc.PlayerInfo{SessionToken: sessionToken}); err != nil {
+		t.Fatalf("a whitelisted player's session was refused: %v", err)
+	}
+	s.auth.SqliteHandler.RemoveFromWhitelist(alice.GetPlayerId()) // leaves the session, like a server that was not whitelist-only
+	if _, err := s.auth.Authorize(ctx, &fgrpc.PlayerInfo{SessionToken: sessionToken}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Authorize confirmed the session of a player who is not on the whitelist: %v", err)
+	}
+
+	s.auth.SqliteHandler.AddToWhitelist(alice.GetPlayerId(), root.GetPlayerId(), "")
+	refreshToken := s.login(t, alice)
+	s.auth.SqliteHandler.RemoveFromWhitelist(alice.GetPlayerId())
+	if _, err := s.auth.Refresh(ctx, &fgrpc.RefreshRequest{RefreshToken: refreshToken}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Refresh renewed the session of a player who is not on the whitelist: %v", err)
+	}
+}
+
+func TestRemoveFromWhitelistEndsSessions(t *testing.T) {
+	s := newTestServer(t, whitelistOnly)
+	root, owner := s.newPlayer(t, "root"), s.newPlayer(t, "owner")
+	if err := s.invites.AddToWhitelist(root, owner); err != nil {
+		t.Fatal(err)
+	}
+	s.login(t, owner)
+	bot, key := s.newBot(t, owner, "ownerbot")
+	if _, err := s.auth.AuthorizeApiKey(context.Background(), &fgrpc.ApiKeyRequest{ApiKey: key}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.invites.RemoveFromWhitelist(root, owner); err != nil {
+		t.Fatal(err)
+	}
+	for _, player := range []string{owner.GetPlayerId(), bot.GetPlayerId()} {
+		if sessions := s.auth.sessions.GetActiveSessions(player); len(sessions) != 0 {
+			t.Errorf("%s still has %d sessions after being removed from the whitelist", player, len(sessions))
+		}
+	}
+}
+
+// TestRevokeUnadmittedSessions checks that turning on whitelist-only mode ends the sessions of the players that are not on the whitelist
+func TestRevokeUnadmittedSessions(t *testing.T) {
+	s := newTestServer(t, nil)
+	root, alice, bob := s.newPlayer(t, "root"), s.newPlayer(t, "alice"), s.newPlayer(t, "bob")
+	s.auth.SqliteHandler.AddToWhitelist(alice.GetPlayerId(), "", "")
+	for _, player := range []string{"root", "alice", "bob"} {
+		s.login(t, s.auth.FindPlayer(player))
+	}
+
+	whitelistOnly(s.auth.config)
+	s.auth.RevokeUnadmittedSessions()
+	for player, admitted := range map[string]bool{root.GetPlayerId(): true, alice.GetPlayerId(): true, bob.GetPlayerId(): false} {
+		if active := len(s.auth.sessions.GetActiveSessions(player)) > 0; active != admitted {
+			t.Errorf("%s should have a session: %v, but has one: %v", player, admitted, active)
+		}
+	}
+}
+
+func TestInviteAdmission(t *testing.T) {
+	s := newTestServer(t, whitelistOnly)
+	root := s.newPlayer(t, "root")
+	invite := func(uses int, lifetime time.Duration) string {
+		code, err := s.invites.CreateInvite(root, uses, lifetime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+	twoUses, expired, revoked := invite(2, 0), invite(1, time.Nanosecond), invite(1, 0)
+	if err := s.invites.RevokeInvite(root, revoked); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		code     string
+		want     codes.Code
+	}{
+		{"no invite code", "alice", "", codes.PermissionDenied},
+		{"invite code that does not exist", "alice", "ABCD-EFGH-JKLM", codes.PermissionDenied},
+		{"invite code", "alice", twoUses, codes.OK},
+		{"invite code typed in lower case", "bob", strings.ToLower(twoUses), codes.OK},
+		{"invite code that is used up", "carol", twoUses, codes.PermissionDenied},
+		{"invite code that expired", "carol", expired, codes.PermissionDenied},
+		{"invite code that was revoked", "carol", revoked, codes.PermissionDenied},
+		{"listed admin without an invite code", "admin", "", codes.OK},
+	}
+	joined := 1 // root
+	for _, test := range tests {
+		_, err := s.auth.Register(context.Background(), &fgrpc.Credentials{Username: test.username, Password: "a password", InviteCode: test.code})
+		if status.Code(err) != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.want)
+		}
+		if err == nil {
+			joined++
+			if player := s.auth.FindPlayer(test.username); player == nil || !s.auth.isWhitelisted(player) {
+				t.Errorf("%s: the player who joined is not on the whitelist", test.name)
+			}
+		}
+	}
+
+	var players int
+	if err := s.auth.SqliteHandler.db.QueryRow("SELECT COUNT(*) FROM players").Scan(&players); err != nil {
+		t.Fatal(err)
+	}
+	if players != joined {
+		t.Errorf("there are %d players, want the %d that were let in", players, joined)
+	}
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cheracc/fortress-grpc"
+	"github.com/cheracc/fortress-grpc/verify"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	sessionTokenLifetime = 5 * time.Minute
+	pemBlockType         = "EC PRIVATE KEY"
+)
+
+// signingKey is a private key used to sign session tokens, along with the id that is put in the header of the tokens it signs
+type signingKey struct {
+	id      string
+	key     *ecdsa.PrivateKey
+	created time.Time
+	retired time.Time // when a newer key replaced this one, zero for the current key
+}
+
+// isUsable returns whether tokens signed by this key may still be valid. Retired keys are kept until every token they signed has expired
+func (k *signingKey) isUsable() bool {
+	return k.retired.IsZero() || time.Since(k.retired) < sessionTokenLifetime
+}
+
+// A Keyring holds the keys that sign and verify session tokens. The keys are kept in a file so that sessions survive a restart.
+// Only the current key signs new tokens, but retired keys still verify the tokens they signed until those expire.
+// The methods of Keyring are thread-safe
+type Keyring struct {
+	*sync.RWMutex
+	keys      map[string]*signingKey
+	currentId string
+	path      string
+	*fortress.Logger
+}
+
+// NewKeyring loads the signing keys from the file at path. If the file does not exist a new key is generated and saved to it
+func NewKeyring(path string, logger *fortress.Logger) *Keyring {
+	k := &Keyring{&sync.RWMutex{}, make(map[string]*signingKey), "", path, logger}
+
+	err := k.load()
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Logf("No signing key file found at %s, generating a new signing key", path)
+		if _, err = k.Rotate(); err != nil {
+			logger.Fatalf("could not create signing key file: %s", err)
+		}
+		return k
+	}
+	if err != nil {
+		logger.Fatalf("could not load signing keys from %s: %s", path, err)
+	}
+
+	logger.Logf("Loaded %d signing keys, current key is %s", len(k.keys), k.currentId)
+	return k
+}
+
+// Sign signs the claims with the current key and sets the key's id in the token header
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	k.RLock()
+	current := k.keys[k.currentId]
+	k.RUnlock()
+
+	if current == nil {
+		return "", fmt.Errorf("there is no signing key loaded")
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = current.id
+	return token.SignedString(current.key)
+}
+
+// KeyFunc is a jwt.Keyfunc that returns the public key named by the token's kid header, as long as that key is still usable
+func (k *Keyring) KeyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	k.RLock()
+	key := k.keys[kid]
+	k.RUnlock()
+
+	if key == nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if !key.isUsable() {
+		return nil, fmt.Errorf("signing key %s has been retired", kid)
+	}
+	return &key.key.PublicKey, nil
+}
+
+// JWKS returns the public half of every key that may still verify tokens, for other services to verify session tokens with
+func (k *Keyring) JWKS() verify.JWKS {
+	k.RLock()
+	defer k.RUnlock()
+
+	jwks := verify.JWKS{Keys: make([]verify.JWK, 0, len(k.keys))}
+	for _, key := range k.keys {
+		if key.isUsable() {
+			jwks.Keys = append(jwks.Keys, verify.NewJWK(key.id, &key.key.PublicKey))
+		}
+	}
+	return jwks
+}
+
+// Rotate generates a new current signing key and retires the old one. Keys that have been retired long enough
+// that all of their tokens have expired are removed. It returns the id of the new key
+func (k *Keyring) Rotate() (string, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	newKey := &signingKey{generateKeyId(), privateKey, now, time.Time{}}
+
+	k.Lock()
+	if old := k.keys[k.currentId]; old != nil {
+		old.retired = now
+	}
+	for id, key := range k.keys {
+		if !key.isUsable() {
+			delete(k.keys, id)
+		}
+	}
+	k.keys[newKey.id] = newKey
+	k.currentId = newKey.id
+	err = k.save()
+	k.Unlock()
+
+	if err != nil {
+		return "", err
+	}
+	k.Logf("Rotated signing keys, current key is now %s", newKey.id)
+	return newKey.id, nil
+}
+
+// load reads the keys from the key file. The key without a retired header is the current key
+func (k *Keyring) load() error {
+	data, err := os.ReadFile(k.path)
+	if err != nil {
+		return err
+	}
+
+	k.Lock()
+	defer k.Unlock()
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != pemBlockType {
+			continue
+		}
+		privateKey, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", block.Headers["kid"], err)
+		}
+		key := &signingKey{id: block.Headers["kid"], key: privateKey}
+		key.created, _ = time.Parse(time.RFC3339, block.Headers["created"])
+		if retired, ok := block.Headers["retired"]; ok {
+			key.retired, _ = time.Parse(time.RFC3339, retired)
+		} else {
+			k.currentId = key.id
+		}
+		k.keys[key.id] = key
+	}
+
+	if k.keys[k.currentId] == nil {
+		return fmt.Errorf("no current key found in %s", k.path)
+	}
+	return nil
+}
+
+// save writes every key to the key file, replacing it. It must be called with the lock held
+func (k *Keyring) save() error {
+	var data []byte
+	for _, key := range k.keys {
+		der, err := x509.MarshalECPrivateKey(key.key)
+		if err != nil {
+			return err
+		}
+		headers := map[string]string{"kid": key.id, "created": key.created.Format(time.RFC3339)}
+		if !key.retired.IsZero() {
+			headers["retired"] = key.retired.Format(time.RFC3339)
+		}
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: pemBlockType, Headers: headers, Bytes: der})...)
+	}
+
+	// write to a temporary file first so a crash can't leave us with half a key file
+	tmp, err := os.CreateTemp(filepath.Dir(k.path), ".signing-keys-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), k.path)
+}
+
+func generateKeyId() string {
+	return hex.EncodeToString(randomBytes(8))
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signTest returns a token signed by the keyring's current key
+func signTest(t *testing.T, k *Keyring) string {
+	t.Helper()
+	token, err := k.Sign(&jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func rotateTest(t *testing.T, k *Keyring) string {
+	t.Helper()
+	id, err := k.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestKeyringRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signing_keys.pem")
+	k := NewKeyring(path, testLogger())
+	oldest := k.currentId
+	oldestToken := signTest(t, k)
+	retired := rotateTest(t, k)
+	retiredToken := signTest(t, k)
+	current := rotateTest(t, k)
+	currentToken := signTest(t, k)
+	k.keys[oldest].retired = time.Now().Add(-sessionTokenLifetime - time.Minute) // every token it signed has expired
+	otherToken := signTest(t, NewKeyring(filepath.Join(t.TempDir(), "other.pem"), testLogger()))
+
+	tests := []struct {
+		name  string
+		token string
+		kid   string
+		valid bool
+	}{
+		{"current key", currentToken, current, true},
+		{"recently retired key", retiredToken, retired, true},
+		{"key retired longer than tokens last", oldestToken, oldest, false},
+		{"key of another keyring", otherToken, "", false},
+	}
+	published := make(map[string]bool)
+	for _, key := range k.JWKS().Keys {
+		published[key.KeyID] = true
+	}
+	for _, test := range tests {
+		_, err := jwt.Parse(test.token, k.KeyFunc, jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg()}))
+		if (err == nil) != test.valid {
+			t.Errorf("%s: token valid %v, want %v (%v)", test.name, err == nil, test.valid, err)
+		}
+		if test.kid != "" && published[test.kid] != test.valid {
+			t.Errorf("%s: key in the JWKS %v, want %v", test.name, published[test.kid], test.valid)
+		}
+	}
+
+	newest := rotateTest(t, k)
+	if _, ok := k.keys[oldest]; ok {
+		t.Errorf("a key whose tokens have all expired was not removed when the keys were rotated")
+	}
+	reloaded := NewKeyring(path, testLogger())
+	if reloaded.currentId != newest || len(reloaded.keys) != 3 {
+		t.Errorf("the reloaded keyring has current key %s and %d keys, want %s and 3", reloaded.currentId, len(reloaded.keys), newest)
+	}
+	if reloaded.keys[retired].retired.IsZero() || !reloaded.keys[newest].retired.IsZero() {
+		t.Errorf("the reloaded keyring did not keep which keys are retired")
+	}
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/argon2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	localProvider     = "local"
+	minPasswordLength = 8
+
+	// argon2id parameters for password hashes, these are stored with each hash so they can be raised later
+	argonTime    uint32 = 3
+	argonMemory  uint32 = 64 * 1024
+	argonThreads uint8  = 2
+	argonKeyLen  uint32 = 32
+	argonSaltLen        = 16
+)
+
+var validUsername = regexp.MustCompile(`^[a-zA-Z0-9_-]{3,24}$`)
+
+// Register is the gRPC receiving function that creates a new local account and logs it in
+func (h *AuthHandler) Register(ctx context.Context, credentials *fgrpc.Credentials) (*fgrpc.AuthInfo, error) {
+	if err := h.checkAuthLimits(peerAddress(ctx)); err != nil { // before hashing anything, each hash costs 64 MiB
+		return nil, err
+	}
+	username := strings.ToLower(credentials.GetUsername())
+	if err := h.checkNewLocalAccount(username, credentials.GetPassword()); err != nil {
+		return nil, err
+	}
+	if err := h.checkNewPlayerAdmitted(localProvider, username, credentials.GetInviteCode()); err != nil {
+		h.recordLoginFailure("", peerAddress(ctx), err.Error())
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	// the account is saved before its player, so when two clients register the same username at once the unique username lets
+	// only one of them through, and the other does not leave a player behind
+	playerId := h.SqliteHandler.LookupIdentityOwner(localProvider, username)
+	if playerId == "" {
+		playerId = uuid.NewString()
+	}
+	if err := h.SqliteHandler.CreateLocalAccount(username, playerId, hashPassword(credentials.GetPassword())); err != nil {
+		return nil, fmt.Errorf("the username %s is already taken", username)
+	}
+	player, _ := h.GetPlayer(PlayerFilter{playerId: playerId}, true)
+	player.SetIdentity(localProvider, username)
+	h.SqliteHandler.LinkIdentity(&LinkedIdentity{provider: localProvider, subject: username, playerId: playerId, linkedAt: time.Now().UTC()})
+	if h.SqliteHandler.IsNameUnique(credentials.GetUsername()) {
+		player.SetName(credentials.GetUsername())
+	}
+	h.SqliteHandler.UpdatePlayerToDb(player)
+	h.Logf("Registered local account %s for player %s", username, player.GetPlayerId())
+
+	return h.loginLocalPlayer(&Auth{provider: localProvider, subject: username, ipAddress: peerAddress(ctx), device: credentials.GetDevice(),
+		inviteCode: credentials.GetInviteCode()})
+}
+
+// checkNewLocalAccount returns an error if a local account can't be made with the (lowercased) username and password
+func (h *AuthHandler) checkNewLocalAccount(username string, password string) error {
+	if !validUsername.MatchString(username) {
+		return fmt.Errorf("usernames must be 3 to 24 letters, numbers, dashes or underscores")
+	}
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("passwords must be at least %d characters", minPasswordLength)
+	}
+	if h.SqliteHandler.GetLocalAccountPlayerId(username) != "" {
+		return fmt.Errorf("the username %s is already taken", username)
+	}
+	return nil
+}
+
+// Login is the gRPC receiving function that logs in a local account with its username and password
+func (h *AuthHandler) Login(ctx context.Context, credentials *fgrpc.Credentials) (*fgrpc.AuthInfo, error) {
+	if err := h.checkAuthLimits(peerAddress(ctx)); err != nil { // before hashing anything, each hash costs 64 MiB
+		return nil, err
+	}
+	username := strings.ToLower(credentials.GetUsername())
+	hash := h.SqliteHandler.GetLocalAccountPasswordHash(username)
+	if hash == "" {
+		hashPassword(credentials.GetPassword()) // take as long as a real check so usernames can't be probed by timing
+		h.Logf("Local login failed for unknown user %s", username)
+		h.recordLoginFailure("", peerAddress(ctx), "unknown local account "+username)
+		return nil, fmt.Errorf("wrong username or password")
+	}
+	if !verifyPassword(credentials.GetPassword(), hash) {
+		h.Logf("Local login failed for user %s: wrong password", username)
+		h.recordLoginFailure(h.SqliteHandler.GetLocalAccountPlayerId(username), peerAddress(ctx), "wrong password for local account "+username)
+		return nil, fmt.Errorf("wrong username or password")
+	}
+
+	return h.loginLocalPlayer(&Auth{provider: localProvider, subject: username, ipAddress: peerAddress(ctx), device: credentials.GetDevice(),
+		inviteCode: credentials.GetInviteCode()})
+}
+
+// loginLocalPlayer authorizes the player that owns the local account auth.subject and returns their session. For players with
+// two-factor authentication on, the auth is held until they send their code
+func (h *AuthHandler) loginLocalPlayer(auth *Auth) (*fgrpc.AuthInfo, error) {
+	player, _ := h.getPlayerByIdentity(localProvider, auth.subject)
+	if h.IsSecondFactorEnabled(player) {
+		if err := h.checkNotBanned(player); err != nil {
+			h.recordLoginFailure(player.GetPlayerId(), auth.ipAddress, err.Error())
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		h.startSecondFactor(auth, player)
+		return &fgrpc.AuthInfo{PlayerID: player.GetPlayerId(), SecondFactorChallenge: auth.challenge}, nil
+	}
+	refreshToken, err := h.AuthorizePlayer(player, auth.device, auth.ipAddress, auth.inviteCode)
+	if err == nil {
+		err = h.useRecoveryCode(player, auth)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	h.Logf("Player %s(%s) logged in with local account %s from %s", player.GetName(), player.GetPlayerId(), auth.subject, auth.ipAddress)
+	return &fgrpc.AuthInfo{PlayerID: player.GetPlayerId(), SessionToken: player.GetSessionToken(), RefreshToken: refreshToken,
+		RecoveryCodes: h.issueFirstRecoveryCodes(player)}, nil
+}
+
+// ChangePassword changes the password of the player's local account after checking their current password, and ends every session
+// of the player except sessionId (the one they changed it from). This error gets passed back to the user/client
+func (h *AuthHandler) ChangePassword(playerId string, sessionId string, oldPassword string, newPassword string) error {
+	username := h.SqliteHandler.GetLocalAccountUsername(playerId)
+	if username == "" {
+		return fmt.Errorf("you do not have a local account")
+	}
+	if !verifyPassword(oldPassword, h.SqliteHandler.GetLocalAccountPasswordHash(username)) {
+		return fmt.Errorf("your current password is not correct")
+	}
+	if len(newPassword) < minPasswordLength {
+		return fmt.Errorf("passwords must be at least %d characters", minPasswordLength)
+	}
+
+	h.SqliteHandler.UpdateLocalAccountPassword(username, hashPassword(newPassword))
+	h.sessions.RevokeAll(playerId, sessionId, "the password was changed")
+	h.Logf("Player %s changed the password of local account %s", playerId, username)
+	return nil
+}
+
+// ResetPassword sets a new password on a local account without knowing the old one, and ends every session of its player. It is
+// used by admins. This error gets passed back to the user/client
+func (h *AuthHandler) ResetPassword(username string, newPassword string) error {
+	username = strings.ToLower(username)
+	playerId := h.SqliteHandler.GetLocalAccountPlayerId(username)
+	if playerId == "" {
+		return fmt.Errorf("there is no local account named %s", username)
+	}
+	if len(newPassword) < minPasswordLength {
+		return fmt.Errorf("passwords must be at least %d characters", minPasswordLength)
+	}
+
+	h.SqliteHandler.UpdateLocalAccountPassword(username, hashPassword(newPassword))
+	h.sessions.RevokeAll(playerId, "", "the password was reset")
+	h.RemoveOnlinePlayer(playerId)
+	h.Logf("The password of local account %s was reset", username)
+	return nil
+}
+
+// hashPassword hashes the password with argon2id and a random salt, returning it in the standard encoded form
+func hashPassword(password string) string {
+	salt := randomBytes(argonSaltLen)
+
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+// verifyPassword checks the password against an encoded hash made by hashPassword
+func verifyPassword(password string, encodedHash string) bool {
+	var version int
+	var memory, iterations uint32
+	var threads uint8
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false
+	}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false
+	}
+
+	key := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(expected)))
+	return subtle.ConstantTimeCompare(key, expected) == 1
+}
+
+func (h *SqliteHandler) initializeLocalAccountsTable() {
+	_, err := h.db.Exec("CREATE TABLE IF NOT EXISTS local_accounts (" +
+		"username TEXT PRIMARY KEY, " +
+		"player_id TEXT, " +
+		"password_hash TEXT, " +
+		"created_at INTEGER, " +
+		"updated_at INTEGER)")
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+}
+
+// CreateLocalAccount inserts a new local account, it returns an error if the username is already taken
+func (h *SqliteHandler) CreateLocalAccount(username string, playerId string, passwordHash string) error {
+	now := time.Now().UTC().Unix()
+	_, err := h.db.Exec("INSERT INTO local_accounts (username, player_id, password_hash, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		username, playerId, passwordHash, now, now)
+	if err != nil {
+		return h.Errorf("SQL: could not create local account %s: %s", username, err)
+	}
+	return nil
+}
+
+func (h *SqliteHandler) UpdateLocalAccountPassword(username string, passwordHash string) {
+	_, err := h.db.Exec("UPDATE local_accounts SET password_hash = ?, updated_at = ? WHERE username = ?", passwordHash, time.Now().UTC().Unix(), username)
+	if err != nil {
+		h.Errorf("SQL: could not update password of local account %s: %s", username, err)
+	}
+}
+
+func (h *SqliteHandler) DeleteLocalAccount(username string) {
+	if _, err := h.db.Exec("DELETE FROM local_accounts WHERE username = ?", username); err != nil {
+		h.Errorf("SQL: could not delete local account %s: %s", username, err)
+	}
+}
+
+// GetLocalAccountPasswordHash returns the password hash of the local account, or "" if there is no such account
+func (h *SqliteHandler) GetLocalAccountPasswordHash(username string) string {
+	return h.queryLocalAccount("SELECT password_hash FROM local_accounts WHERE username = ?", username)
+}
+
+// GetLocalAccountPlayerId returns the playerId that owns the local account, or "" if there is no such account
+func (h *SqliteHandler) GetLocalAccountPlayerId(username string) string {
+	return h.queryLocalAccount("SELECT player_id FROM local_accounts WHERE username = ?", username)
+}
+
+// GetLocalAccountUsername returns the username of the player's local account, or "" if they do not have one
+func (h *SqliteHandler) GetLocalAccountUsername(playerId string) string {
+	return h.queryLocalAccount("SELECT username FROM local_accounts WHERE player_id = ?", playerId)
+}
+
+func (h *SqliteHandler) queryLocalAccount(query string, arg string) string {
+	var value string
+	err := h.db.QueryRow(query, arg).Scan(&value)
+	if err == sql.ErrNoRows {
+		return ""
+	}
+	if err != nil {
+		h.Errorf("SQL: %s", err)
+		return ""
+	}
+	return value
+}
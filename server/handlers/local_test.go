@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"golang.org/x/crypto/argon2"
+)
+
+// register makes a local account with the username and password and returns the id of its player
+func (s *testServer) register(t *testing.T, username string, password string) string {
+	t.Helper()
+	authInfo, err := s.auth.Register(context.Background(), &fgrpc.Credentials{Username: username, Password: password, Device: "test"})
+	if err != nil {
+		t.Fatalf("could not register %s: %v", username, err)
+	}
+	return authInfo.GetPlayerID()
+}
+
+// localLogin logs in to the local account and returns the id of the session it started
+func (s *testServer) localLogin(t *testing.T, username string, password string) string {
+	t.Helper()
+	authInfo, err := s.auth.Login(context.Background(), &fgrpc.Credentials{Username: username, Password: password, Device: "test"})
+	if err != nil {
+		t.Fatalf("could not log in as %s: %v", username, err)
+	}
+	sessionId, _, _ := strings.Cut(authInfo.GetRefreshToken(), ".")
+	return sessionId
+}
+
+func TestPasswordChangesEndSessions(t *testing.T) {
+	s := newTestServer(t, nil)
+	playerId := s.register(t, "alice", "first password")
+	kept := s.localLogin(t, "alice", "first password")
+
+	if err := s.auth.ChangePassword(playerId, kept, "first password", "second password"); err != nil {
+		t.Fatal(err)
+	}
+	sessions := s.auth.sessions.GetActiveSessions(playerId)
+	if len(sessions) != 1 || sessions[0].GetSessionId() != kept {
+		t.Errorf("after a password change the player has %d sessions, want only the one it was changed from", len(sessions))
+	}
+
+	if err := s.auth.ResetPassword("alice", "third password"); err != nil {
+		t.Fatal(err)
+	}
+	if sessions := s.auth.sessions.GetActiveSessions(playerId); len(sessions) != 0 {
+		t.Errorf("after a password reset the player still has %d sessions", len(sessions))
+	}
+}
+
+// TestConcurrentRegister checks that registering the same username several times at once makes one account and one player
+func TestConcurrentRegister(t *testing.T) {
+	s := newTestServer(t, nil)
+	errs := make(chan error)
+	for range 3 {
+		go func() {
+			_, err := s.auth.Register(context.Background(), &fgrpc.Credentials{Username: "alice", Password: "a password", Device: "test"})
+			errs <- err
+		}()
+	}
+	registered := 0
+	for range 3 {
+		if err := <-errs; err == nil {
+			registered++
+		}
+	}
+
+	var players int
+	if err := s.auth.SqliteHandler.db.QueryRow("SELECT COUNT(*) FROM players").Scan(&players); err != nil {
+		t.Fatal(err)
+	}
+	if registered != 1 || players != 1 {
+		t.Errorf("registering alice 3 times at once made %d accounts and %d players, want 1 of each", registered, players)
+	}
+}
+
+func TestIsNameUnique(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.newPlayer(t, "alice")
+	tests := map[string]bool{"alice": false, "ALICE": false, "alice2": true, "bob": true}
+	for name, want := range tests {
+		if got := s.auth.SqliteHandler.IsNameUnique(name); got != want {
+			t.Errorf("IsNameUnique(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestVerifyPassword(t *testing.T) {
+	hash := hashPassword("correct horse")
+	parts := strings.Split(hash, "$")
+	// a hash made with other parameters, which are read from the hash when it is checked
+	salt := []byte("0123456789abcdef")
+	cheap := fmt.Sprintf("$argon2id$v=%d$m=1024,t=1,p=1$%s$%s", argon2.Version, base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(argon2.IDKey([]byte("correct horse"), salt, 1, 1024, 1, 32)))
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		want     bool
+	}{
+		{"right password", "correct horse", hash, true},
+		{"wrong password", "correct horsE", hash, false},
+		{"empty password", "", hash, false},
+		{"other parameters", "correct horse", cheap, true},
+		{"other parameters, wrong password", "battery staple", cheap, false},
+		{"empty hash", "correct horse", "", false},
+		{"argon2i", "correct horse", strings.Replace(hash, "$argon2id$", "$argon2i$", 1), false},
+		{"other version", "correct horse", strings.Replace(hash, parts[2], "v=16", 1), false},
+		{"bad parameters", "correct horse", strings.Replace(hash, parts[3], "m=x,t=3,p=2", 1), false},
+		{"bad salt", "correct horse", strings.Replace(hash, parts[4], "!!", 1), false},
+		{"bad key", "correct horse", strings.Replace(hash, parts[5], "!!", 1), false},
+		{"missing part", "correct horse", strings.Join(parts[:5], "$"), false},
+	}
+	for _, test := range tests {
+		if got := verifyPassword(test.password, test.hash); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	if hashPassword("correct horse") == hash {
+		t.Errorf("two hashes of the same password are the same, the salt is not random")
+	}
+}
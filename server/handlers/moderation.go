@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cheracc/fortress-grpc"
+	"github.com/cheracc/fortress-grpc/server/handlers/commands"
+)
+
+// the kinds of sanction a moderator can give a player
+const (
+	SanctionKick = "kick"
+	SanctionBan  = "ban"
+	SanctionMute = "mute"
+)
+
+// A Sanction is a kick, ban or mute given to a player by a moderator. Bans and mutes last until they expire or are lifted,
+// kicks are kept only as a record
+type Sanction struct {
+	id          int64
+	playerId    string
+	kind        string
+	reason      string
+	moderatorId string
+	createdAt   time.Time
+	expiresAt   time.Time // zero for a permanent sanction
+	liftedAt    time.Time // zero unless a moderator lifted it early
+	liftedBy    string
+}
+
+// isActive returns whether the sanction is still in effect
+func (s *Sanction) isActive() bool {
+	if s.kind == SanctionKick || !s.liftedAt.IsZero() {
+		return false
+	}
+	return s.expiresAt.IsZero() || time.Now().Before(s.expiresAt)
+}
+
+// describe explains the sanction to the player it was given to, such as "you are banned until 2024-01-02 15:04:05: spamming"
+func (s *Sanction) describe() string {
+	return "you are " + s.state()
+}
+
+// describeTo explains the sanction to the player, who is either the player it was given to or one of their bots
+func (s *Sanction) describeTo(player *fortress.Player) string {
+	if s.playerId != player.GetPlayerId() {
+		return "the owner of this bot is " + s.state()
+	}
+	return s.describe()
+}
+
+// state is what the sanction does and why, such as "banned until 2024-01-02 15:04:05: spamming"
+func (s *Sanction) state() string {
+	verb := map[string]string{SanctionBan: "banned", SanctionMute: "muted", SanctionKick: "kicked"}[s.kind]
+	until := "permanently"
+	if !s.expiresAt.IsZero() {
+		until = "until " + s.expiresAt.Local().Format(time.DateTime)
+	}
+	return fmt.Sprintf("%s %s: %s", verb, until, s.reason)
+}
+
+// A ModerationHandler carries out the kicks, bans and mutes that moderators give. The bans and mutes themselves are enforced by the
+// AuthHandler and ChatHandler, which look them up with SqliteHandler.LookupActiveSanction
+type ModerationHandler struct {
+	*AuthHandler
+	roles *RoleHandler
+	*fortress.Logger
+}
+
+// NewModerationHandler constructs a new ModerationHandler
+func NewModerationHandler(auth *AuthHandler, roles *RoleHandler, logger *fortress.Logger) *ModerationHandler {
+	return &ModerationHandler{auth, roles, logger}
+}
+
+// Kick ends every session of the player right away. They can log in again. This error gets passed back to the user/client
+func (h *ModerationHandler) Kick(moderator *fortress.Player, player *fortress.Player, reason string) error {
+	if err := h.checkCanSanction(moderator, player); err != nil {
+		return err
+	}
+
+	h.SqliteHandler.CreateSanctionDbRecord(&Sanction{playerId: player.GetPlayerId(), kind: SanctionKick, reason: reason,
+		moderatorId: moderator.GetPlayerId(), createdAt: time.Now().UTC()})
+	h.endSessions(player, fmt.Sprintf("you were kicked by %s: %s", moderator.GetName(), reason))
+	h.Logf("%s(%s) kicked %s(%s): %s", moderator.GetName(), moderator.GetPlayerId(), player.GetName(), player.GetPlayerId(), reason)
+	return nil
+}
+
+// Ban ends every session of the player and stops them from logging in until the ban expires or is lifted. A duration of 0
+// is a permanent ban. This error gets passed back to the user/client
+func (h *ModerationHandler) Ban(moderator *fortress.Player, player *fortress.Player, duration time.Duration, reason string) error {
+	if err := h.checkCanSanction(moderator, player); err != nil {
+		return err
+	}
+
+	ban := h.createSanction(moderator, player, SanctionBan, duration, reason)
+	h.endSessions(player, ban.describe())
+	h.Logf("%s(%s) banned %s(%s) %s", moderator.GetName(), moderator.GetPlayerId(), player.GetName(), player.GetPlayerId(), durationDescription(duration))
+	h.SqliteHandler.RecordSecurityEvent(&SecurityEvent{kind: EventBan, playerId: player.GetPlayerId(), actorId: moderator.GetPlayerId(), detail: durationDescription(duration) + ": " + reason})
+	return nil
+}
+
+// Mute stops the player from sending chat messages until the mute expires or is lifted. A duration of 0 is a permanent mute.
+// This error gets passed back to the user/client
+func (h *ModerationHandler) Mute(moderator *fortress.Player, player *fortress.Player, duration time.Duration, reason string) error {
+	if err := h.checkCanSanction(moderator, player); err != nil {
+		return err
+	}
+
+	h.createSanction(moderator, player, SanctionMute, duration, reason)
+	h.Logf("%s(%s) muted %s(%s) %s", moderator.GetName(), moderator.GetPlayerId(), player.GetName(), player.GetPlayerId(), durationDescription(duration))
+	return nil
+}
+
+// Unban lifts the player's active ban. This error gets passed back to the user/client
+func (h *ModerationHandler) Unban(moderator *fortress.Player, player *fortress.Player) error {
+	return h.liftSanction(moderator, player, SanctionBan)
+}
+
+// Unmute lifts the player's active mute. This error gets passed back to the user/client
+func (h *ModerationHandler) Unmute(moderator *fortress.Player, player *fortress.Player) error {
+	return h.liftSanction(moderator, player, SanctionMute)
+}
+
+// ListSanctions describes every sanction the player has been given, newest first
+func (h *ModerationHandler) ListSanctions(player *fortress.Player) []commands.SanctionInfo {
+	return h.describeSanctions(h.SqliteHandler.LookupSanctions(player.GetPlayerId()))
+}
+
+// ListActiveSanctions describes every ban and mute that is in effect, newest first
+func (h *ModerationHandler) ListActiveSanctions() []commands.SanctionInfo {
+	return h.describeSanctions(h.SqliteHandler.LookupActiveSanctions())
+}
+
+func (h *ModerationHandler) describeSanctions(sanctions []*Sanction) []commands.SanctionInfo {
+	infos := make([]commands.SanctionInfo, 0)
+	for _, s := range sanctions {
+		infos = append(infos, commands.SanctionInfo{Id: s.id, Kind: s.kind, Player: h.playerName(s.playerId), Reason: s.reason, Moderator: h.playerName(s.moderatorId),
+			CreatedAt: s.createdAt, ExpiresAt: s.expiresAt, LiftedAt: s.liftedAt, Active: s.isActive()})
+	}
+	return infos
+}
+
+// playerName returns the name of the player with the given id, or the id itself if they can't be found
+func (h *ModerationHandler) playerName(playerId string) string {
+	if p := h.FindPlayer(playerId); p != nil {
+		return p.GetName()
+	}
+	return playerId
+}
+
+// checkCanSanction returns an error if the moderator is not allowed to sanction the player. Nobody can sanction themselves or an admin
+func (h *ModerationHandler) checkCanSanction(moderator *fortress.Player, player *fortress.Player) error {
+	if moderator.GetPlayerId() == player.GetPlayerId() {
+		return fmt.Errorf("you can not do that to yourself")
+	}
+	if h.roles.HasPermission(player, AllPermissions) {
+		return fmt.Errorf("%s is an admin and can not be kicked, banned or muted", player.GetName())
+	}
+	return nil
+}
+
+// createSanction saves a new ban or mute, replacing any active one of the same kind
+func (h *ModerationHandler) createSanction(moderator *fortress.Player, player *fortress.Player, kind string, duration time.Duration, reason string) *Sanction {
+	now := time.Now().UTC()
+	if active := h.SqliteHandler.LookupActiveSanction(player.GetPlayerId(), kind); active != nil {
+		h.SqliteHandler.LiftSanction(active.id, moderator.GetPlayerId(), now)
+	}
+
+	sanction := &Sanction{playerId: player.GetPlayerId(), kind: kind, reason: reason, moderatorId: moderator.GetPlayerId(), createdAt: now}
+	if duration > 0 {
+		sanction.expiresAt = now.Add(duration)
+	}
+	h.SqliteHandler.CreateSanctionDbRecord(sanction)
+	return sanction
+}
+
+// liftSanction ends the player's active sanction of the given kind early
+func (h *ModerationHandler) liftSanction(moderator *fortress.Player, player *fortress.Player, kind string) error {
+	active := h.SqliteHandler.LookupActiveSanction(player.GetPlayerId(), kind)
+	if active == nil {
+		return fmt.Errorf("%s does not have an active %s", player.GetName(), kind)
+	}
+
+	h.SqliteHandler.LiftSanction(active.id, moderator.GetPlayerId(), time.Now().UTC())
+	h.Logf("%s(%s) lifted the %s of %s(%s)", moderator.GetName(), moderator.GetPlayerId(), kind, player.GetName(), player.GetPlayerId())
+	if kind == SanctionBan {
+		h.SqliteHandler.RecordSecurityEvent(&SecurityEvent{kind: EventUnban, playerId: player.GetPlayerId(), actorId: moderator.GetPlayerId()})
+	}
+	return nil
+}
+
+// endSessions revokes all of the player's sessions, and those of their bots (which also removes them from chat), and logs them out
+func (h *AuthHandler) endSessions(player *fortress.Player, reason string) {
+	for _, playerId := range append([]string{player.GetPlayerId()}, h.SqliteHandler.LookupBotIds(player.GetPlayerId())...) {
+		h.sessions.RevokeAll(playerId, "", reason)
+		h.RemoveOnlinePlayer(playerId)
+	}
+}
+
+// activeSanction returns the player's ban or mute that is in effect, or nil if there is none. A bot acts for its owner, so it is
+// also banned or muted while its owner is
+func (h *AuthHandler) activeSanction(player *fortress.Player, kind string) *Sanction {
+	if sanction := h.SqliteHandler.LookupActiveSanction(player.GetPlayerId(), kind); sanction != nil || !player.IsBot() {
+		return sanction
+	}
+	if ownerId := h.SqliteHandler.LookupBotOwner(player.GetPlayerId()); ownerId != "" {
+		return h.SqliteHandler.LookupActiveSanction(ownerId, kind)
+	}
+	return nil
+}
+
+// checkNotBanned returns an error describing the player's ban, or for a bot its owner's, if they have an active one
+func (h *AuthHandler) checkNotBanned(player *fortress.Player) error {
+	if ban := h.activeSanction(player, SanctionBan); ban != nil {
+		h.Logf("Refused login of banned player %s(%s)", player.GetName(), player.GetPlayerId())
+		return fmt.Errorf("%s", ban.describeTo(player))
+	}
+	return nil
+}
+
+func durationDescription(duration time.Duration) string {
+	if duration <= 0 {
+		return "permanently"
+	}
+	return "for " + duration.String()
+}
+
+func (h *SqliteHandler) initializeSanctionsTable() {
+	_, err := h.db.Exec("CREATE TABLE IF NOT EXISTS sanctions (" +
+		"sanction_id INTEGER PRIMARY KEY AUTOINCREMENT, " +
+		"player_id TEXT, " +
+		"kind TEXT, " +
+		"reason TEXT, " +
+		"moderator_id TEXT, " +
+		"created_at INTEGER, " +
+		"expires_at INTEGER, " +
+		"lifted_at INTEGER, " +
+		"lifted_by TEXT)")
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+}
+
+func (h *SqliteHandler) CreateSanctionDbRecord(s *Sanction) {
+	res, err := h.db.Exec("INSERT INTO sanctions (player_id, kind, reason, moderator_id, created_at, expires_at, lifted_at, lifted_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		s.playerId, s.kind, s.reason, s.moderatorId, s.createdAt.Unix(), unixOrZero(s.expiresAt), unixOrZero(s.liftedAt), s.liftedBy)
+	if err != nil {
+		h.Errorf("SQL: could not save %s of player %s: %s", s.kind, s.playerId, err)
+		return
+	}
+	s.id, _ = res.LastInsertId()
+}
+
+func (h *SqliteHandler) LiftSanction(sanctionId int64, liftedBy string, liftedAt time.Time) {
+	_, err := h.db.Exec("UPDATE sanctions SET lifted_at = ?, lifted_by = ? WHERE sanction_id = ?", liftedAt.Unix(), liftedBy, sanctionId)
+	if err != nil {
+		h.Errorf("SQL: could not lift sanction %d: %s", sanctionId, err)
+	}
+}
+
+// LookupActiveSanction returns the player's newest ban or mute that is still in effect, or nil if they have none
+func (h *SqliteHandler) LookupActiveSanction(playerId string, kind string) *Sanction {
+	row := h.db.QueryRow("SELECT sanction_id, player_id, kind, reason, moderator_id, created_at, expires_at, lifted_at, lifted_by FROM sanctions "+
+		"WHERE player_id = ? AND kind = ? AND lifted_at = 0 AND (expires_at = 0 OR expires_at > ?) ORDER BY created_at DESC, sanction_id DESC LIMIT 1",
+		playerId, kind, time.Now().UTC().Unix())
+
+	s, err := scanSanction(row)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		h.Errorf("SQL: could not look up %s of player %s: %s", kind, playerId, err)
+		return nil
+	}
+	return s
+}
+
+// LookupSanctions returns every sanction the player has been given, newest first
+func (h *SqliteHandler) LookupSanctions(playerId string) []*Sanction {
+	rows, err := h.db.Query("SELECT sanction_id, player_id, kind, reason, moderator_id, created_at, expires_at, lifted_at, lifted_by FROM sanctions "+
+		"WHERE player_id = ? ORDER BY created_at DESC, sanction_id DESC", playerId)
+	if err != nil {
+		h.Errorf("SQL: could not look up sanctions of player %s: %s", playerId, err)
+		return nil
+	}
+	return h.scanSanctions(rows)
+}
+
+// LookupActiveSanctions returns every ban and mute that is still in effect, of any player, newest first
+func (h *SqliteHandler) LookupActiveSanctions() []*Sanction {
+	rows, err := h.db.Query("SELECT sanction_id, player_id, kind, reason, moderator_id, created_at, expires_at, lifted_at, lifted_by FROM sanctions "+
+		"WHERE kind != ? AND lifted_at = 0 AND (expires_at = 0 OR expires_at > ?) ORDER BY created_at DESC, sanction_id DESC", SanctionKick, time.Now().UTC().Unix())
+	if err != nil {
+		h.Errorf("SQL: could not look up active sanctions: %s", err)
+		return nil
+	}
+	return h.scanSanctions(rows)
+}
+
+// scanSanctions reads every sanction from rows selected with every column of the sanctions table, and closes them
+func (h *SqliteHandler) scanSanctions(rows *sql.Rows) []*Sanction {
+	defer rows.Close()
+
+	sanctions := make([]*Sanction, 0)
+	for rows.Next() {
+		s, err := scanSanction(rows)
+		if err != nil {
+			h.Errorf("SQL: %s", err)
+			return nil
+		}
+		sanctions = append(sanctions, s)
+	}
+	return sanctions
+}
+
+// scanSanction reads a sanction from a row selected with every column of the sanctions table, in order
+func scanSanction(row interface{ Scan(...any) error }) (*Sanction, error) {
+	s := &Sanction{}
+	var created, expires, lifted int64
+	err := row.Scan(&s.id, &s.playerId, &s.kind, &s.reason, &s.moderatorId, &created, &expires, &lifted, &s.liftedBy)
+	if err != nil {
+		return nil, err
+	}
+	s.createdAt = time.Unix(created, 0)
+	s.expiresAt = timeOrZero(expires)
+	s.liftedAt = timeOrZero(lifted)
+	return s, nil
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cheracc/fortress-grpc"
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newBot returns a new bot of the owner and an api key for it that can chat
+func (s *testServer) newBot(t *testing.T, owner *fortress.Player, name string) (*fortress.Player, string) {
+	t.Helper()
+	if err := s.bots.CreateBot(owner, name); err != nil {
+		t.Fatal(err)
+	}
+	key, err := s.bots.CreateApiKey(owner, name, []string{PermissionChat})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.auth.FindPlayer(name), key
+}
+
+func TestBannedOwnerBotCanNotLogIn(t *testing.T) {
+	s := newTestServer(t, nil)
+	moderator, owner := s.newPlayer(t, "moderator"), s.newPlayer(t, "owner")
+	_, key := s.newBot(t, owner, "ownerbot")
+
+	if _, err := s.auth.AuthorizeApiKey(context.Background(), &fgrpc.ApiKeyRequest{ApiKey: key}); err != nil {
+		t.Fatalf("the bot could not log in before its owner was banned: %v", err)
+	}
+	if err := s.moderation.Ban(moderator, owner, 0, "spamming"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.auth.AuthorizeApiKey(context.Background(), &fgrpc.ApiKeyRequest{ApiKey: key}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("the bot of a banned owner logged in with %v, want PermissionDenied", err)
+	}
+}
+
+func TestBanEndsBotSessions(t *testing.T) {
+	s := newTestServer(t, nil)
+	moderator, owner := s.newPlayer(t, "moderator"), s.newPlayer(t, "owner")
+	bot, key := s.newBot(t, owner, "ownerbot")
+	if _, err := s.auth.AuthorizeApiKey(context.Background(), &fgrpc.ApiKeyRequest{ApiKey: key}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.moderation.Ban(moderator, owner, 0, "spamming"); err != nil {
+		t.Fatal(err)
+	}
+	if sessions := s.auth.sessions.GetActiveSessions(bot.GetPlayerId()); len(sessions) != 0 {
+		t.Errorf("the bot of a banned owner still has %d sessions", len(sessions))
+	}
+	if s.auth.IsOnline(PlayerFilter{playerId: bot.GetPlayerId()}) {
+		t.Errorf("the bot of a banned owner is still online")
+	}
+}
+
+func TestMutedOwnerBotCanNotChat(t *testing.T) {
+	s := newTestServer(t, nil)
+	moderator, owner := s.newPlayer(t, "moderator"), s.newPlayer(t, "owner")
+	_, key := s.newBot(t, owner, "ownerbot")
+	authInfo, err := s.auth.AuthorizeApiKey(context.Background(), &fgrpc.ApiKeyRequest{ApiKey: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := s.callContext(t, authInfo.GetSessionToken())
+
+	if _, _, err := s.chat.checkCanChat(ctx); err != nil {
+		t.Fatalf("the bot could not chat before its owner was muted: %v", err)
+	}
+	if err := s.moderation.Mute(moderator, owner, 0, "spamming"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.chat.checkCanChat(ctx); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("the bot of a muted owner could chat: %v, want PermissionDenied", err)
+	}
+}
+
+func TestListActiveSanctions(t *testing.T) {
+	s := newTestServer(t, nil)
+	moderator := s.newPlayer(t, "moderator")
+	alice, bob, carol, dave := s.newPlayer(t, "alice"), s.newPlayer(t, "bob"), s.newPlayer(t, "carol"), s.newPlayer(t, "dave")
+	if err := s.moderation.Ban(moderator, alice, 0, "cheating"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.moderation.Mute(moderator, bob, time.Hour, "spamming"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.moderation.Kick(moderator, carol, "afk"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.moderation.Ban(moderator, dave, 0, "mistake"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.moderation.Unban(moderator, dave); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, sanction := range s.moderation.ListActiveSanctions() {
+		got = append(got, sanction.Kind+" "+sanction.Player)
+	}
+	slices.Sort(got)
+	if want := []string{"ban alice", "mute bob"}; !slices.Equal(got, want) {
+		t.Errorf("the active sanctions are %v, want %v", got, want)
+	}
+}
@@ -1,46 +1,54 @@
 package handlers
 
 import (
-	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
+	"html"
+	"math/big"
 	"net/http"
-	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/cheracc/fortress-grpc"
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
+	"google.golang.org/grpc/status"
 )
 
-const oauthGoogleUrlAPI = "https://www.googleapis.com/oauth2/v2/userinfo?access_token="
-
-var googleOauthConfig = &oauth2.Config{
-	RedirectURL:  "http://localhost:8000/auth/google/callback",
-	ClientID:     os.Getenv("GOOGLE_OAUTH_CLIENT_ID"),
-	ClientSecret: os.Getenv("GOOGLE_OAUTH_CLIENT_SECRET"),
-	Scopes:       []string{"openid"},
-	Endpoint:     google.Endpoint,
-}
+// the path that the keys which verify session tokens are published at
+const jwksPath = "/.well-known/jwks.json"
 
 type OauthHandler struct {
 	httpServer *http.Server
 	mux        *http.ServeMux
 	*AuthHandler
 	*fortress.Logger
+	*IdentityProviders
 }
 
-type GoogleJson struct {
-	Id      string `json:"id"`
-	Picture string `json:"picture"`
+// IdentityProviders contains the identity providers that players can sign in with, keyed by their name
+// The methods of IdentityProviders are thread-safe
+type IdentityProviders struct {
+	*sync.RWMutex
+	providers map[string]IdentityProvider
 }
 
-func (h *OauthHandler) StartListener(authHandler *AuthHandler) {
-	h.AuthHandler = authHandler
-	h.mux.HandleFunc("/auth/google/callback", h.OauthGoogleCallback)
+func (p *IdentityProviders) RegisterProvider(provider IdentityProvider) {
+	p.Lock()
+	p.providers[provider.Name()] = provider
+	p.Unlock()
+}
+func (p *IdentityProviders) GetProvider(name string) IdentityProvider {
+	p.RLock()
+	defer p.RUnlock()
+	return p.providers[name]
+}
 
+func (h *OauthHandler) StartListener() {
 	go func() {
 		h.Logf("Starting Oauth http server, listening on %s", h.httpServer.Addr)
 		if err := h.httpServer.ListenAndServe(); err != nil {
@@ -49,14 +57,23 @@ func (h *OauthHandler) StartListener(authHandler *AuthHandler) {
 	}()
 }
 
-func NewOauthHandler(logger *fortress.Logger) OauthHandler {
+// registerRoutes gives the OauthHandler the AuthHandler that logins are finished with, and adds the pages it serves
+func (h *OauthHandler) registerRoutes(authHandler *AuthHandler) {
+	h.AuthHandler = authHandler
+	h.mux.HandleFunc("/auth/{provider}/callback", h.OauthCallback)
+	h.mux.HandleFunc("/device", h.DeviceVerification)
+	h.mux.HandleFunc("GET "+jwksPath, h.JWKS)
+}
+
+func NewOauthHandler(config *Config, logger *fortress.Logger) OauthHandler {
 	oauthHandler := OauthHandler{}
 	oauthHandler.Logger = logger
+	oauthHandler.IdentityProviders = &IdentityProviders{&sync.RWMutex{}, make(map[string]IdentityProvider)}
 	mux := http.NewServeMux()
 	oauthHandler.mux = mux
 
 	server := &http.Server{
-		Addr:    "localhost:8000",
+		Addr:    config.HttpAddr,
 		Handler: oauthHandler.mux,
 	}
 
@@ -65,89 +82,190 @@ func NewOauthHandler(logger *fortress.Logger) OauthHandler {
 	return oauthHandler
 }
 
-func (h *OauthHandler) OauthGoogleCallback(w http.ResponseWriter, r *http.Request) {
+// OauthCallback receives the redirect from an identity provider once a player has signed in. Each oauth state can only be used
+// by one callback, before it expires, and (if config.BindLoginToIP is set) from the ip address that started the login
+func (h *OauthHandler) OauthCallback(w http.ResponseWriter, r *http.Request) {
 	receivedState := r.FormValue("state") // the random state we attached to the login url
 
-	auth := h.GetAuth(receivedState)
-	if auth == nil { // don't recognize this state
-		h.Log("Callback from Google contained an oauth state we did not generate, aborting.")
+	auth, err := h.claimCallback(receivedState)
+	if err != nil {
+		h.Logf("Refused callback from %s: %s", r.RemoteAddr, err)
+		writeErrorPage(w, http.StatusBadRequest, "This login link is not valid.", capitalize(err.Error())+". Please start a new login from the game.")
+		return
+	}
+	if auth.isExpired() {
+		h.removeAuth(auth)
+		h.Logf("Callback from identity provider was for a login that has expired, aborting.")
+		writeErrorPage(w, http.StatusGone, "This login link has expired.", "Please start a new login from the game.")
+		return
+	}
+	if h.config.BindLoginToIP && auth.ipAddress != "" && auth.deviceCode == "" && hostOf(auth.ipAddress) != hostOf(r.RemoteAddr) {
+		auth.failure = "the login was finished from a different network than it was started from"
+		h.Logf("Callback from %s for a login started by %s, aborting.", r.RemoteAddr, auth.ipAddress)
+		h.recordLoginFailure("", auth.ipAddress, "the login was finished from "+hostOf(r.RemoteAddr))
+		writeErrorPage(w, http.StatusForbidden, "You can not log in.", "Open the login link on the same network as the game, or use a device login.")
+		return
+	}
+
+	provider := h.GetProvider(r.PathValue("provider"))
+	if provider == nil || provider.Name() != auth.provider {
+		h.Logf("Callback for provider %s does not match the provider %s that this login was started with, aborting.", r.PathValue("provider"), auth.provider)
+		h.removeAuth(auth)
+		writeErrorPage(w, http.StatusBadRequest, "This login link is not valid.", "Please start a new login from the game.")
+		return
+	}
+	if providerError := r.FormValue("error"); providerError != "" { // such as access_denied when the player cancels
+		auth.failure = "signing in with " + provider.Name() + " was cancelled or failed (" + providerError + ")"
+		description := r.FormValue("error_description") // optional, providers often leave it out
+		h.Logf("Identity provider %s returned error %s: %s", provider.Name(), providerError, description)
+		h.recordLoginFailure("", auth.ipAddress, auth.failure)
+		if description == "" {
+			description = providerError
+		}
+		writeErrorPage(w, http.StatusUnauthorized, "You are not logged in.", "Signing in with "+provider.Name()+" was cancelled or failed. "+
+			capitalize(strings.TrimSuffix(description, "."))+".")
 		return
 	}
 
-	data := h.fetchGoogleDataFromResponse(r.FormValue("code"))
+	identity, err := provider.Identify(r.Context(), r.FormValue("code"), auth.codeVerifier, auth.nonce)
+	if err != nil || identity.Subject == "" { // never log anyone in without knowing who they are
+		auth.failure = "could not find out who signed in with " + provider.Name()
+		h.Errorf("could not identify player with %s: %v", provider.Name(), err)
+		h.recordLoginFailure("", auth.ipAddress, auth.failure)
+		writeErrorPage(w, http.StatusBadGateway, "You are not logged in.", "We could not get your account details from "+provider.Name()+", please try again.")
+		return
+	}
 
-	// set the google id to what we received
-	auth.googleId = data.Id
-	auth.avatarUrl = data.Picture
+	if auth.linkPlayerId != "" { // a logged in player is linking this identity to their account
+		h.completeLink(w, auth, identity)
+		return
+	}
 
-	player, isNew := h.GetPlayer(PlayerFilter{googleId: data.Id}, true)
+	// set the identity to what we received
+	auth.subject = identity.Subject
+	auth.avatarUrl = identity.AvatarUrl
 
-	if isNew {
-		player.SetGoogleId(data.Id)
-	} else if h.IsOnline(PlayerFilter{playerId: player.GetPlayerId()}) {
-		// check if the current session key is valid
-		if h.IsValidToken(player.GetSessionToken()) { // player restarted or connected from another location
-			// find a way to invalidate the current Player (may need to record ip or add a refresh token)
-			fmt.Fprintf(w, "<html><body><h1>You are already logged in.</h1><br><h2>You will need to log out or wait for the old session to end.</h2></body></html>")
+	var player *fortress.Player
+	if auth.recoverPlayerId != "" { // the player proved they own this account with a recovery code
+		if player, err = h.recoverWithIdentity(auth.recoverPlayerId, identity); err != nil {
+			auth.failure = err.Error()
+			h.Logf("could not recover player %s: %s", auth.recoverPlayerId, auth.failure)
+			h.recordLoginFailure(auth.recoverPlayerId, auth.ipAddress, auth.failure)
+			writeErrorPage(w, http.StatusConflict, "Your account was not recovered.", auth.failure)
 			return
 		}
+	} else {
+		if err := h.checkNewPlayerAdmitted(identity.Provider, identity.Subject, auth.inviteCode); err != nil {
+			auth.failure = err.Error()
+			h.recordLoginFailure("", auth.ipAddress, auth.failure)
+			writeErrorPage(w, http.StatusForbidden, "You can not log in.", auth.failure)
+			return
+		}
+		player, _ = h.getPlayerByIdentity(identity.Provider, identity.Subject)
 	}
-
-	player.SetAvatarUrl(data.Picture)
-	h.AuthorizePlayer(player)
+	auth.playerId = player.GetPlayerId()
+	player.SetAvatarUrl(identity.AvatarUrl)
+	if h.IsSecondFactorEnabled(player) {
+		if err := h.checkNotBanned(player); err != nil {
+			auth.failure = err.Error()
+			h.Logf("could not authorize player %s: %s", player.GetPlayerId(), auth.failure)
+			h.recordLoginFailure(player.GetPlayerId(), auth.ipAddress, auth.failure)
+			writeErrorPage(w, http.StatusForbidden, "You can not log in.", auth.failure)
+			return
+		}
+		h.startSecondFactor(auth, player)
+		writePage(w, "Almost there.", "Enter the code from your authenticator app in the game to finish logging in")
+		return
+	}
+	refreshToken, err := h.AuthorizePlayer(player, auth.device, auth.ipAddress, auth.inviteCode)
+	if err == nil {
+		err = h.useRecoveryCode(player, auth)
+	}
+	if err != nil {
+		auth.failure = status.Convert(err).Message()
+		h.Logf("could not authorize player %s: %s", player.GetPlayerId(), auth.failure)
+		writeErrorPage(w, http.StatusForbidden, "You can not log in.", auth.failure)
+		return
+	}
+	auth.refreshToken = refreshToken
 	auth.sessionToken = player.GetSessionToken()
 
-	h.Logf("Player %s(%s) logged in via Google (GID:%s)", player.GetName(), player.GetPlayerId(), player.GetGoogleId())
+	h.Logf("Player %s(%s) logged in via %s (subject:%s)", player.GetName(), player.GetPlayerId(), identity.Provider, identity.Subject)
 
-	fmt.Fprintf(w, "<html><body><h1>You have successfully logged in.</h1><br><b>You may close this tab</b></body></html>")
+	writePage(w, "You have successfully logged in.", "You may close this tab")
 }
 
-func (h *OauthHandler) fetchGoogleDataFromResponse(code string) *GoogleJson {
-	data := h.getUserDataFromGoogle(code)
-	authInfo := GoogleJson{}
-
-	err := json.Unmarshal(data, &authInfo)
-	if err != nil {
-		h.Log(err.Error())
+// JWKS publishes the keys that verify session tokens (as a JSON Web Key Set), so that other services can trust them. A retired key
+// stays in the set until the tokens it signed have expired
+func (h *OauthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(sessionTokenLifetime/time.Second)))
+	if err := json.NewEncoder(w).Encode(h.keys.JWKS()); err != nil {
+		h.Errorf("could not write jwks to %s: %s", r.RemoteAddr, err)
 	}
-
-	return &authInfo
 }
 
-func (h *OauthHandler) getUserDataFromGoogle(code string) []byte {
-	// Use 'code' to get token and get user info from Google.
+// writePage writes a simple html page with a heading and a message
+func writePage(w http.ResponseWriter, heading string, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<html><body><h1>%s</h1><br><b>%s</b></body></html>", html.EscapeString(heading), html.EscapeString(message))
+}
 
-	token, err := googleOauthConfig.Exchange(context.Background(), code)
-	if err != nil {
-		h.Errorf("code exchange wrong: %s", err.Error())
-		return nil
+// capitalize returns the message with its first letter in upper case, to show an error message as a sentence
+func capitalize(message string) string {
+	first, size := utf8.DecodeRuneInString(message)
+	if first == utf8.RuneError {
+		return message // empty, or not text we can capitalize
 	}
-	response, err := http.Get(oauthGoogleUrlAPI + token.AccessToken)
-	if err != nil {
-		h.Errorf("failed getting user info: %s", err.Error())
-		return nil
-	}
-	defer response.Body.Close()
-	contents, err := io.ReadAll(response.Body)
-	if err != nil {
-		h.Errorf("failed read response: %s", err.Error())
-		return nil
-	}
-	return contents
+	return string(unicode.ToUpper(first)) + message[size:]
 }
 
-//build link to sign in with google
+// writeErrorPage writes a simple html page with the http status code
+func writeErrorPage(w http.ResponseWriter, statusCode int, heading string, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(statusCode)
+	fmt.Fprintf(w, "<html><body><h1>%s</h1><br><b>%s</b></body></html>", html.EscapeString(heading), html.EscapeString(message))
+}
+
+// GenerateLoginURL builds a link to sign in with the named provider. It returns the url and a new Auth holding the oauth state,
+// PKCE verifier and nonce the link was built with. The caller fills in the rest of the Auth and records it
+func (h *OauthHandler) GenerateLoginURL(providerName string) (string, *Auth, error) {
+	provider := h.GetProvider(providerName)
+	if provider == nil {
+		return "", nil, fmt.Errorf("unknown identity provider: %s", providerName)
+	}
 
-func (h *OauthHandler) GenerateLoginURL() (string, string) {
-	oauthState := generateRandomState()
-	url := googleOauthConfig.AuthCodeURL(oauthState)
+	auth := &Auth{provider: providerName, oauthState: generateRandomState(), codeVerifier: oauth2.GenerateVerifier(), nonce: generateRandomState()}
+	url := provider.LoginURL(auth.oauthState, auth.codeVerifier, auth.nonce)
 
-	return url, oauthState
+	return url, auth, nil
 }
 
 func generateRandomState() string {
-	b := make([]byte, 16)
-	rand.Read(b)
+	return base64.URLEncoding.EncodeToString(randomBytes(16))
+}
+
+// randomBytes returns n random bytes. It panics if the system's random source fails, as the bytes are used for secrets and ids
+// that must not be guessable
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("could not read random numbers: %s", err))
+	}
+	return b
+}
 
-	return base64.URLEncoding.EncodeToString(b)
+// randomCode returns length characters picked uniformly at random from the alphabet. It panics if the system's random source
+// fails, as there is no safe code to hand out instead
+func randomCode(alphabet string, length int) string {
+	code := make([]byte, length)
+	size := big.NewInt(int64(len(alphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			panic(fmt.Sprintf("could not read random numbers: %s", err))
+		}
+		code[i] = alphabet[n.Int64()]
+	}
+	return string(code)
 }
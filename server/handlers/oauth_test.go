@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// testProvider is an identity provider that never gets as far as identifying anyone
+type testProvider struct{}
+
+func (testProvider) Name() string {
+	return "test"
+}
+
+func (testProvider) LoginURL(state string, verifier string, nonce string) string {
+	return "https://provider.example.com/login?state=" + state
+}
+
+func (testProvider) Identify(ctx context.Context, code string, verifier string, nonce string) (*Identity, error) {
+	return nil, fmt.Errorf("the test provider does not identify anyone")
+}
+
+// TestCallbackProviderError checks the page shown when the provider sends the player back with an error, with or without a description
+func TestCallbackProviderError(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.auth.RegisterProvider(testProvider{})
+	tests := []struct {
+		error       string
+		description string
+		want        string
+	}{
+		{"access_denied", "the user cancelled the login", "Signing in with test was cancelled or failed. The user cancelled the login."},
+		{"access_denied", "", "Signing in with test was cancelled or failed. Access_denied."},
+		{"server_error", "Try again later.", "Signing in with test was cancelled or failed. Try again later."},
+	}
+	for i, test := range tests {
+		_, auth, err := s.auth.OauthHandler.GenerateLoginURL("test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.auth.addPendingAuth(auth); err != nil {
+			t.Fatal(err)
+		}
+
+		query := url.Values{"state": {auth.oauthState}, "error": {test.error}}
+		if test.description != "" {
+			query.Set("error_description", test.description)
+		}
+		r := httptest.NewRequest(http.MethodGet, "/auth/test/callback?"+query.Encode(), nil)
+		r.SetPathValue("provider", "test")
+		w := httptest.NewRecorder()
+		s.auth.OauthHandler.OauthCallback(w, r)
+
+		if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), test.want) {
+			t.Errorf("error %d: got status %d and page %q, want %d and %q", i+1, w.Code, w.Body.String(), http.StatusUnauthorized, test.want)
+		}
+		if auth.failure == "" {
+			t.Errorf("error %d: the login was not marked as failed", i+1)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	pendingAuthSweepInterval = 1 * time.Minute
+	authAttemptWindow        = 1 * time.Minute
+)
+
+// isExpired returns whether the player took too long to sign in with the identity provider
+func (a *Auth) isExpired() bool {
+	return time.Now().After(a.expiresAt)
+}
+
+// addPendingAuth checks that the ip address the auth came from is within its limits, then records the auth under its oauth state
+// (and device code, for device logins) until it completes or expires. It returns a ResourceExhausted error if the address has started
+// too many logins in the last minute or has too many that are not finished
+func (h *AuthHandler) addPendingAuth(auth *Auth) error {
+	if err := h.checkAuthLimits(auth.ipAddress); err != nil {
+		return err
+	}
+
+	auth.createdAt = time.Now()
+	auth.expiresAt = auth.createdAt.Add(h.config.PendingAuthLifetime)
+	h.AddAuth(auth.oauthState, auth)
+	if auth.deviceCode != "" {
+		h.AddAuth(auth.deviceCode, auth)
+	}
+	return nil
+}
+
+// checkAuthLimits records a new login attempt from the ip address and returns an error if it is over either limit
+func (h *AuthHandler) checkAuthLimits(ipAddress string) error {
+	if err := h.recordAuthAttempt(ipAddress); err != nil {
+		return err
+	}
+
+	host := hostOf(ipAddress)
+	pending := &h.AuthenticatingPlayers
+	pending.RLock()
+	tooManyPending := h.config.MaxPendingAuthsPerIP > 0 && pending.countPendingAuths(host) >= h.config.MaxPendingAuthsPerIP
+	pending.RUnlock()
+
+	if tooManyPending {
+		h.Logf("Refused login attempt from %s: too many unfinished logins", host)
+		return status.Error(codes.ResourceExhausted, "too many unfinished logins from your address, finish one or wait for them to expire")
+	}
+	return nil
+}
+
+// recordAuthAttempt records a new login attempt from the ip address and returns an error if the address has made more than
+// config.AuthAttemptsPerMinute. Unlike checkAuthLimits it does not count unfinished logins, for steps of a login that do not start
+// one, such as entering a device's user code
+func (h *AuthHandler) recordAuthAttempt(ipAddress string) error {
+	host := hostOf(ipAddress)
+	now := time.Now()
+
+	pending := &h.AuthenticatingPlayers
+	pending.Lock()
+	recent := make([]time.Time, 0)
+	for _, t := range pending.attempts[host] {
+		if now.Sub(t) < authAttemptWindow {
+			recent = append(recent, t)
+		}
+	}
+	if h.config.AuthAttemptsPerMinute > 0 && len(recent) >= h.config.AuthAttemptsPerMinute {
+		pending.attempts[host] = recent
+		pending.Unlock()
+		h.Logf("Refused login attempt from %s: too many attempts in the last minute", host)
+		h.recordLoginFailure("", host, "too many login attempts in the last minute")
+		return status.Error(codes.ResourceExhausted, "too many login attempts from your address, please wait a minute and try again")
+	}
+	pending.attempts[host] = append(recent, now)
+	pending.Unlock()
+	return nil
+}
+
+// claimCallback returns the auth for the oauth state and marks its callback as used, so each login link only works once
+func (a *AuthenticatingPlayers) claimCallback(state string) (*Auth, error) {
+	a.Lock()
+	defer a.Unlock()
+	auth := a.auths[state]
+	if state == "" || auth == nil || auth.oauthState != state { // device codes are keys too, but never part of a login link
+		return nil, fmt.Errorf("we did not start a login with this link")
+	}
+	if auth.callbackUsed {
+		return nil, fmt.Errorf("this login link has already been used")
+	}
+	auth.callbackUsed = true
+	return auth, nil
+}
+
+// claimCodeAttempt counts an attempt at the auth's second factor code and returns which attempt it is. It returns false, without
+// counting it, once the auth has had maxSecondFactorAttempts
+func (a *AuthenticatingPlayers) claimCodeAttempt(auth *Auth) (int, bool) {
+	a.Lock()
+	defer a.Unlock()
+	if auth.codeAttempts >= maxSecondFactorAttempts {
+		return auth.codeAttempts, false
+	}
+	auth.codeAttempts++
+	return auth.codeAttempts, true
+}
+
+// claimAccountCodeAttempt counts an attempt at one of the player's second factor codes, whichever login or command it is for. It
+// returns false, without counting it, once the player has made maxAccountCodeAttempts within accountCodeAttemptWindow that were
+// not followed by a right code, so a new login does not give a guesser more tries
+func (a *AuthenticatingPlayers) claimAccountCodeAttempt(playerId string) bool {
+	now := time.Now()
+	a.Lock()
+	defer a.Unlock()
+	recent := make([]time.Time, 0)
+	for _, t := range a.codeAttempts[playerId] {
+		if now.Sub(t) < accountCodeAttemptWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= maxAccountCodeAttempts {
+		a.codeAttempts[playerId] = recent
+		return false
+	}
+	a.codeAttempts[playerId] = append(recent, now)
+	return true
+}
+
+// clearAccountCodeAttempts forgets the player's attempts at their second factor codes once they have sent a right one
+func (a *AuthenticatingPlayers) clearAccountCodeAttempts(playerId string) {
+	a.Lock()
+	delete(a.codeAttempts, playerId)
+	a.Unlock()
+}
+
+// recordPoll notes that the device polled its login and returns the interval it should poll at, and whether it polled before its
+// last interval was up. Polling too soon makes the interval longer
+func (a *AuthenticatingPlayers) recordPoll(auth *Auth) (time.Duration, bool) {
+	now := time.Now()
+	a.Lock()
+	defer a.Unlock()
+	tooSoon := now.Sub(auth.lastPoll) < auth.pollInterval
+	auth.lastPoll = now
+	if tooSoon {
+		auth.pollInterval += devicePollSlowDownStep
+	}
+	return auth.pollInterval, tooSoon
+}
+
+// countPendingAuths returns how many unexpired auths came from the host. It must be called with the lock held
+func (a *AuthenticatingPlayers) countPendingAuths(host string) int {
+	counted := make(map[*Auth]bool) // device logins are stored under two keys
+	for _, auth := range a.auths {
+		if !auth.isExpired() && hostOf(auth.ipAddress) == host {
+			counted[auth] = true
+		}
+	}
+	return len(counted)
+}
+
+// sweep forgets auths that have expired (remembering the device codes of device logins, see expireDeviceAuth), expired device
+// codes older than expiredDeviceCodeLifetime, and login and second factor attempts that no longer count towards their limits
+func (a *AuthenticatingPlayers) sweep() int {
+	now := time.Now()
+	a.Lock()
+	defer a.Unlock()
+
+	removed := make(map[*Auth]bool)
+	for key, auth := range a.auths {
+		if auth.isExpired() {
+			delete(a.auths, key)
+			removed[auth] = true
+			if auth.deviceCode != "" {
+				a.expiredDeviceCodes[auth.deviceCode] = now
+			}
+		}
+	}
+	for deviceCode, expired := range a.expiredDeviceCodes {
+		if now.Sub(expired) >= expiredDeviceCodeLifetime {
+			delete(a.expiredDeviceCodes, deviceCode)
+		}
+	}
+	for host, attempts := range a.attempts {
+		if len(attempts) == 0 || now.Sub(attempts[len(attempts)-1]) >= authAttemptWindow {
+			delete(a.attempts, host)
+		}
+	}
+	for playerId, attempts := range a.codeAttempts {
+		if len(attempts) == 0 || now.Sub(attempts[len(attempts)-1]) >= accountCodeAttemptWindow {
+			delete(a.codeAttempts, playerId)
+		}
+	}
+	return len(removed)
+}
+
+// startPendingAuthSweeper removes expired auths every pendingAuthSweepInterval, and adds the counts of throttled security events
+// to the audit log
+func (h *AuthHandler) startPendingAuthSweeper() {
+	go func() {
+		for {
+			time.Sleep(pendingAuthSweepInterval)
+			if removed := h.AuthenticatingPlayers.sweep(); removed > 0 {
+				h.Logf("Removed %d expired logins", removed)
+			}
+			for _, summary := range h.AuthenticatingPlayers.sweepThrottledEvents() {
+				h.SqliteHandler.RecordSecurityEvent(summary)
+			}
+		}
+	}()
+}
+
+// hostOf returns the ip address of a peer address without its port
+func hostOf(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	return host
+}
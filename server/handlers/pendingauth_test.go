@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthAttemptLimit(t *testing.T) {
+	s := newTestServer(t, func(config *Config) {
+		config.AuthAttemptsPerMinute = 3
+		config.MaxPendingAuthsPerIP = 0
+	})
+	tests := []struct {
+		address string
+		want    codes.Code
+	}{
+		{"10.0.0.1:1000", codes.OK},
+		{"10.0.0.1:1001", codes.OK},
+		{"10.0.0.1:1002", codes.OK},
+		{"10.0.0.1:1003", codes.ResourceExhausted}, // the port does not matter
+		{"10.0.0.2:1000", codes.OK},
+		{"10.0.0.1:1004", codes.ResourceExhausted},
+	}
+	for i, test := range tests {
+		if err := s.auth.checkAuthLimits(test.address); status.Code(err) != test.want {
+			t.Errorf("attempt %d from %s: got %v, want %v", i+1, test.address, err, test.want)
+		}
+	}
+
+	s.auth.AuthenticatingPlayers.attempts["10.0.0.1"] = []time.Time{time.Now().Add(-authAttemptWindow)}
+	if err := s.auth.checkAuthLimits("10.0.0.1:1005"); err != nil {
+		t.Errorf("an attempt was refused after the earlier ones left the window: %v", err)
+	}
+}
+
+func TestPendingAuthLimit(t *testing.T) {
+	s := newTestServer(t, func(config *Config) {
+		config.AuthAttemptsPerMinute = 0
+		config.MaxPendingAuthsPerIP = 2
+	})
+	add := func(address string) error {
+		return s.auth.addPendingAuth(&Auth{oauthState: fmt.Sprint("state-", time.Now().UnixNano()), ipAddress: address})
+	}
+
+	tests := []struct {
+		address string
+		want    codes.Code
+	}{
+		{"10.0.0.1:1000", codes.OK},
+		{"10.0.0.1:1001", codes.OK},
+		{"10.0.0.1:1002", codes.ResourceExhausted},
+		{"10.0.0.2:1000", codes.OK},
+	}
+	for i, test := range tests {
+		if err := add(test.address); status.Code(err) != test.want {
+			t.Errorf("pending login %d from %s: got %v, want %v", i+1, test.address, err, test.want)
+		}
+	}
+
+	for _, auth := range s.auth.AuthenticatingPlayers.auths {
+		auth.expiresAt = time.Now().Add(-time.Second)
+	}
+	if err := add("10.0.0.1:1003"); err != nil {
+		t.Errorf("expired logins still count towards the limit: %v", err)
+	}
+	if removed := s.auth.AuthenticatingPlayers.sweep(); removed != 3 {
+		t.Errorf("the sweep removed %d expired logins, want 3", removed)
+	}
+	if len(s.auth.AuthenticatingPlayers.auths) != 1 {
+		t.Errorf("%d logins are left after the sweep, want 1", len(s.auth.AuthenticatingPlayers.auths))
+	}
+}
+
+// TestExpiredDeviceCode checks that a device polling an expired device code is told it expired, whether or not the sweep has
+// forgotten the login yet, and however many times it polls
+func TestExpiredDeviceCode(t *testing.T) {
+	s := newTestServer(t, nil)
+	poll := func(deviceCode string) string {
+		authInfo, err := s.auth.Authorize(context.Background(), &fgrpc.PlayerInfo{SessionToken: deviceCode})
+		if err != nil {
+			t.Fatalf("polling %s returned %v", deviceCode, err)
+		}
+		return authInfo.GetError()
+	}
+	for _, deviceCode := range []string{"swept-device-code", "polled-device-code"} {
+		auth := &Auth{oauthState: deviceCode + "-state", deviceCode: deviceCode, userCode: deviceCode, ipAddress: "10.0.0.1:1000"}
+		if err := s.auth.addPendingAuth(auth); err != nil {
+			t.Fatal(err)
+		}
+		auth.expiresAt = time.Now().Add(-time.Second)
+	}
+
+	if got := poll("polled-device-code"); got != deviceErrorExpired {
+		t.Errorf("polling an expired device code returned %q, want %q", got, deviceErrorExpired)
+	}
+	s.auth.AuthenticatingPlayers.sweep()
+	for _, deviceCode := range []string{"swept-device-code", "polled-device-code"} {
+		if got := poll(deviceCode); got != deviceErrorExpired {
+			t.Errorf("polling %s after the sweep returned %q, want %q", deviceCode, got, deviceErrorExpired)
+		}
+	}
+
+	s.auth.AuthenticatingPlayers.expiredDeviceCodes["swept-device-code"] = time.Now().Add(-expiredDeviceCodeLifetime)
+	s.auth.AuthenticatingPlayers.sweep()
+	if s.auth.isExpiredDeviceCode("swept-device-code") || !s.auth.isExpiredDeviceCode("polled-device-code") {
+		t.Errorf("the sweep should forget only the device codes that expired over %v ago", expiredDeviceCodeLifetime)
+	}
+}
+
+// TestDeviceFormLimit checks that entering user codes at /device counts towards the address's login attempts, so they can't be guessed
+func TestDeviceFormLimit(t *testing.T) {
+	s := newTestServer(t, func(config *Config) {
+		config.AuthAttemptsPerMinute = 2
+	})
+	tests := []struct {
+		address string
+		want    int
+	}{
+		{"10.0.0.1:1000", http.StatusNotFound},
+		{"10.0.0.1:1001", http.StatusNotFound},
+		{"10.0.0.1:1002", http.StatusTooManyRequests},
+		{"10.0.0.2:1000", http.StatusNotFound},
+	}
+	for i, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/device", strings.NewReader("user_code=BCDF-GHJK"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.RemoteAddr = test.address
+		w := httptest.NewRecorder()
+		s.auth.OauthHandler.DeviceVerification(w, r)
+		if w.Code != test.want {
+			t.Errorf("code %d from %s: got status %d, want %d", i+1, test.address, w.Code, test.want)
+		}
+	}
+}
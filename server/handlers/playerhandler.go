@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -85,6 +86,7 @@ func (o *OnlinePlayers) GetOnlinePlayer(filter PlayerFilter) *fortress.Player {
 	for _, v := range o.GetOnlinePlayers() {
 		if (filter.playerId != "" && v.GetPlayerId() == filter.playerId) ||
 			(filter.googleId != "" && v.GetGoogleId() == filter.googleId) ||
+			(filter.subject != "" && v.GetProvider() == filter.provider && v.GetSubject() == filter.subject) ||
 			(filter.name != "" && v.GetName() == filter.name) {
 			return v
 		}
@@ -119,14 +121,11 @@ func (h *PlayerHandler) SetAuthHandler(auth *AuthHandler) {
 }
 
 func (h *PlayerHandler) GetPlayerData(ctx context.Context, playerInfo *fgrpc.PlayerInfo) (*fgrpc.PlayerMessage, error) {
-	tokenString := playerInfo.SessionToken
-
-	id := h.GetPlayerIdFromTokenString(tokenString)
-	if id == "" {
-		return &fgrpc.PlayerMessage{}, h.Errorf("Server was unable to verify or decode session token %s", tokenString)
+	// the auth interceptor has already verified the session token and loaded the player it belongs to
+	p := fortress.PlayerFromContext(ctx)
+	if p == nil {
+		return &fgrpc.PlayerMessage{}, h.Error("Server was unable to find the player for this session")
 	}
-	// if reaching this point, the session token sent is valid and we can send the requested player data
-	p, _ := h.GetPlayer(PlayerFilter{playerId: id}, true)
 
 	payload := &fgrpc.PlayerMessage{PlayerId: p.GetPlayerId(), Name: p.GetName(), CreatedAt: p.GetCreatedAt().Unix()}
 	return payload, nil
@@ -190,6 +189,32 @@ func (h *PlayerHandler) saveModifiedPlayersToDb() {
 	}
 }
 
+// FindPlayer returns the player whose id or name (ignoring case) is exactly nameOrId, from the online players or the database.
+// Unlike GetPlayer it never creates a player or marks one as online, it returns nil if there is no match
+func (h *PlayerHandler) FindPlayer(nameOrId string) *fortress.Player {
+	if nameOrId == "" {
+		return nil
+	}
+	for _, p := range h.GetOnlinePlayers() {
+		if p.GetPlayerId() == nameOrId || strings.EqualFold(p.GetName(), nameOrId) {
+			return p
+		}
+	}
+
+	playerId := h.SqliteHandler.LookupPlayerIdByName(nameOrId)
+	if playerId == "" {
+		if _, err := uuid.Parse(nameOrId); err != nil {
+			return nil
+		}
+		playerId = nameOrId
+	}
+	p := h.SqliteHandler.LookupPlayerFromDb(PlayerFilter{playerId: playerId})
+	if p == nil || p.GetPlayerId() != playerId {
+		return nil
+	}
+	return p
+}
+
 // GetPlayerNameFromId returns the name associated with the given playerId, optionally checking the database. Returns "" if no player is found
 func (h *PlayerHandler) GetPlayerNameFromId(playerId string, checkDatabase bool) string {
 	p, _ := h.GetPlayer(PlayerFilter{playerId: playerId}, checkDatabase)
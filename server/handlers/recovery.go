@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cheracc/fortress-grpc"
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const recoveryCodeCount = 10
+
+// issueFirstRecoveryCodes gives the player recovery codes if they have never had any, which is on their first login (or for players
+// from before recovery codes, their first login since). It returns the codes, or nil if the player already had some. Bots and guests
+// have no identity of their own to lose, so they never get any
+func (h *AuthHandler) issueFirstRecoveryCodes(player *fortress.Player) []string {
+	if player.IsBot() || player.IsGuest() || h.SqliteHandler.HasRecoveryCodes(player.GetPlayerId()) {
+		return nil
+	}
+	h.Logf("Issued recovery codes to player %s(%s) on their first login", player.GetName(), player.GetPlayerId())
+	return h.newRecoveryCodes(player.GetPlayerId())
+}
+
+// newRecoveryCodes replaces the player's recovery codes with new ones and returns them. Only their hashes are stored
+func (h *AuthHandler) newRecoveryCodes(playerId string) []string {
+	codes, hashes := generateOneTimeCodes(recoveryCodeCount)
+	h.SqliteHandler.ReplaceRecoveryCodes(playerId, hashes)
+	return codes
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the player has
+func (h *AuthHandler) CountRecoveryCodes(player *fortress.Player) int {
+	return h.SqliteHandler.CountRecoveryCodes(player.GetPlayerId())
+}
+
+// RegenerateRecoveryCodes replaces the player's recovery codes, so the old ones stop working. This error gets passed back to the user/client
+func (h *AuthHandler) RegenerateRecoveryCodes(player *fortress.Player) ([]string, error) {
+	if player.IsBot() {
+		return nil, fmt.Errorf("bots log in with api keys and can not use recovery codes")
+	}
+	if player.IsGuest() {
+		return nil, fmt.Errorf("guests have no account to recover, use 'link <provider>' to make a full account first")
+	}
+
+	h.Logf("Player %s(%s) made new recovery codes", player.GetName(), player.GetPlayerId())
+	return h.newRecoveryCodes(player.GetPlayerId()), nil
+}
+
+// Recover is the gRPC receiving function for players that can no longer log in with any of their identities. The player proves they
+// own their account with one of its recovery codes, then signs in with a new identity which is linked to their account and logs them
+// in. The login works like one from Authorize, the client polls Authorize with the oauth state (or device code) it gets back.
+// For the local provider a new local account is made instead, and the player is logged in right away
+func (h *AuthHandler) Recover(ctx context.Context, request *fgrpc.RecoveryRequest) (*fgrpc.AuthInfo, error) {
+	ip := peerAddress(ctx)
+	if request.GetProvider() == fortress.GuestProvider {
+		return nil, status.Error(codes.InvalidArgument, "choose an identity provider to recover your account with, guests can't be linked")
+	}
+	if request.GetProvider() == localProvider {
+		return h.recoverWithLocalAccount(ctx, request)
+	}
+
+	// the login is started before the code is checked so that every attempt counts against the address's login limits
+	playerInfo := &fgrpc.PlayerInfo{Provider: request.GetProvider(), Device: request.GetDevice(), DeviceFlow: request.GetDeviceFlow()}
+	authInfo, err := h.startAuth(&fgrpc.AuthInfo{}, playerInfo, ip)
+	if err != nil {
+		return nil, err
+	}
+	auth := h.GetAuth(authInfo.GetSessionToken())
+	if auth == nil {
+		return nil, h.Errorf("could not find the login started to recover player %s", request.GetPlayerName())
+	}
+
+	playerId, codeHash := h.checkRecoveryCode(request.GetPlayerName(), request.GetCode(), ip)
+	if playerId == "" {
+		h.removeAuth(auth)
+		return nil, status.Error(codes.PermissionDenied, "wrong player name or recovery code")
+	}
+	auth.recoverPlayerId = playerId
+	auth.recoveryCode = codeHash
+	h.Logf("Player %s used a recovery code from %s, waiting for them to sign in with %s", playerId, ip, auth.provider)
+
+	authInfo.PlayerID = playerId
+	return authInfo, nil
+}
+
+// recoverWithLocalAccount makes a new local account for the player that owns the recovery code, and logs them in with it
+func (h *AuthHandler) recoverWithLocalAccount(ctx context.Context, request *fgrpc.RecoveryRequest) (*fgrpc.AuthInfo, error) {
+	ip := peerAddress(ctx)
+	if err := h.checkAuthLimits(ip); err != nil {
+		return nil, err
+	}
+	username := strings.ToLower(request.GetUsername())
+	if err := h.checkNewLocalAccount(username, request.GetPassword()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	playerId, codeHash := h.checkRecoveryCode(request.GetPlayerName(), request.GetCode(), ip)
+	if playerId == "" {
+		return nil, status.Error(codes.PermissionDenied, "wrong player name or recovery code")
+	}
+	if err := h.SqliteHandler.CreateLocalAccount(username, playerId, hashPassword(request.GetPassword())); err != nil {
+		return nil, status.Errorf(codes.AlreadyExists, "the username %s is already taken", username)
+	}
+	h.SqliteHandler.LinkIdentity(&LinkedIdentity{provider: localProvider, subject: username, playerId: playerId, linkedAt: time.Now().UTC()})
+	h.Logf("Recovered player %s with the new local account %s", playerId, username)
+
+	return h.loginLocalPlayer(&Auth{provider: localProvider, subject: username, ipAddress: ip, device: request.GetDevice(), recoveryCode: codeHash})
+}
+
+// recoverWithIdentity links the identity the player signed in with to the player they are recovering, unless it belongs to
+// someone else. It returns the recovered player. This error is shown to the player
+func (h *AuthHandler) recoverWithIdentity(playerId string, identity *Identity) (*fortress.Player, error) {
+	owner := h.SqliteHandler.LookupIdentityOwner(identity.Provider, identity.Subject)
+	if owner != "" && owner != playerId {
+		return nil, fmt.Errorf("this %s account is linked to another player, sign in with one that is not linked to anyone", identity.Provider)
+	}
+
+	player, _ := h.GetPlayer(PlayerFilter{playerId: playerId}, true)
+	if owner == "" {
+		h.SqliteHandler.LinkIdentity(&LinkedIdentity{provider: identity.Provider, subject: identity.Subject, playerId: playerId, linkedAt: time.Now().UTC()})
+		h.Logf("Recovered player %s(%s) by linking %s identity %s", player.GetName(), playerId, identity.Provider, identity.Subject)
+	}
+	return player, nil
+}
+
+// checkRecoveryCode checks that the recovery code is an unused one of the player with the given name (or id). It returns the
+// player's id and the code's hash, or "" if it is not. The code is not used up until the player has a session, see useRecoveryCode
+func (h *AuthHandler) checkRecoveryCode(playerName string, code string, ipAddress string) (string, string) {
+	hash := hashToken(normalizeBackupCode(code))
+	player := h.FindPlayer(playerName)
+	if player == nil || !h.SqliteHandler.HasUnusedRecoveryCode(player.GetPlayerId(), hash) {
+		h.Logf("Wrong recovery code for player %s from %s", playerName, ipAddress)
+		if player != nil {
+			h.recordLoginFailure(player.GetPlayerId(), ipAddress, "wrong recovery code")
+		} else {
+			h.recordLoginFailure("", ipAddress, "recovery code for unknown player "+playerName)
+		}
+		return "", ""
+	}
+	return player.GetPlayerId(), hash
+}
+
+// useRecoveryCode uses up the recovery code that the auth's login was started with, if it was for a recovery, once the player has
+// been given a session. Until then a refused login (such as for a ban) leaves the code unused. If another login used the code in
+// the meantime, the player's new session is ended again. This error gets passed back to the user/client
+func (h *AuthHandler) useRecoveryCode(player *fortress.Player, auth *Auth) error {
+	if auth.recoveryCode == "" || h.SqliteHandler.UseRecoveryCode(player.GetPlayerId(), auth.recoveryCode) {
+		return nil
+	}
+	_, claims := h.getTokenFromString(player.GetSessionToken())
+	h.sessions.Revoke(claims.SessionID, "the recovery code was used by another login")
+	h.Logf("Ended the session of player %s, its recovery code was used by another login", player.GetPlayerId())
+	return status.Error(codes.PermissionDenied, "this recovery code has already been used")
+}
+
+func (h *SqliteHandler) initializeRecoveryCodesTable() {
+	_, err := h.db.Exec("CREATE TABLE IF NOT EXISTS recovery_codes (" +
+		"player_id TEXT, " +
+		"code_hash TEXT, " +
+		"created_at INTEGER, " +
+		"used_at INTEGER)")
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+}
+
+func (h *SqliteHandler) ReplaceRecoveryCodes(playerId string, hashes []string) {
+	if _, err := h.db.Exec("DELETE FROM recovery_codes WHERE player_id = ?", playerId); err != nil {
+		h.Errorf("SQL: could not delete recovery codes of player %s: %s", playerId, err)
+		return
+	}
+	now := time.Now().UTC().Unix()
+	for _, hash := range hashes {
+		if _, err := h.db.Exec("INSERT INTO recovery_codes (player_id, code_hash, created_at, used_at) VALUES (?, ?, ?, 0)", playerId, hash, now); err != nil {
+			h.Errorf("SQL: could not save recovery code of player %s: %s", playerId, err)
+		}
+	}
+}
+
+// HasUnusedRecoveryCode returns whether the player has an unused recovery code with the given hash
+func (h *SqliteHandler) HasUnusedRecoveryCode(playerId string, hash string) bool {
+	var count int
+	if err := h.db.QueryRow("SELECT COUNT(*) FROM recovery_codes WHERE player_id = ? AND code_hash = ? AND used_at = 0", playerId, hash).Scan(&count); err != nil {
+		h.Errorf("SQL: could not look up recovery code of player %s: %s", playerId, err)
+		return false
+	}
+	return count > 0
+}
+
+// UseRecoveryCode marks the player's recovery code with the given hash as used, and returns whether there was an unused one
+func (h *SqliteHandler) UseRecoveryCode(playerId string, hash string) bool {
+	result, err := h.db.Exec("UPDATE recovery_codes SET used_at = ? WHERE player_id = ? AND code_hash = ? AND used_at = 0", time.Now().UTC().Unix(), playerId, hash)
+	if err != nil {
+		h.Errorf("SQL: could not use recovery code of player %s: %s", playerId, err)
+		return false
+	}
+	n, _ := result.RowsAffected()
+	return n > 0
+}
+
+// HasRecoveryCodes returns whether the player has ever been given recovery codes, used or not
+func (h *SqliteHandler) HasRecoveryCodes(playerId string) bool {
+	var count int
+	if err := h.db.QueryRow("SELECT COUNT(*) FROM recovery_codes WHERE player_id = ?", playerId).Scan(&count); err != nil {
+		h.Errorf("SQL: could not count recovery codes of player %s: %s", playerId, err)
+		return true // don't hand out new codes when we can't tell
+	}
+	return count > 0
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the player has
+func (h *SqliteHandler) CountRecoveryCodes(playerId string) int {
+	var count int
+	if err := h.db.QueryRow("SELECT COUNT(*) FROM recovery_codes WHERE player_id = ? AND used_at = 0", playerId).Scan(&count); err != nil {
+		h.Errorf("SQL: could not count recovery codes of player %s: %s", playerId, err)
+	}
+	return count
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestRecoveryCodeKeptOnRefusedLogin checks that a recovery code is only used up by a recovery that ends in a session
+func TestRecoveryCodeKeptOnRefusedLogin(t *testing.T) {
+	s := newTestServer(t, nil)
+	moderator, alice := s.newPlayer(t, "moderator"), s.newPlayer(t, "alice")
+	code := s.auth.newRecoveryCodes(alice.GetPlayerId())[0]
+	recoverAs := func(username string) error {
+		_, err := s.auth.Recover(context.Background(), &fgrpc.RecoveryRequest{PlayerName: "alice", Code: code, Provider: localProvider,
+			Username: username, Password: "correct horse battery"})
+		return err
+	}
+
+	if err := s.moderation.Ban(moderator, alice, 0, "spamming"); err != nil {
+		t.Fatal(err)
+	}
+	if err := recoverAs("alice2"); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("a banned player recovered their account with %v, want PermissionDenied", err)
+	}
+	if n := s.auth.CountRecoveryCodes(alice); n != recoveryCodeCount {
+		t.Errorf("the refused recovery used up a code, %d of %d are left", n, recoveryCodeCount)
+	}
+
+	if err := s.moderation.Unban(moderator, alice); err != nil {
+		t.Fatal(err)
+	}
+	if err := recoverAs("alice3"); err != nil {
+		t.Fatalf("the recovery failed after the ban was lifted: %v", err)
+	}
+	if n := s.auth.CountRecoveryCodes(alice); n != recoveryCodeCount-1 {
+		t.Errorf("%d of %d recovery codes are left after recovering, want one used", n, recoveryCodeCount)
+	}
+	if err := recoverAs("alice4"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("a used recovery code was accepted again with %v, want PermissionDenied", err)
+	}
+}
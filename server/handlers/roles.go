@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cheracc/fortress-grpc"
+	"github.com/cheracc/fortress-grpc/server/handlers/commands"
+)
+
+// the built-in roles. Every player has RolePlayer (or RoleGuest until they link a real identity), the others are granted by admins
+const (
+	RoleGuest     = "guest"
+	RolePlayer    = "player"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// the permissions that registered commands can require. A role that has AllPermissions can do anything
+const (
+	AllPermissions           = "*"
+	PermissionListPlayers    = "players.list"
+	PermissionChat           = "chat.send"
+	PermissionRename         = "players.rename"
+	PermissionChangePassword = "account.password"
+	PermissionManageSessions = "account.sessions"
+	PermissionSecondFactor   = "account.2fa"
+	PermissionIdentities     = "account.identities"
+	PermissionResetPassword  = "accounts.resetpassword"
+	PermissionManageRoles    = "roles.manage"
+	PermissionKick           = "moderation.kick"
+	PermissionBan            = "moderation.ban"
+	PermissionMute           = "moderation.mute"
+	PermissionViewSanctions  = "moderation.sanctions"
+	PermissionManageBots     = "bots.manage"
+	PermissionManageInvites  = "server.invites"
+	PermissionManageTerms    = "server.terms"
+	PermissionViewAudit      = "server.audit"
+	PermissionRotateKey      = "server.rotatekey"
+	PermissionStopServer     = "server.stop"
+)
+
+// builtinRoles maps the built-in roles to their permissions. They can't be changed or deleted
+var builtinRoles = map[string][]string{
+	RoleGuest:  {PermissionListPlayers, PermissionIdentities},
+	RolePlayer: {PermissionListPlayers, PermissionChat, PermissionRename, PermissionChangePassword, PermissionManageSessions, PermissionSecondFactor, PermissionIdentities},
+	RoleModerator: {PermissionListPlayers, PermissionChat, PermissionRename, PermissionChangePassword, PermissionManageSessions, PermissionSecondFactor, PermissionIdentities,
+		PermissionKick, PermissionBan, PermissionMute, PermissionViewSanctions, PermissionManageBots},
+	RoleAdmin: {AllPermissions},
+}
+
+var validRoleName = regexp.MustCompile(`^[a-z0-9_-]{2,24}$`)
+
+// A RoleHandler keeps track of which roles each player has and what those roles are allowed to do.
+// Roles are stored in the database so its methods are safe to use from any goroutine
+type RoleHandler struct {
+	*SqliteHandler
+	*fortress.Logger
+	// players that are always admins, as player ids or provider:subject identities (such as local:alice)
+	bootstrapAdmins []string
+}
+
+// NewRoleHandler constructs a new RoleHandler. The players listed in config.Admins are always admins
+func NewRoleHandler(sqliteHandler *SqliteHandler, config *Config, logger *fortress.Logger) *RoleHandler {
+	return &RoleHandler{sqliteHandler, logger, config.Admins}
+}
+
+// GetRoles returns the names of every role the player has, sorted. Guests only have RoleGuest
+func (h *RoleHandler) GetRoles(player *fortress.Player) []string {
+	if player.IsGuest() {
+		return []string{RoleGuest}
+	}
+	roles := append([]string{RolePlayer}, h.SqliteHandler.LookupPlayerRoles(player.GetPlayerId())...)
+	if h.isBootstrapAdmin(player) {
+		roles = append(roles, RoleAdmin)
+	}
+	slices.Sort(roles)
+	return slices.Compact(roles)
+}
+
+// HasPermission returns whether any of the player's roles grants the permission
+func (h *RoleHandler) HasPermission(player *fortress.Player, permission string) bool {
+	return h.rolesGrant(h.GetRoles(player), permission)
+}
+
+// usableRoles returns the roles the player can use right now. Roles that grant every permission can only be used by players
+// that have two-factor authentication on
+func (h *RoleHandler) usableRoles(player *fortress.Player) []string {
+	roles := h.GetRoles(player)
+	enrolment := h.SqliteHandler.LookupSecondFactor(player.GetPlayerId())
+	if enrolment != nil && enrolment.enabled {
+		return roles
+	}
+	return slices.DeleteFunc(roles, func(role string) bool {
+		return slices.Contains(h.getRolePermissions(role), AllPermissions)
+	})
+}
+
+// MissingSecondFactor returns whether the player has a role they can't use until they turn on two-factor authentication
+func (h *RoleHandler) MissingSecondFactor(player *fortress.Player) bool {
+	return len(h.usableRoles(player)) != len(h.GetRoles(player))
+}
+
+// rolesGrant returns whether any of the roles grants the permission
+func (h *RoleHandler) rolesGrant(roles []string, permission string) bool {
+	for _, role := range roles {
+		permissions := h.getRolePermissions(role)
+		if slices.Contains(permissions, AllPermissions) || slices.Contains(permissions, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// GrantRole gives the role to the player. The granting player must already have every permission the role grants.
+// This error gets passed back to the user/client
+func (h *RoleHandler) GrantRole(granter *fortress.Player, player *fortress.Player, role string) error {
+	role = strings.ToLower(role)
+	if role == RolePlayer {
+		return fmt.Errorf("every player already has the %s role", RolePlayer)
+	}
+	if role == RoleGuest {
+		return fmt.Errorf("the %s role can not be granted, only guests have it", RoleGuest)
+	}
+	if player.IsGuest() {
+		return fmt.Errorf("%s is a guest, they must link an identity before they can be given roles", player.GetName())
+	}
+	if !h.roleExists(role) {
+		return fmt.Errorf("there is no role named %s", role)
+	}
+	if err := h.checkHasRolePermissions(granter, role, "grant"); err != nil {
+		return err
+	}
+	if slices.Contains(h.GetRoles(player), role) {
+		return fmt.Errorf("%s already has the %s role", player.GetName(), role)
+	}
+
+	h.SqliteHandler.AddPlayerRole(player.GetPlayerId(), role, granter.GetPlayerId())
+	h.Logf("%s(%s) granted the %s role to %s(%s)", granter.GetName(), granter.GetPlayerId(), role, player.GetName(), player.GetPlayerId())
+	h.SqliteHandler.RecordSecurityEvent(&SecurityEvent{kind: EventRoleGrant, playerId: player.GetPlayerId(), actorId: granter.GetPlayerId(), detail: role})
+	return nil
+}
+
+// RevokeRole takes the role away from the player. This error gets passed back to the user/client
+func (h *RoleHandler) RevokeRole(revoker *fortress.Player, player *fortress.Player, role string) error {
+	role = strings.ToLower(role)
+	if role == RolePlayer || role == RoleGuest {
+		return fmt.Errorf("the %s role can not be revoked", role)
+	}
+	if role == RoleAdmin && h.isBootstrapAdmin(player) {
+		return fmt.Errorf("%s is an admin because of the server configuration (FORTRESS_ADMINS)", player.GetName())
+	}
+	if err := h.checkHasRolePermissions(revoker, role, "revoke"); err != nil {
+		return err
+	}
+	if !h.SqliteHandler.RemovePlayerRole(player.GetPlayerId(), role) {
+		return fmt.Errorf("%s does not have the %s role", player.GetName(), role)
+	}
+
+	h.Logf("%s(%s) revoked the %s role from %s(%s)", revoker.GetName(), revoker.GetPlayerId(), role, player.GetName(), player.GetPlayerId())
+	h.SqliteHandler.RecordSecurityEvent(&SecurityEvent{kind: EventRoleRevoke, playerId: player.GetPlayerId(), actorId: revoker.GetPlayerId(), detail: role})
+	return nil
+}
+
+// ListRoles describes every built-in and custom role for the roles command
+func (h *RoleHandler) ListRoles() []commands.RoleInfo {
+	roles := make([]commands.RoleInfo, 0)
+	for name, permissions := range builtinRoles {
+		roles = append(roles, commands.RoleInfo{Name: name, Permissions: permissions, Builtin: true})
+	}
+	for name, permissions := range h.SqliteHandler.LookupCustomRoles() {
+		roles = append(roles, commands.RoleInfo{Name: name, Permissions: permissions})
+	}
+	slices.SortFunc(roles, func(a, b commands.RoleInfo) int { return strings.Compare(a.Name, b.Name) })
+	return roles
+}
+
+// CreateRole adds a custom role with the given permissions. This error gets passed back to the user/client
+func (h *RoleHandler) CreateRole(creator *fortress.Player, role string, permissions []string) error {
+	role = strings.ToLower(role)
+	if !validRoleName.MatchString(role) {
+		return fmt.Errorf("role names must be 2 to 24 lowercase letters, numbers, dashes or underscores")
+	}
+	if h.roleExists(role) {
+		return fmt.Errorf("the role %s already exists", role)
+	}
+	for _, permission := range permissions {
+		if !h.HasPermission(creator, permission) {
+			return fmt.Errorf("you can not create a role with the %s permission because you do not have it", permission)
+		}
+	}
+
+	h.SqliteHandler.CreateCustomRole(role, permissions)
+	h.Logf("%s(%s) created the role %s with permissions %s", creator.GetName(), creator.GetPlayerId(), role, strings.Join(permissions, ","))
+	h.SqliteHandler.RecordSecurityEvent(&SecurityEvent{kind: EventRoleCreate, actorId: creator.GetPlayerId(), detail: role + " with permissions " + strings.Join(permissions, ",")})
+	return nil
+}
+
+// DeleteRole removes a custom role and takes it away from every player that had it. This error gets passed back to the user/client
+func (h *RoleHandler) DeleteRole(deleter *fortress.Player, role string) error {
+	role = strings.ToLower(role)
+	if _, builtin := builtinRoles[role]; builtin {
+		return fmt.Errorf("the %s role is built in and can not be deleted", role)
+	}
+	if !h.roleExists(role) {
+		return fmt.Errorf("there is no role named %s", role)
+	}
+	if err := h.checkHasRolePermissions(deleter, role, "delete"); err != nil {
+		return err
+	}
+
+	h.SqliteHandler.DeleteCustomRole(role)
+	h.Logf("%s(%s) deleted the role %s", deleter.GetName(), deleter.GetPlayerId(), role)
+	h.SqliteHandler.RecordSecurityEvent(&SecurityEvent{kind: EventRoleDelete, actorId: deleter.GetPlayerId(), detail: role})
+	return nil
+}
+
+// checkHasRolePermissions returns an error if the player does not have every permission of the role, so nobody can grant, revoke
+// or delete a role that can do more than they can. action names what they tried to do in the error
+func (h *RoleHandler) checkHasRolePermissions(player *fortress.Player, role string, action string) error {
+	for _, permission := range h.getRolePermissions(role) {
+		if !h.HasPermission(player, permission) {
+			return fmt.Errorf("you can not %s the %s role because you do not have all of its permissions", action, role)
+		}
+	}
+	return nil
+}
+
+// getRolePermissions returns the permissions of the built-in or custom role, or nil if there is no such role
+func (h *RoleHandler) getRolePermissions(role string) []string {
+	if permissions, builtin := builtinRoles[role]; builtin {
+		return permissions
+	}
+	return h.SqliteHandler.LookupCustomRoles()[role]
+}
+
+func (h *RoleHandler) roleExists(role string) bool {
+	return h.getRolePermissions(role) != nil
+}
+
+// isBootstrapAdmin returns whether the player is listed in config.Admins, by id or by identity
+func (h *RoleHandler) isBootstrapAdmin(player *fortress.Player) bool {
+	return h.SqliteHandler.isListedAdmin(h.bootstrapAdmins, player)
+}
+
+// isListedAdmin returns whether the player is in the list of admins, by id or by the provider:subject of any identity linked to them
+func (h *SqliteHandler) isListedAdmin(admins []string, player *fortress.Player) bool {
+	if len(admins) == 0 {
+		return false
+	}
+	if slices.Contains(admins, player.GetPlayerId()) || isListedIdentity(admins, player.GetProvider(), player.GetSubject()) {
+		return true
+	}
+	for _, identity := range h.LookupIdentities(player.GetPlayerId()) {
+		if isListedIdentity(admins, identity.provider, identity.subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// isListedIdentity returns whether the provider:subject identity is in the list of admins
+func isListedIdentity(admins []string, provider string, subject string) bool {
+	identity := provider + ":" + subject
+	for _, admin := range admins {
+		if strings.EqualFold(admin, identity) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *SqliteHandler) initializeRolesTables() {
+	_, err := h.db.Exec("CREATE TABLE IF NOT EXISTS roles (" +
+		"name TEXT PRIMARY KEY, " +
+		"permissions TEXT, " +
+		"created_at INTEGER)")
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+
+	_, err = h.db.Exec("CREATE TABLE IF NOT EXISTS player_roles (" +
+		"player_id TEXT, " +
+		"role TEXT, " +
+		"granted_by TEXT, " +
+		"granted_at INTEGER, " +
+		"PRIMARY KEY (player_id, role))")
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+}
+
+// LookupPlayerRoles returns the roles that have been granted to the player
+func (h *SqliteHandler) LookupPlayerRoles(playerId string) []string {
+	rows, err := h.db.Query("SELECT role FROM player_roles WHERE player_id = ?", playerId)
+	if err != nil {
+		h.Errorf("SQL: could not look up roles of player %s: %s", playerId, err)
+		return nil
+	}
+	defer rows.Close()
+
+	roles := make([]string, 0)
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			h.Errorf("SQL: %s", err)
+			return nil
+		}
+		roles = append(roles, role)
+	}
+	return roles
+}
+
+func (h *SqliteHandler) AddPlayerRole(playerId string, role string, grantedBy string) {
+	_, err := h.db.Exec("INSERT OR IGNORE INTO player_roles (player_id, role, granted_by, granted_at) VALUES (?, ?, ?, ?)",
+		playerId, role, grantedBy, time.Now().UTC().Unix())
+	if err != nil {
+		h.Errorf("SQL: could not grant role %s to player %s: %s", role, playerId, err)
+	}
+}
+
+// RemovePlayerRole takes the role away from the player and returns whether they had it
+func (h *SqliteHandler) RemovePlayerRole(playerId string, role string) bool {
+	res, err := h.db.Exec("DELETE FROM player_roles WHERE player_id = ? AND role = ?", playerId, role)
+	if err != nil {
+		h.Errorf("SQL: could not revoke role %s from player %s: %s", role, playerId, err)
+		return false
+	}
+	rows, _ := res.RowsAffected()
+	return rows > 0
+}
+
+// LookupCustomRoles returns the permissions of every custom role keyed by the role's name
+func (h *SqliteHandler) LookupCustomRoles() map[string][]string {
+	roles := make(map[string][]string)
+	rows, err := h.db.Query("SELECT name, permissions FROM roles")
+	if err != nil {
+		h.Errorf("SQL: could not look up roles: %s", err)
+		return roles
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var permissions sql.NullString
+		if err := rows.Scan(&name, &permissions); err != nil {
+			h.Errorf("SQL: %s", err)
+			return roles
+		}
+		roles[name] = make([]string, 0)
+		if permissions.String != "" {
+			roles[name] = strings.Split(permissions.String, ",")
+		}
+	}
+	return roles
+}
+
+func (h *SqliteHandler) CreateCustomRole(name string, permissions []string) {
+	_, err := h.db.Exec("INSERT INTO roles (name, permissions, created_at) VALUES (?, ?, ?)", name, strings.Join(permissions, ","), time.Now().UTC().Unix())
+	if err != nil {
+		h.Errorf("SQL: could not create role %s: %s", name, err)
+	}
+}
+
+// DeleteCustomRole deletes the role and removes it from every player
+func (h *SqliteHandler) DeleteCustomRole(name string) {
+	if _, err := h.db.Exec("DELETE FROM player_roles WHERE role = ?", name); err != nil {
+		h.Errorf("SQL: could not remove role %s from players: %s", name, err)
+	}
+	if _, err := h.db.Exec("DELETE FROM roles WHERE name = ?", name); err != nil {
+		h.Errorf("SQL: could not delete role %s: %s", name, err)
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import "testing"
+
+// TestRolePermissionChecks checks that a player can only grant, revoke or delete roles that can do no more than they can
+func TestRolePermissionChecks(t *testing.T) {
+	s := newTestServer(t, func(config *Config) {
+		config.Admins = []string{"local:root"}
+	})
+	root, moderator, alice := s.newPlayer(t, "root"), s.newPlayer(t, "moderator"), s.newPlayer(t, "alice")
+	if err := s.roles.GrantRole(root, moderator, RoleModerator); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.roles.CreateRole(root, "auditor", []string{PermissionViewAudit}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.roles.GrantRole(moderator, alice, "auditor"); err == nil {
+		t.Errorf("a moderator granted a role with a permission they do not have")
+	}
+	if err := s.roles.GrantRole(root, alice, "auditor"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.roles.RevokeRole(moderator, alice, "auditor"); err == nil {
+		t.Errorf("a moderator revoked a role with a permission they do not have")
+	}
+	if err := s.roles.DeleteRole(moderator, "auditor"); err == nil {
+		t.Errorf("a moderator deleted a role with a permission they do not have")
+	}
+	if !s.roles.HasPermission(alice, PermissionViewAudit) {
+		t.Fatalf("alice lost the auditor role to a refused revoke or delete")
+	}
+	if err := s.roles.DeleteRole(root, "auditor"); err != nil {
+		t.Errorf("an admin could not delete the role: %v", err)
+	}
+	if s.roles.HasPermission(alice, PermissionViewAudit) {
+		t.Errorf("alice kept the permissions of a deleted role")
+	}
+}
@@ -0,0 +1,417 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cheracc/fortress-grpc"
+	"github.com/google/uuid"
+)
+
+const sessionSweepInterval = 1 * time.Minute
+
+// A Session is one login of a player on one device. Access tokens carry the session's id, so revoking the session
+// invalidates them immediately. The session is kept alive by a refresh token that is replaced every time it is used
+type Session struct {
+	*sync.RWMutex
+	sessionId           string
+	playerId            string
+	device              string
+	ipAddress           string
+	refreshHash         string // the hash of the current refresh token
+	previousRefreshHash string // the hash of the refresh token that was replaced last, used to detect stolen tokens
+	issuedAt            time.Time
+	lastSeen            time.Time
+	expiresAt           time.Time
+	revokedAt           time.Time
+	revokeReason        string
+	apiKeyId            string // the api key a bot logged in with, "" for players
+}
+
+func (s *Session) GetSessionId() string {
+	return s.sessionId
+}
+
+func (s *Session) GetPlayerId() string {
+	return s.playerId
+}
+
+func (s *Session) GetDevice() string {
+	return s.device
+}
+
+func (s *Session) GetIpAddress() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.ipAddress
+}
+
+func (s *Session) GetIssuedAt() time.Time {
+	return s.issuedAt
+}
+
+func (s *Session) GetLastSeen() time.Time {
+	s.RLock()
+	defer s.RUnlock()
+	return s.lastSeen
+}
+
+// GetApiKeyId returns the id of the api key that started this session, or "" if it was not started with one
+func (s *Session) GetApiKeyId() string {
+	return s.apiKeyId
+}
+
+// GetRevokeReason returns why the session was revoked, or "" if it was not
+func (s *Session) GetRevokeReason() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.revokeReason
+}
+
+// isActive returns whether the session has not expired or been revoked
+func (s *Session) isActive() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.revokedAt.IsZero() && time.Now().Before(s.expiresAt)
+}
+
+// SessionRegistry keeps track of every player's sessions. Sessions are saved to the database and cached while they are in use.
+// The methods of SessionRegistry are thread-safe
+type SessionRegistry struct {
+	*sync.RWMutex
+	sessions  map[string]*Session
+	lifetime  time.Duration            // how long a refresh token is valid after it was issued
+	listeners []func(*Session, string) // called with the session and the reason whenever a session is revoked
+	*SqliteHandler
+	*fortress.Logger
+}
+
+// NewSessionRegistry constructs a SessionRegistry and starts the routine that drops finished sessions from the cache
+func NewSessionRegistry(sqliteHandler *SqliteHandler, lifetime time.Duration, logger *fortress.Logger) *SessionRegistry {
+	registry := &SessionRegistry{&sync.RWMutex{}, make(map[string]*Session), lifetime, nil, sqliteHandler, logger}
+
+	go func() {
+		for {
+			time.Sleep(sessionSweepInterval)
+			registry.sweep()
+		}
+	}()
+	return registry
+}
+
+// CreateSession starts a new session for the player and returns it along with its first refresh token. apiKeyId is the key
+// a bot logged in with, or "" for players
+func (r *SessionRegistry) CreateSession(playerId string, device string, ipAddress string, apiKeyId string) (*Session, string) {
+	now := time.Now().UTC()
+	refreshToken, refreshHash := generateRefreshToken()
+	session := &Session{
+		RWMutex:     &sync.RWMutex{},
+		sessionId:   uuid.NewString(),
+		playerId:    playerId,
+		device:      device,
+		ipAddress:   ipAddress,
+		refreshHash: refreshHash,
+		issuedAt:    now,
+		lastSeen:    now,
+		expiresAt:   now.Add(r.lifetime),
+		apiKeyId:    apiKeyId,
+	}
+
+	r.Lock()
+	r.sessions[session.sessionId] = session
+	r.Unlock()
+	r.SqliteHandler.CreateSessionDbRecord(session)
+
+	return session, session.sessionId + "." + refreshToken
+}
+
+// GetSession returns the session with the given id from the cache or the database, or nil if there is no such session
+func (r *SessionRegistry) GetSession(sessionId string) *Session {
+	r.RLock()
+	session := r.sessions[sessionId]
+	r.RUnlock()
+	if session != nil {
+		return session
+	}
+
+	session = r.SqliteHandler.LookupSessionFromDb(sessionId)
+	if session != nil && session.isActive() {
+		r.Lock()
+		r.sessions[sessionId] = session
+		r.Unlock()
+	}
+	return session
+}
+
+// GetActiveSessions returns every session of the player that has not ended, oldest first
+func (r *SessionRegistry) GetActiveSessions(playerId string) []*Session {
+	sessions := make([]*Session, 0)
+	for _, id := range r.SqliteHandler.LookupActiveSessionIds(playerId) {
+		if session := r.GetSession(id); session != nil && session.isActive() {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions
+}
+
+// OnRevoke registers a function that is called whenever a session is revoked, with the session and the reason
+func (r *SessionRegistry) OnRevoke(listener func(*Session, string)) {
+	r.Lock()
+	r.listeners = append(r.listeners, listener)
+	r.Unlock()
+}
+
+// IsActive returns whether the session exists and has not expired or been revoked
+func (r *SessionRegistry) IsActive(sessionId string) bool {
+	session := r.GetSession(sessionId)
+	return session != nil && session.isActive()
+}
+
+// Refresh checks the refresh token and replaces it with a new one. It returns the session and the new refresh token.
+// If a refresh token that was already replaced is used again, someone has a copy of it, so the session is revoked
+func (r *SessionRegistry) Refresh(refreshToken string, ipAddress string) (*Session, string, error) {
+	sessionId, secret, _ := strings.Cut(refreshToken, ".")
+	session := r.GetSession(sessionId)
+	if session == nil || !session.isActive() {
+		if session != nil && session.GetRevokeReason() != "" {
+			return nil, "", fmt.Errorf("the session has ended: %s", session.GetRevokeReason())
+		}
+		return nil, "", fmt.Errorf("the session has ended, please log in again")
+	}
+
+	hash := hashToken(secret)
+	session.Lock()
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(session.previousRefreshHash)) == 1 {
+		session.Unlock()
+		r.Revoke(sessionId, "a refresh token was used twice")
+		return nil, "", fmt.Errorf("the session has ended, please log in again")
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(session.refreshHash)) != 1 {
+		session.Unlock()
+		return nil, "", fmt.Errorf("invalid refresh token")
+	}
+
+	newToken, newHash := generateRefreshToken()
+	now := time.Now().UTC()
+	session.previousRefreshHash = session.refreshHash
+	session.refreshHash = newHash
+	session.ipAddress = ipAddress
+	session.lastSeen = now
+	session.expiresAt = now.Add(r.lifetime)
+	session.Unlock()
+	r.SqliteHandler.UpdateSessionDbRecord(session)
+
+	return session, sessionId + "." + newToken, nil
+}
+
+// Touch records that the session was just used from the given ip address
+func (r *SessionRegistry) Touch(sessionId string, ipAddress string) {
+	session := r.GetSession(sessionId)
+	if session == nil {
+		return
+	}
+	session.Lock()
+	session.lastSeen = time.Now().UTC()
+	session.ipAddress = ipAddress
+	session.Unlock()
+	r.SqliteHandler.UpdateSessionDbRecord(session)
+}
+
+// Revoke ends the session. Its access tokens are rejected from now on and its refresh token can not be used
+func (r *SessionRegistry) Revoke(sessionId string, reason string) {
+	session := r.GetSession(sessionId)
+	if session == nil {
+		return
+	}
+	session.Lock()
+	if !session.revokedAt.IsZero() {
+		session.Unlock()
+		return
+	}
+	session.revokedAt = time.Now().UTC()
+	session.revokeReason = reason
+	session.Unlock()
+	r.SqliteHandler.UpdateSessionDbRecord(session)
+
+	r.Logf("Revoked session %s of player %s: %s", sessionId, session.playerId, reason)
+
+	r.RLock()
+	listeners := r.listeners
+	r.RUnlock()
+	for _, listener := range listeners {
+		listener(session, reason)
+	}
+}
+
+// RevokeAll revokes every active session of the player except the session with the id keep (which may be "")
+func (r *SessionRegistry) RevokeAll(playerId string, keep string, reason string) int {
+	count := 0
+	for _, session := range r.GetActiveSessions(playerId) {
+		if session.GetSessionId() != keep {
+			r.Revoke(session.GetSessionId(), reason)
+			count++
+		}
+	}
+	return count
+}
+
+// sweep removes sessions that have ended from the cache. They stay in the database so they are still recognized as ended
+func (r *SessionRegistry) sweep() {
+	r.Lock()
+	for id, s := range r.sessions {
+		if !s.isActive() {
+			delete(r.sessions, id)
+		}
+	}
+	r.Unlock()
+}
+
+// generateRefreshToken returns a new random refresh token secret and its hash. It panics if the system's random source fails
+func generateRefreshToken() (string, string) {
+	token := base64.RawURLEncoding.EncodeToString(randomBytes(32))
+	return token, hashToken(token)
+}
+
+// hashToken returns the hex encoded sha256 hash of a token. Tokens are random and long so they do not need a slow hash
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (h *SqliteHandler) initializeSessionsTable() {
+	_, err := h.db.Exec("CREATE TABLE IF NOT EXISTS sessions (" +
+		"session_id TEXT PRIMARY KEY, " +
+		"player_id TEXT, " +
+		"device TEXT, " +
+		"ip_address TEXT, " +
+		"refresh_hash TEXT, " +
+		"previous_refresh_hash TEXT, " +
+		"issued_at INTEGER, " +
+		"last_seen INTEGER, " +
+		"expires_at INTEGER, " +
+		"revoked_at INTEGER, " +
+		"revoke_reason TEXT, " +
+		"api_key_id TEXT DEFAULT '')")
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+	h.addColumnIfMissing("sessions", "api_key_id", "TEXT DEFAULT ''")
+}
+
+func (h *SqliteHandler) CreateSessionDbRecord(s *Session) {
+	s.RLock()
+	defer s.RUnlock()
+	_, err := h.db.Exec("INSERT INTO sessions (session_id, player_id, device, ip_address, refresh_hash, previous_refresh_hash, issued_at, last_seen, expires_at, revoked_at, revoke_reason, api_key_id) "+
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		s.sessionId, s.playerId, s.device, s.ipAddress, s.refreshHash, s.previousRefreshHash, s.issuedAt.Unix(), s.lastSeen.Unix(), s.expiresAt.Unix(), unixOrZero(s.revokedAt), s.revokeReason, s.apiKeyId)
+	if err != nil {
+		h.Errorf("SQL: could not create session %s: %s", s.sessionId, err)
+	}
+}
+
+func (h *SqliteHandler) UpdateSessionDbRecord(s *Session) {
+	s.RLock()
+	defer s.RUnlock()
+	_, err := h.db.Exec("UPDATE sessions SET ip_address = ?, refresh_hash = ?, previous_refresh_hash = ?, last_seen = ?, expires_at = ?, revoked_at = ?, revoke_reason = ? WHERE session_id = ?",
+		s.ipAddress, s.refreshHash, s.previousRefreshHash, s.lastSeen.Unix(), s.expiresAt.Unix(), unixOrZero(s.revokedAt), s.revokeReason, s.sessionId)
+	if err != nil {
+		h.Errorf("SQL: could not update session %s: %s", s.sessionId, err)
+	}
+}
+
+// LookupSessionFromDb loads the session with the given id, it returns nil if there is no such session
+func (h *SqliteHandler) LookupSessionFromDb(sessionId string) *Session {
+	row := h.db.QueryRow("SELECT session_id, player_id, device, ip_address, refresh_hash, previous_refresh_hash, issued_at, last_seen, expires_at, revoked_at, revoke_reason, api_key_id "+
+		"FROM sessions WHERE session_id = ?", sessionId)
+
+	s, err := scanSession(row)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		h.Errorf("SQL: could not load session %s: %s", sessionId, err)
+		return nil
+	}
+	return s
+}
+
+// LookupActiveSessionIds returns the ids of the player's sessions that have not expired or been revoked, oldest first
+func (h *SqliteHandler) LookupActiveSessionIds(playerId string) []string {
+	rows, err := h.db.Query("SELECT session_id FROM sessions WHERE player_id = ? AND revoked_at = 0 AND expires_at > ? ORDER BY issued_at",
+		playerId, time.Now().UTC().Unix())
+	if err != nil {
+		h.Errorf("SQL: could not look up sessions of player %s: %s", playerId, err)
+		return nil
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			h.Errorf("SQL: %s", err)
+			return nil
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// LookupPlayersWithActiveSessions returns the ids of the players that have a session that has not expired or been revoked
+func (h *SqliteHandler) LookupPlayersWithActiveSessions() []string {
+	rows, err := h.db.Query("SELECT DISTINCT player_id FROM sessions WHERE revoked_at = 0 AND expires_at > ?", time.Now().UTC().Unix())
+	if err != nil {
+		h.Errorf("SQL: could not look up players with sessions: %s", err)
+		return nil
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			h.Errorf("SQL: %s", err)
+			return nil
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// scanSession reads a session from a row selected with every column of the sessions table, in order
+func scanSession(row interface{ Scan(...any) error }) (*Session, error) {
+	s := &Session{RWMutex: &sync.RWMutex{}}
+	var issued, seen, expires, revoked int64
+	err := row.Scan(&s.sessionId, &s.playerId, &s.device, &s.ipAddress, &s.refreshHash, &s.previousRefreshHash, &issued, &seen, &expires, &revoked, &s.revokeReason, &s.apiKeyId)
+	if err != nil {
+		return nil, err
+	}
+	s.issuedAt = time.Unix(issued, 0)
+	s.lastSeen = time.Unix(seen, 0)
+	s.expiresAt = time.Unix(expires, 0)
+	s.revokedAt = timeOrZero(revoked)
+	return s, nil
+}
+
+// unixOrZero returns the unix time of t, or 0 if t is the zero time
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// timeOrZero is the reverse of unixOrZero
+func timeOrZero(unix int64) time.Time {
+	if unix == 0 {
+		return time.Time{}
+	}
+	return time.Unix(unix, 0)
+}
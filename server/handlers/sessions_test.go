@@ -0,0 +1,74 @@
+package handlers
+
+import "testing"
+
+func TestRefreshTokenReuse(t *testing.T) {
+	s := newTestServer(t, nil)
+	sessions := s.auth.sessions
+	session, first := sessions.CreateSession("player", "test", "", "")
+
+	second, err := refreshTest(sessions, first)
+	if err != nil {
+		t.Fatalf("the first refresh failed: %v", err)
+	}
+	tests := []struct {
+		name   string
+		token  string
+		ok     bool
+		active bool // whether the session is still active afterwards
+	}{
+		{"unknown session", "unknown.secret", false, true},
+		{"wrong secret", session.GetSessionId() + ".wrong", false, true},
+		{"token that was replaced", first, false, false},
+		{"current token after a replaced one was used", second, false, false},
+	}
+	for _, test := range tests {
+		if _, err := refreshTest(sessions, test.token); (err == nil) != test.ok {
+			t.Errorf("%s: refresh error %v, want ok %v", test.name, err, test.ok)
+		}
+		if active := sessions.IsActive(session.GetSessionId()); active != test.active {
+			t.Errorf("%s: session active %v, want %v", test.name, active, test.active)
+		}
+	}
+}
+
+// refreshTest refreshes the session of the refresh token and returns the new refresh token
+func refreshTest(sessions *SessionRegistry, refreshToken string) (string, error) {
+	_, next, err := sessions.Refresh(refreshToken, "")
+	return next, err
+}
+
+func TestSessionPolicies(t *testing.T) {
+	tests := []struct {
+		policy      string
+		maxSessions int
+		logins      int
+		want        int
+	}{
+		{SessionPolicyMultiple, 0, 4, 4},
+		{SessionPolicyMultiple, 2, 4, 2},
+		{SessionPolicyMultiple, 5, 4, 4},
+		{SessionPolicyTakeover, 0, 4, 1},
+		{SessionPolicyTakeover, 5, 4, 1},
+		{"takover", 2, 4, 2}, // unknown policies fall back to SessionPolicyMultiple
+	}
+	for _, test := range tests {
+		s := newTestServer(t, func(config *Config) {
+			config.SessionPolicy = test.policy
+			config.MaxSessionsPerPlayer = test.maxSessions
+		})
+		player := s.newPlayer(t, "alice")
+		var last string
+		for range test.logins {
+			last = s.login(t, player)
+		}
+
+		active := s.auth.sessions.GetActiveSessions(player.GetPlayerId())
+		if len(active) != test.want {
+			t.Errorf("%s with at most %d: %d logins left %d sessions, want %d", test.policy, test.maxSessions, test.logins, len(active), test.want)
+		}
+		if _, err := refreshTest(s.auth.sessions, last); err != nil {
+			t.Errorf("%s with at most %d: the newest session was ended: %v", test.policy, test.maxSessions, err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"database/sql"
+	"strings"
 	"time"
 
 	"github.com/cheracc/fortress-grpc"
@@ -16,6 +17,8 @@ type SqliteHandler struct {
 type PlayerFilter struct {
 	playerId string
 	googleId string
+	provider string // provider and subject only match together
+	subject  string
 	name     string
 }
 
@@ -37,6 +40,8 @@ func (h *SqliteHandler) InitializeDatabase() {
 	// 	 	"'player_id'	TEXT"
 	// 		"'google_id'	TEXT"
 	//		"'name'			TEXT"
+	//		"'provider'		TEXT"
+	//		"'subject'		TEXT"
 	//		"'session_token' VARCHAR(255)"
 	//		"'avatar_url	VARCHAR(255)"
 	//		"'created_at'	"
@@ -46,6 +51,8 @@ func (h *SqliteHandler) InitializeDatabase() {
 	stmt, err := h.db.Prepare("CREATE TABLE IF NOT EXISTS players (" +
 		"player_id	TEXT, " +
 		"google_id	TEXT, " +
+		"provider TEXT DEFAULT '', " +
+		"subject TEXT DEFAULT '', " +
 		"name TEXT, " +
 		"session_token TEXT, " +
 		"avatar_url	TEXT, " +
@@ -58,32 +65,81 @@ func (h *SqliteHandler) InitializeDatabase() {
 	stmt.Exec()
 	defer stmt.Close()
 
+	// databases created before players could use other identity providers only have a google_id
+	if h.addColumnIfMissing("players", "provider", "TEXT DEFAULT ''") {
+		h.addColumnIfMissing("players", "subject", "TEXT DEFAULT ''")
+		if _, err := h.db.Exec("UPDATE players SET provider = 'google', subject = google_id WHERE google_id != ''"); err != nil {
+			h.Fatal(err.Error())
+		}
+		h.Log("migrated players table to store identity provider and subject")
+	}
+
+	h.initializeLocalAccountsTable()
+	h.initializeSessionsTable()
+	h.initializeRolesTables()
+	h.initializeSanctionsTable()
+	h.initializeBotsTables()
+	h.initializeSecondFactorTables()
+	h.initializeIdentitiesTable()
+	h.initializeInvitesTables()
+	h.initializeTermsTable()
+	h.initializeRecoveryCodesTable()
+	h.initializeSecurityEventsTable()
+	h.initializeChatMessagesTable()
+
 	h.Log("initialized database and table")
 }
 
+// addColumnIfMissing adds the column to the table if the table does not have it yet, and returns whether it was added
+func (h *SqliteHandler) addColumnIfMissing(table string, column string, definition string) bool {
+	rows, err := h.db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			h.Fatal(err.Error())
+		}
+		if name == column {
+			return false
+		}
+	}
+
+	if _, err := h.db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition); err != nil {
+		h.Fatal(err.Error())
+	}
+	return true
+}
+
 func (h *SqliteHandler) LookupPlayerFromDb(f PlayerFilter) *fortress.Player {
-	sql := "SELECT player_id, google_id, name, session_token, avatar_url, created_at, updated_at, last_read FROM players WHERE "
-	sqlWherePlayerId := "player_id like '%" + f.playerId + "%' "
-	sqlWhereGoogleId := "google_id like '%" + f.googleId + "%' "
-	sqlWhereName := "name like '%" + f.name + "%'"
+	sql := "SELECT player_id, google_id, provider, subject, name, session_token, avatar_url, created_at, updated_at, last_read FROM players WHERE "
+	wheres := make([]string, 0)
+	args := make([]any, 0)
 
 	if f.playerId != "" {
-		sql = sql + sqlWherePlayerId
+		wheres = append(wheres, "player_id like '%' || ? || '%'")
+		args = append(args, f.playerId)
 	}
 	if f.googleId != "" {
-		if f.playerId != "" {
-			sql = sql + " OR "
-		}
-		sql = sql + sqlWhereGoogleId
+		wheres = append(wheres, "google_id like '%' || ? || '%'")
+		args = append(args, f.googleId)
+	}
+	if f.provider != "" && f.subject != "" {
+		wheres = append(wheres, "(provider = ? AND subject = ?)")
+		args = append(args, f.provider, f.subject)
 	}
 	if f.name != "" {
-		if f.playerId != "" || f.googleId != "" {
-			sql = sql + " OR "
-		}
-		sql = sql + sqlWhereName
+		wheres = append(wheres, "name like '%' || ? || '%'")
+		args = append(args, f.name)
+	}
+	if len(wheres) == 0 {
+		return nil
 	}
 
-	rows, err := h.db.Query(sql)
+	rows, err := h.db.Query(sql+strings.Join(wheres, " OR "), args...)
 
 	if err != nil {
 		h.Fatal(err.Error())
@@ -96,14 +152,14 @@ func (h *SqliteHandler) LookupPlayerFromDb(f PlayerFilter) *fortress.Player {
 	players := make([]fortress.Player, 0)
 
 	for rows.Next() {
-		var playerId, googleId, name, sessionToken, avatarUrl string
+		var playerId, googleId, provider, subject, name, sessionToken, avatarUrl string
 		var created, updated, read int64
-		err = rows.Scan(&playerId, &googleId, &name, &sessionToken, &avatarUrl, &created, &updated, &read)
+		err = rows.Scan(&playerId, &googleId, &provider, &subject, &name, &sessionToken, &avatarUrl, &created, &updated, &read)
 		if err != nil {
 			h.Fatal(err.Error())
 		}
 
-		player := fortress.LoadPlayer(playerId, googleId, name, sessionToken, avatarUrl, time.Unix(created, 0), time.Unix(updated, 0), time.Unix(read, 0))
+		player := fortress.LoadPlayer(playerId, googleId, provider, subject, name, sessionToken, avatarUrl, time.Unix(created, 0), time.Unix(updated, 0), time.Unix(read, 0))
 
 		players = append(players, player)
 	}
@@ -113,11 +169,11 @@ func (h *SqliteHandler) LookupPlayerFromDb(f PlayerFilter) *fortress.Player {
 	}
 
 	if len(players) < 1 {
-		h.Logf("SQL: no players found for player filter playerid:%s googleid:%s name:%s", f.playerId, f.googleId, f.name)
+		h.Logf("SQL: no players found for player filter playerid:%s googleid:%s identity:%s/%s name:%s", f.playerId, f.googleId, f.provider, f.subject, f.name)
 		return nil
 	}
 	if len(players) > 1 {
-		h.Errorf("SQL: multiple players found for filter playerid:%s googleid:%s name:%s (these should all be unique??) only the first was returned", f.playerId, f.googleId, f.name)
+		h.Errorf("SQL: multiple players found for filter playerid:%s googleid:%s identity:%s/%s name:%s (these should all be unique??) only the first was returned", f.playerId, f.googleId, f.provider, f.subject, f.name)
 		return &players[0]
 	}
 
@@ -125,14 +181,14 @@ func (h *SqliteHandler) LookupPlayerFromDb(f PlayerFilter) *fortress.Player {
 }
 
 func (h *SqliteHandler) UpdatePlayerToDb(p *fortress.Player) {
-	// "UPDATE players set google_id = ?, name = ?, session_token = ?, avatar_url = ?, created_at = ?, updated_at = ?, last_read = ? WHERE player_id = ?"
-	stmt, err := h.db.Prepare("UPDATE players set google_id = ?, name = ?, session_token = ?, avatar_url = ?, created_at = ?, updated_at = ?, last_read = ? WHERE player_id = ?")
+	// "UPDATE players set google_id = ?, provider = ?, subject = ?, name = ?, session_token = ?, avatar_url = ?, created_at = ?, updated_at = ?, last_read = ? WHERE player_id = ?"
+	stmt, err := h.db.Prepare("UPDATE players set google_id = ?, provider = ?, subject = ?, name = ?, session_token = ?, avatar_url = ?, created_at = ?, updated_at = ?, last_read = ? WHERE player_id = ?")
 	if err != nil {
 		h.Fatal(err.Error())
 	}
 
 	var res sql.Result
-	res, err = stmt.Exec(p.GetGoogleId(), p.GetName(), p.GetSessionToken(), p.GetAvatarUrl(), p.CreatedAt.Unix(), p.UpdatedAt.Unix(), p.LastRead.Unix(), p.GetPlayerId())
+	res, err = stmt.Exec(p.GetGoogleId(), p.GetProvider(), p.GetSubject(), p.GetName(), p.GetSessionToken(), p.GetAvatarUrl(), p.CreatedAt.Unix(), p.UpdatedAt.Unix(), p.LastRead.Unix(), p.GetPlayerId())
 	if err != nil {
 		h.Fatal(err.Error())
 	}
@@ -148,17 +204,27 @@ func (h *SqliteHandler) UpdatePlayerToDb(p *fortress.Player) {
 }
 
 func (h *SqliteHandler) CreateNewPlayerDbRecord(p *fortress.Player) {
-	// "INSERT INTO players (player_id, google_id, name, session_token, avatar_url, created_at, updated_at, last_read) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
-	stmt, _ := h.db.Prepare("INSERT INTO players (player_id, google_id, name, session_token, avatar_url, created_at, updated_at, last_read) VALUES (?, ?, ?, ?, ?, ?, ?, ?)")
-	stmt.Exec(p.GetPlayerId(), p.GetGoogleId(), p.GetName(), p.GetSessionToken(), p.GetAvatarUrl(), p.CreatedAt.Unix(), p.UpdatedAt.Unix(), p.LastRead.Unix())
+	// "INSERT INTO players (player_id, google_id, provider, subject, name, session_token, avatar_url, created_at, updated_at, last_read) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	stmt, _ := h.db.Prepare("INSERT INTO players (player_id, google_id, provider, subject, name, session_token, avatar_url, created_at, updated_at, last_read) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	stmt.Exec(p.GetPlayerId(), p.GetGoogleId(), p.GetProvider(), p.GetSubject(), p.GetName(), p.GetSessionToken(), p.GetAvatarUrl(), p.CreatedAt.Unix(), p.UpdatedAt.Unix(), p.LastRead.Unix())
 	defer stmt.Close()
 
 	h.Logf("Added new database record for player %s(%s)", p.GetName(), p.GetPlayerId())
 }
 
-// TODO needs to be implemented - just check to see if it exists in the name column case-insensitive
+// LookupPlayerIdByName returns the id of the player with exactly this name (ignoring case), or "" if there is none
+func (h *SqliteHandler) LookupPlayerIdByName(name string) string {
+	var playerId string
+	err := h.db.QueryRow("SELECT player_id FROM players WHERE name = ? COLLATE NOCASE", name).Scan(&playerId)
+	if err != nil && err != sql.ErrNoRows {
+		h.Errorf("SQL: could not look up player named %s: %s", name, err)
+	}
+	return playerId
+}
+
+// IsNameUnique returns whether no player has the name yet (ignoring case)
 func (h *SqliteHandler) IsNameUnique(name string) bool {
-	return true
+	return h.LookupPlayerIdByName(name) == ""
 }
 
 func (h *SqliteHandler) CloseDb() {
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cheracc/fortress-grpc"
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A TermsDocument is one version of the terms of service
+type TermsDocument struct {
+	version int
+	text    string
+}
+
+// TermsOfService holds the versions of the terms of service that are in config.TermsDir, as files named after their version such as
+// 1.txt and 2.md. Players must accept the newest version before they can use commands or chat. If there are no files, there are no
+// terms to accept. The methods of TermsOfService are thread-safe
+type TermsOfService struct {
+	*sync.RWMutex
+	dir    string
+	latest *TermsDocument
+}
+
+// Load reads the terms from the directory again and returns the newest version, or 0 if there are none
+func (t *TermsOfService) Load() (int, error) {
+	entries, err := os.ReadDir(t.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		entries, err = nil, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var latest *TermsDocument
+	for _, entry := range entries {
+		version, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		if entry.IsDir() || err != nil || version < 1 || (latest != nil && version <= latest.version) {
+			continue
+		}
+		text, err := os.ReadFile(filepath.Join(t.dir, entry.Name()))
+		if err != nil {
+			return 0, err
+		}
+		latest = &TermsDocument{version, strings.TrimSpace(string(text))}
+	}
+
+	t.Lock()
+	t.latest = latest
+	t.Unlock()
+	if latest == nil {
+		return 0, nil
+	}
+	return latest.version, nil
+}
+
+// Latest returns the newest version of the terms, or nil if there are none
+func (t *TermsOfService) Latest() *TermsDocument {
+	t.RLock()
+	defer t.RUnlock()
+	return t.latest
+}
+
+// loadTerms loads the terms of service when the server starts
+func (h *AuthHandler) loadTerms() {
+	version, err := h.terms.Load()
+	if err != nil {
+		h.Fatalf("could not load the terms of service from %s: %s", h.config.TermsDir, err)
+	}
+	if version == 0 {
+		h.Logf("No terms of service found in %s, players do not have to accept any", h.config.TermsDir)
+		return
+	}
+	h.Logf("Loaded version %d of the terms of service", version)
+}
+
+// ReloadTerms reads the terms of service again, so that a new version can be published without restarting. Players have to accept
+// the new version before they can use commands or chat again. This error gets passed back to the user/client
+func (h *AuthHandler) ReloadTerms(player *fortress.Player) (int, error) {
+	version, err := h.terms.Load()
+	if err != nil {
+		h.Errorf("could not reload the terms of service from %s: %s", h.config.TermsDir, err)
+		return 0, fmt.Errorf("could not read the terms of service, see the server log")
+	}
+	h.Logf("%s(%s) reloaded the terms of service, the current version is %d", player.GetName(), player.GetPlayerId(), version)
+	return version, nil
+}
+
+// checkAcceptedTerms returns a FailedPrecondition error if the player has not accepted the newest terms of service. Bots act for
+// their owners, so they are not asked. This error gets passed back to the user/client
+func (h *AuthHandler) checkAcceptedTerms(player *fortress.Player) error {
+	latest := h.terms.Latest()
+	if latest == nil || player.IsBot() {
+		return nil
+	}
+	if accepted, _ := h.SqliteHandler.LookupAcceptedTerms(player.GetPlayerId()); accepted >= latest.version {
+		return nil
+	}
+	return status.Errorf(codes.FailedPrecondition, "you must accept the terms of service (version %d) first, use 'terms' to read them and 'accept' to accept them", latest.version)
+}
+
+// GetTerms is the gRPC receiving function that returns the newest terms of service and the version the player has accepted
+func (h *PlayerHandler) GetTerms(ctx context.Context, _ *fgrpc.Empty) (*fgrpc.TermsMessage, error) {
+	player := fortress.PlayerFromContext(ctx) // set by the auth interceptor once it has verified the session token
+	if player == nil {
+		return nil, h.Error("Server was unable to find the player for this session")
+	}
+	return h.termsMessage(player), nil
+}
+
+// AcceptTerms is the gRPC receiving function that records the player accepting the terms of service. The version must be the newest,
+// so that players can't accept terms they have not been shown
+func (h *PlayerHandler) AcceptTerms(ctx context.Context, request *fgrpc.AcceptTermsRequest) (*fgrpc.TermsMessage, error) {
+	player := fortress.PlayerFromContext(ctx) // set by the auth interceptor once it has verified the session token
+	if player == nil {
+		return nil, h.Error("Server was unable to find the player for this session")
+	}
+
+	latest := h.terms.Latest()
+	if latest == nil {
+		return nil, status.Error(codes.NotFound, "this server has no terms of service to accept")
+	}
+	if int(request.GetVersion()) != latest.version {
+		return nil, status.Errorf(codes.FailedPrecondition, "version %d of the terms of service is not the current one, use 'terms' to read version %d", request.GetVersion(), latest.version)
+	}
+
+	h.SqliteHandler.AcceptTerms(player.GetPlayerId(), latest.version)
+	h.Logf("Player %s(%s) accepted version %d of the terms of service", player.GetName(), player.GetPlayerId(), latest.version)
+	return h.termsMessage(player), nil
+}
+
+func (h *PlayerHandler) termsMessage(player *fortress.Player) *fgrpc.TermsMessage {
+	message := &fgrpc.TermsMessage{}
+	if latest := h.terms.Latest(); latest != nil {
+		message.Version = int32(latest.version)
+		message.Text = latest.text
+	}
+	accepted, acceptedAt := h.SqliteHandler.LookupAcceptedTerms(player.GetPlayerId())
+	message.AcceptedVersion = int32(accepted)
+	if accepted > 0 {
+		message.AcceptedAt = acceptedAt.Unix()
+	}
+	return message
+}
+
+func (h *SqliteHandler) initializeTermsTable() {
+	_, err := h.db.Exec("CREATE TABLE IF NOT EXISTS terms_acceptances (" +
+		"player_id TEXT, " +
+		"version INTEGER, " +
+		"accepted_at INTEGER, " +
+		"PRIMARY KEY (player_id, version))")
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+}
+
+func (h *SqliteHandler) AcceptTerms(playerId string, version int) {
+	_, err := h.db.Exec("INSERT OR IGNORE INTO terms_acceptances (player_id, version, accepted_at) VALUES (?, ?, ?)", playerId, version, time.Now().UTC().Unix())
+	if err != nil {
+		h.Errorf("SQL: could not record player %s accepting version %d of the terms: %s", playerId, version, err)
+	}
+}
+
+// LookupAcceptedTerms returns the newest version of the terms the player has accepted and when, or 0 if they never accepted any
+func (h *SqliteHandler) LookupAcceptedTerms(playerId string) (int, time.Time) {
+	var version int
+	var acceptedAt int64
+	err := h.db.QueryRow("SELECT version, accepted_at FROM terms_acceptances WHERE player_id = ? ORDER BY version DESC LIMIT 1", playerId).Scan(&version, &acceptedAt)
+	if err != nil && err != sql.ErrNoRows {
+		h.Errorf("SQL: could not look up the terms accepted by player %s: %s", playerId, err)
+	}
+	return version, time.Unix(acceptedAt, 0)
+}
@@ -0,0 +1,364 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cheracc/fortress-grpc"
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// totp codes as in RFC 6238, with the defaults every authenticator app supports
+	totpPeriod = 30
+	totpDigits = 6
+	totpSkew   = 1 // how many periods before and after now are also accepted, for clocks that are a little off
+	totpIssuer = "Fortress"
+
+	secondFactorLifetime    = 5 * time.Minute
+	maxSecondFactorAttempts = 5
+	backupCodeCount         = 10
+	backupCodeCharacters    = "abcdefghjkmnpqrstuvwxyz23456789"
+	backupCodeLength        = 10
+
+	// how many codes a player's account can be sent in a window before it stops taking them, however many logins they are spread over
+	maxAccountCodeAttempts   = 10
+	accountCodeAttemptWindow = 15 * time.Minute
+)
+
+// totpEncoding is the base32 encoding authenticator apps expect secrets in
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// errWrongCode is returned by checkSecondFactorCode for a code that is not right. This error gets passed back to the user/client
+var errWrongCode = errors.New("wrong code")
+
+// startSecondFactor holds back the login of a player that has two-factor authentication on. The auth is kept under a new
+// challenge until the client sends a code for it to VerifySecondFactor
+func (h *AuthHandler) startSecondFactor(auth *Auth, player *fortress.Player) {
+	auth.playerId = player.GetPlayerId()
+	auth.challenge = generateRandomState()
+	auth.expiresAt = time.Now().Add(secondFactorLifetime)
+	h.AddAuth(auth.challenge, auth)
+	h.Logf("Player %s(%s) signed in, waiting for their second factor", player.GetName(), player.GetPlayerId())
+}
+
+// VerifySecondFactor is the gRPC receiving function that finishes a login held back by startSecondFactor. It returns the
+// session once the code from the player's authenticator app (or one of their backup codes) checks out
+func (h *AuthHandler) VerifySecondFactor(ctx context.Context, request *fgrpc.SecondFactorRequest) (*fgrpc.AuthInfo, error) {
+	auth := h.GetAuth(request.GetChallenge())
+	if auth == nil || auth.challenge != request.GetChallenge() || auth.isExpired() {
+		return nil, status.Error(codes.Unauthenticated, "this login has expired, please log in again")
+	}
+
+	attempt, ok := h.claimCodeAttempt(auth)
+	if !ok {
+		h.DeleteAuth(auth.challenge)
+		return nil, status.Error(codes.Unauthenticated, "too many wrong codes, please log in again")
+	}
+	if err := h.checkSecondFactorCode(auth.playerId, request.GetCode()); err != nil {
+		if err != errWrongCode {
+			return nil, err
+		}
+		h.Logf("Wrong second factor code for player %s from %s (attempt %d)", auth.playerId, peerAddress(ctx), attempt)
+		h.recordLoginFailure(auth.playerId, peerAddress(ctx), "wrong second factor code")
+		if attempt >= maxSecondFactorAttempts {
+			h.DeleteAuth(auth.challenge)
+			return nil, status.Error(codes.Unauthenticated, "too many wrong codes, please log in again")
+		}
+		return nil, status.Error(codes.InvalidArgument, "wrong code, please try again")
+	}
+	h.DeleteAuth(auth.challenge)
+
+	player, _ := h.GetPlayer(PlayerFilter{playerId: auth.playerId}, true)
+	refreshToken, err := h.AuthorizePlayer(player, auth.device, auth.ipAddress, auth.inviteCode)
+	if err == nil {
+		err = h.useRecoveryCode(player, auth)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if auth.avatarUrl != "" {
+		player.SetAvatarUrl(auth.avatarUrl)
+	}
+	h.Logf("Player %s(%s) passed their second factor and logged in from %s", player.GetName(), player.GetPlayerId(), auth.ipAddress)
+	return &fgrpc.AuthInfo{PlayerID: player.GetPlayerId(), SessionToken: player.GetSessionToken(), RefreshToken: refreshToken,
+		RecoveryCodes: h.issueFirstRecoveryCodes(player)}, nil
+}
+
+// IsSecondFactorEnabled returns whether the player has to enter a code from their authenticator app to log in
+func (h *AuthHandler) IsSecondFactorEnabled(player *fortress.Player) bool {
+	enrolment := h.SqliteHandler.LookupSecondFactor(player.GetPlayerId())
+	return enrolment != nil && enrolment.enabled
+}
+
+// SecondFactorStatus returns whether the player has two-factor authentication on and how many unused backup codes they have left
+func (h *AuthHandler) SecondFactorStatus(player *fortress.Player) (bool, int) {
+	return h.IsSecondFactorEnabled(player), h.SqliteHandler.CountBackupCodes(player.GetPlayerId())
+}
+
+// SetupSecondFactor gives the player a new totp secret to add to their authenticator app. It does not take effect until the player
+// confirms it with EnableSecondFactor. It returns the otpauth uri and the secret. This error gets passed back to the user/client
+func (h *AuthHandler) SetupSecondFactor(player *fortress.Player) (string, string, error) {
+	if player.IsBot() {
+		return "", "", fmt.Errorf("bots log in with api keys and can not use two-factor authentication")
+	}
+	if h.IsSecondFactorEnabled(player) {
+		return "", "", fmt.Errorf("two-factor authentication is already on, turn it off first to use a new authenticator")
+	}
+
+	secret := totpEncoding.EncodeToString(randomBytes(20))
+	h.SqliteHandler.SaveSecondFactor(player.GetPlayerId(), &secondFactor{secret: secret})
+
+	label := url.PathEscape(totpIssuer + ":" + player.GetName())
+	uri := fmt.Sprintf("otpauth://totp/%s?secret=%s&issuer=%s&algorithm=SHA1&digits=%d&period=%d", label, secret, url.QueryEscape(totpIssuer), totpDigits, totpPeriod)
+	h.Logf("Player %s(%s) started setting up two-factor authentication", player.GetName(), player.GetPlayerId())
+	return uri, secret, nil
+}
+
+// EnableSecondFactor turns on two-factor authentication once the player proves their authenticator works by sending a code from it.
+// It returns the player's backup codes. This error gets passed back to the user/client
+func (h *AuthHandler) EnableSecondFactor(player *fortress.Player, code string) ([]string, error) {
+	enrolment := h.SqliteHandler.LookupSecondFactor(player.GetPlayerId())
+	if enrolment == nil {
+		return nil, fmt.Errorf("use '2fa setup' first")
+	}
+	if enrolment.enabled {
+		return nil, fmt.Errorf("two-factor authentication is already on")
+	}
+	if !h.verifyTotp(player.GetPlayerId(), enrolment, code) {
+		return nil, fmt.Errorf("wrong code, check that your device's clock is correct and try again")
+	}
+
+	enrolment.enabled = true
+	h.SqliteHandler.SaveSecondFactor(player.GetPlayerId(), enrolment)
+	h.Logf("Player %s(%s) turned on two-factor authentication", player.GetName(), player.GetPlayerId())
+	return h.newBackupCodes(player.GetPlayerId()), nil
+}
+
+// DisableSecondFactor turns off two-factor authentication after checking a current code or backup code.
+// This error gets passed back to the user/client
+func (h *AuthHandler) DisableSecondFactor(player *fortress.Player, code string) error {
+	if !h.IsSecondFactorEnabled(player) {
+		return fmt.Errorf("two-factor authentication is not on")
+	}
+	if err := h.checkSecondFactorCode(player.GetPlayerId(), code); err != nil {
+		return err
+	}
+
+	h.SqliteHandler.DeleteSecondFactor(player.GetPlayerId())
+	h.Logf("Player %s(%s) turned off two-factor authentication", player.GetName(), player.GetPlayerId())
+	return nil
+}
+
+// RegenerateBackupCodes replaces the player's backup codes after checking a current code or backup code.
+// This error gets passed back to the user/client
+func (h *AuthHandler) RegenerateBackupCodes(player *fortress.Player, code string) ([]string, error) {
+	if !h.IsSecondFactorEnabled(player) {
+		return nil, fmt.Errorf("two-factor authentication is not on")
+	}
+	if err := h.checkSecondFactorCode(player.GetPlayerId(), code); err != nil {
+		return nil, err
+	}
+
+	h.Logf("Player %s(%s) made new backup codes", player.GetName(), player.GetPlayerId())
+	return h.newBackupCodes(player.GetPlayerId()), nil
+}
+
+// checkSecondFactorCode checks a code from the player's authenticator app, or else uses up one of their backup codes. It returns
+// errWrongCode if the code is not right, or a ResourceExhausted error without checking it if the player has been sent too many codes
+// lately (see claimAccountCodeAttempt). This error gets passed back to the user/client
+func (h *AuthHandler) checkSecondFactorCode(playerId string, code string) error {
+	enrolment := h.SqliteHandler.LookupSecondFactor(playerId)
+	if enrolment == nil || !enrolment.enabled {
+		return errWrongCode
+	}
+	if !h.claimAccountCodeAttempt(playerId) {
+		h.Logf("Refused second factor code for player %s: too many wrong codes", playerId)
+		return status.Error(codes.ResourceExhausted, "too many wrong codes, please wait a few minutes and try again")
+	}
+	if h.verifyTotp(playerId, enrolment, code) || h.SqliteHandler.UseBackupCode(playerId, hashToken(normalizeBackupCode(code))) {
+		h.clearAccountCodeAttempts(playerId)
+		return nil
+	}
+	return errWrongCode
+}
+
+// verifyTotp checks a code against the secret. Each code can only be used once, so a code that was seen can't be replayed, not even
+// by two requests at once (see UseTotpStep)
+func (h *AuthHandler) verifyTotp(playerId string, enrolment *secondFactor, code string) bool {
+	secret, err := totpEncoding.DecodeString(enrolment.secret)
+	code = strings.TrimSpace(code)
+	if err != nil || len(code) != totpDigits {
+		return false
+	}
+
+	now := time.Now().Unix() / totpPeriod
+	for step := now - totpSkew; step <= now+totpSkew; step++ {
+		if step > enrolment.lastStep && subtle.ConstantTimeCompare([]byte(totpCode(secret, step)), []byte(code)) == 1 {
+			if !h.SqliteHandler.UseTotpStep(playerId, step) {
+				return false
+			}
+			enrolment.lastStep = step
+			return true
+		}
+	}
+	return false
+}
+
+// newBackupCodes replaces the player's backup codes with new ones and returns them. Only their hashes are stored
+func (h *AuthHandler) newBackupCodes(playerId string) []string {
+	codes, hashes := generateOneTimeCodes(backupCodeCount)
+	h.SqliteHandler.ReplaceBackupCodes(playerId, hashes)
+	return codes
+}
+
+// generateOneTimeCodes returns count random codes formatted like abcde-fghjk, and the hashes to store instead of them. Entered
+// codes are checked with hashToken(normalizeBackupCode(code))
+func generateOneTimeCodes(count int) ([]string, []string) {
+	codes := make([]string, count)
+	hashes := make([]string, count)
+	for i := range codes {
+		code := randomCode(backupCodeCharacters, backupCodeLength)
+		codes[i] = code[:backupCodeLength/2] + "-" + code[backupCodeLength/2:]
+		hashes[i] = hashToken(code)
+	}
+	return codes, hashes
+}
+
+// totpCode computes the code for a time step, using the dynamic truncation from RFC 4226
+func totpCode(secret []byte, step int64) string {
+	mac := hmac.New(sha1.New, secret)
+	binary.Write(mac, binary.BigEndian, step)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulus := uint32(1)
+	for range totpDigits {
+		modulus *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%modulus)
+}
+
+// normalizeBackupCode undoes the formatting of a backup code and anything else a user might type around it
+func normalizeBackupCode(entered string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(entered)))
+}
+
+// secondFactor is a player's totp secret. Until it is enabled the player is still setting it up
+type secondFactor struct {
+	secret   string
+	enabled  bool
+	lastStep int64 // the time step of the last code that was accepted
+}
+
+func (h *SqliteHandler) initializeSecondFactorTables() {
+	_, err := h.db.Exec("CREATE TABLE IF NOT EXISTS second_factors (" +
+		"player_id TEXT PRIMARY KEY, " +
+		"secret TEXT, " +
+		"enabled INTEGER, " +
+		"last_step INTEGER)")
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+
+	_, err = h.db.Exec("CREATE TABLE IF NOT EXISTS backup_codes (" +
+		"player_id TEXT, " +
+		"code_hash TEXT, " +
+		"used_at INTEGER)")
+	if err != nil {
+		h.Fatal(err.Error())
+	}
+}
+
+// LookupSecondFactor returns the player's totp secret, or nil if they have none
+func (h *SqliteHandler) LookupSecondFactor(playerId string) *secondFactor {
+	f := &secondFactor{}
+	err := h.db.QueryRow("SELECT secret, enabled, last_step FROM second_factors WHERE player_id = ?", playerId).Scan(&f.secret, &f.enabled, &f.lastStep)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		h.Errorf("SQL: could not load second factor of player %s: %s", playerId, err)
+		return nil
+	}
+	return f
+}
+
+func (h *SqliteHandler) SaveSecondFactor(playerId string, f *secondFactor) {
+	_, err := h.db.Exec("INSERT INTO second_factors (player_id, secret, enabled, last_step) VALUES (?, ?, ?, ?) "+
+		"ON CONFLICT(player_id) DO UPDATE SET secret = excluded.secret, enabled = excluded.enabled, last_step = excluded.last_step",
+		playerId, f.secret, f.enabled, f.lastStep)
+	if err != nil {
+		h.Errorf("SQL: could not save second factor of player %s: %s", playerId, err)
+	}
+}
+
+// UseTotpStep records that the player's code for the time step was accepted, and returns whether no code of that step or a later one
+// had been accepted yet. Checking and recording it in one statement stops the same code from being accepted twice at once
+func (h *SqliteHandler) UseTotpStep(playerId string, step int64) bool {
+	result, err := h.db.Exec("UPDATE second_factors SET last_step = ? WHERE player_id = ? AND last_step < ?", step, playerId, step)
+	if err != nil {
+		h.Errorf("SQL: could not use totp step of player %s: %s", playerId, err)
+		return false
+	}
+	n, _ := result.RowsAffected()
+	return n > 0
+}
+
+// DeleteSecondFactor removes the player's totp secret and backup codes
+func (h *SqliteHandler) DeleteSecondFactor(playerId string) {
+	if _, err := h.db.Exec("DELETE FROM second_factors WHERE player_id = ?", playerId); err != nil {
+		h.Errorf("SQL: could not delete second factor of player %s: %s", playerId, err)
+	}
+	h.ReplaceBackupCodes(playerId, nil)
+}
+
+func (h *SqliteHandler) ReplaceBackupCodes(playerId string, hashes []string) {
+	if _, err := h.db.Exec("DELETE FROM backup_codes WHERE player_id = ?", playerId); err != nil {
+		h.Errorf("SQL: could not delete backup codes of player %s: %s", playerId, err)
+		return
+	}
+	for _, hash := range hashes {
+		if _, err := h.db.Exec("INSERT INTO backup_codes (player_id, code_hash, used_at) VALUES (?, ?, 0)", playerId, hash); err != nil {
+			h.Errorf("SQL: could not save backup code of player %s: %s", playerId, err)
+		}
+	}
+}
+
+// UseBackupCode marks the player's backup code with the given hash as used, and returns whether there was an unused one
+func (h *SqliteHandler) UseBackupCode(playerId string, hash string) bool {
+	result, err := h.db.Exec("UPDATE backup_codes SET used_at = ? WHERE player_id = ? AND code_hash = ? AND used_at = 0", time.Now().UTC().Unix(), playerId, hash)
+	if err != nil {
+		h.Errorf("SQL: could not use backup code of player %s: %s", playerId, err)
+		return false
+	}
+	n, _ := result.RowsAffected()
+	return n > 0
+}
+
+// CountBackupCodes returns how many unused backup codes the player has
+func (h *SqliteHandler) CountBackupCodes(playerId string) int {
+	var count int
+	if err := h.db.QueryRow("SELECT COUNT(*) FROM backup_codes WHERE player_id = ? AND used_at = 0", playerId).Scan(&count); err != nil {
+		h.Errorf("SQL: could not count backup codes of player %s: %s", playerId, err)
+	}
+	return count
+}
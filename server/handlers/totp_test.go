@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	fgrpc "github.com/cheracc/fortress-grpc/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// enableTotp turns on two-factor authentication for the player with a fixed secret, and returns the secret
+func (s *testServer) enableTotp(t *testing.T, playerId string) []byte {
+	t.Helper()
+	secret := []byte("12345678901234567890")
+	s.auth.SqliteHandler.SaveSecondFactor(playerId, &secondFactor{secret: totpEncoding.EncodeToString(secret), enabled: true})
+	return secret
+}
+
+// TestTotpCode checks codes against the SHA1 test vectors of RFC 6238, which are 8 digits long. Ours are the last 6 of them
+func TestTotpCode(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	tests := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+	for unix, want := range tests {
+		if got := totpCode(secret, unix/totpPeriod); got != want[2:] {
+			t.Errorf("the code at %d is %s, want %s", unix, got, want[2:])
+		}
+	}
+}
+
+func TestTotpReplay(t *testing.T) {
+	s := newTestServer(t, nil)
+	player := s.newPlayer(t, "alice")
+	secret := s.enableTotp(t, player.GetPlayerId())
+	now := time.Now().Unix() / totpPeriod
+
+	tests := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{"code of the step before", totpCode(secret, now-1), true},
+		{"same code again", totpCode(secret, now-1), false},
+		{"code of the current step", totpCode(secret, now), true},
+		{"code of the step before once a later one was used", totpCode(secret, now-1), false},
+		{"code of the next step", totpCode(secret, now+1), true},
+		{"code two steps ahead", totpCode(secret, now+2), false},
+		{"code that is too short", totpCode(secret, now+2)[1:], false},
+		{"code with spaces around it", " " + totpCode(secret, now+2) + " ", false},
+	}
+	for _, test := range tests {
+		enrolment := s.auth.SqliteHandler.LookupSecondFactor(player.GetPlayerId())
+		if got := s.auth.verifyTotp(player.GetPlayerId(), enrolment, test.code); got != test.want {
+			t.Errorf("%s: accepted %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// TestTotpConcurrentReplay checks that a code sent by two requests at once, which both loaded the player's second factor before
+// either accepted the code, is only accepted once
+func TestTotpConcurrentReplay(t *testing.T) {
+	s := newTestServer(t, nil)
+	player := s.newPlayer(t, "alice")
+	code := totpCode(s.enableTotp(t, player.GetPlayerId()), time.Now().Unix()/totpPeriod)
+
+	first := s.auth.SqliteHandler.LookupSecondFactor(player.GetPlayerId())
+	second := s.auth.SqliteHandler.LookupSecondFactor(player.GetPlayerId())
+	if !s.auth.verifyTotp(player.GetPlayerId(), first, code) {
+		t.Fatalf("a current code was not accepted")
+	}
+	if s.auth.verifyTotp(player.GetPlayerId(), second, code) {
+		t.Errorf("the same code was accepted twice")
+	}
+}
+
+// TestSecondFactorAccountLimit checks that wrong codes count against the account across logins, so starting a new login does not
+// give more guesses
+func TestSecondFactorAccountLimit(t *testing.T) {
+	s := newTestServer(t, nil)
+	player := s.newPlayer(t, "alice")
+	secret := s.enableTotp(t, player.GetPlayerId())
+	verify := func(code string) error {
+		auth := &Auth{provider: localProvider, subject: "alice"}
+		s.auth.startSecondFactor(auth, player)
+		_, err := s.auth.VerifySecondFactor(context.Background(), &fgrpc.SecondFactorRequest{Challenge: auth.challenge, Code: code})
+		return err
+	}
+
+	for i := range maxAccountCodeAttempts {
+		if err := verify("000000"); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("wrong code %d returned %v, want InvalidArgument", i+1, err)
+		}
+	}
+	code := totpCode(secret, time.Now().Unix()/totpPeriod)
+	if err := verify(code); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("a code sent after %d wrong ones in new logins returned %v, want ResourceExhausted", maxAccountCodeAttempts, err)
+	}
+
+	s.auth.clearAccountCodeAttempts(player.GetPlayerId()) // as if the window had passed
+	if err := verify(code); err != nil {
+		t.Errorf("a right code was refused once the account could take codes again: %v", err)
+	}
+}
@@ -8,19 +8,55 @@ import (
 
 func main() {
 	logger := fortress.NewLogger()
-	grpcHandler := handlers.NewGrpcServer(logger)
+	config := handlers.LoadConfig()
 	sqlite := handlers.NewSqliteHandler(logger)
 	playerHandler := handlers.NewPlayerHandler(sqlite, logger)
-	auth := handlers.NewAuthHandler(playerHandler, logger)
+	auth := handlers.NewAuthHandler(playerHandler, config, logger)
 	playerHandler.SetAuthHandler(auth)
-	chat := handlers.NewChatHandler(logger, auth)
+	roles := handlers.NewRoleHandler(sqlite, config, logger)
+	moderation := handlers.NewModerationHandler(auth, roles, logger)
+	bots := handlers.NewBotHandler(auth, roles, logger)
+	invites := handlers.NewInviteHandler(auth, logger)
+	chat := handlers.NewChatHandler(logger, auth, roles)
+	grpcHandler := handlers.NewGrpcServer(auth, logger)
 
 	sqlite.InitializeDatabase()
+	auth.RevokeUnadmittedSessions()
 
-	commandHandler := handlers.NewCommandHandler(auth, playerHandler, logger)
-	commandHandler.RegisterCommand(&handlers.Command{Name: "stop", Exec: &commands.StopCommand{CloseDatabaseFunc: sqlite.CloseDb}})
-	commandHandler.RegisterCommand(&handlers.Command{Name: "name", Exec: &commands.NameCommand{RenamePlayerFunc: playerHandler.RenamePlayer}})
-	commandHandler.RegisterCommand(&handlers.Command{Name: "list", Exec: &commands.ListCommand{GetOnlinePlayersFunc: playerHandler.GetOnlinePlayers}})
+	commandHandler := handlers.NewCommandHandler(auth, playerHandler, roles, logger)
+	commandHandler.RegisterCommand(&handlers.Command{Name: "stop", Exec: &commands.StopCommand{CloseDatabaseFunc: sqlite.CloseDb}, Permission: handlers.PermissionStopServer})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "name", Exec: &commands.NameCommand{RenamePlayerFunc: playerHandler.RenamePlayer}, Permission: handlers.PermissionRename})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "list", Exec: &commands.ListCommand{GetOnlinePlayersFunc: playerHandler.GetOnlinePlayers}, Permission: handlers.PermissionListPlayers})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "passwd", Exec: &commands.PasswordCommand{ChangePasswordFunc: auth.ChangePassword}, HideArguments: true, Permission: handlers.PermissionChangePassword})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "resetpassword", Exec: &commands.ResetPasswordCommand{ResetPasswordFunc: auth.ResetPassword}, HideArguments: true, Permission: handlers.PermissionResetPassword})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "sessions", Exec: &commands.SessionsCommand{ListSessionsFunc: auth.ListSessions, RevokeSessionFunc: auth.RevokePlayerSession}, Permission: handlers.PermissionManageSessions})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "identities", Exec: &commands.IdentitiesCommand{ListIdentitiesFunc: auth.ListIdentities}, Permission: handlers.PermissionIdentities})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "link", Exec: &commands.LinkCommand{StartLinkFunc: auth.StartLink}, Permission: handlers.PermissionIdentities, RequiresRecentLogin: true})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "unlink", Exec: &commands.UnlinkCommand{UnlinkFunc: auth.Unlink}, Permission: handlers.PermissionIdentities, RequiresRecentLogin: true})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "recovery", Exec: &commands.RecoveryCommand{CountFunc: auth.CountRecoveryCodes, RegenerateFunc: auth.RegenerateRecoveryCodes},
+		Permission: handlers.PermissionIdentities, RequiresRecentLogin: true})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "2fa", Exec: &commands.SecondFactorCommand{StatusFunc: auth.SecondFactorStatus, SetupFunc: auth.SetupSecondFactor,
+		EnableFunc: auth.EnableSecondFactor, DisableFunc: auth.DisableSecondFactor, BackupCodesFunc: auth.RegenerateBackupCodes}, HideArguments: true, Permission: handlers.PermissionSecondFactor})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "reloadterms", Exec: &commands.ReloadTermsCommand{ReloadTermsFunc: auth.ReloadTerms}, Permission: handlers.PermissionManageTerms})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "audit", Exec: &commands.AuditCommand{ListEventsFunc: auth.ListSecurityEvents}, Permission: handlers.PermissionViewAudit})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "rotatekey", Exec: &commands.RotateKeyCommand{RotateKeyFunc: auth.RotateSigningKey}, Permission: handlers.PermissionRotateKey})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "grant", Exec: &commands.GrantCommand{FindPlayerFunc: playerHandler.FindPlayer, GrantRoleFunc: roles.GrantRole}, Permission: handlers.PermissionManageRoles})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "revoke", Exec: &commands.RevokeCommand{FindPlayerFunc: playerHandler.FindPlayer, RevokeRoleFunc: roles.RevokeRole}, Permission: handlers.PermissionManageRoles})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "roles", Exec: &commands.RolesCommand{ListRolesFunc: roles.ListRoles, GetRolesFunc: roles.GetRoles,
+		FindPlayerFunc: playerHandler.FindPlayer, CreateRoleFunc: roles.CreateRole, DeleteRoleFunc: roles.DeleteRole}, Permission: handlers.PermissionManageRoles})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "kick", Exec: &commands.KickCommand{FindPlayerFunc: playerHandler.FindPlayer, KickFunc: moderation.Kick}, Permission: handlers.PermissionKick})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "ban", Exec: &commands.SanctionCommand{Name: "ban", FindPlayerFunc: playerHandler.FindPlayer, SanctionFunc: moderation.Ban}, Permission: handlers.PermissionBan})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "unban", Exec: &commands.LiftSanctionCommand{Name: "unban", FindPlayerFunc: playerHandler.FindPlayer, LiftFunc: moderation.Unban}, Permission: handlers.PermissionBan})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "mute", Exec: &commands.SanctionCommand{Name: "mute", FindPlayerFunc: playerHandler.FindPlayer, SanctionFunc: moderation.Mute}, Permission: handlers.PermissionMute})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "unmute", Exec: &commands.LiftSanctionCommand{Name: "unmute", FindPlayerFunc: playerHandler.FindPlayer, LiftFunc: moderation.Unmute}, Permission: handlers.PermissionMute})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "sanctions", Exec: &commands.SanctionsCommand{FindPlayerFunc: playerHandler.FindPlayer, ListSanctionsFunc: moderation.ListSanctions,
+		ListActiveSanctionsFunc: moderation.ListActiveSanctions}, Permission: handlers.PermissionViewSanctions})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "bot", Exec: &commands.BotCommand{CreateBotFunc: bots.CreateBot, DeleteBotFunc: bots.DeleteBot, ListBotsFunc: bots.ListBots,
+		CreateKeyFunc: bots.CreateApiKey, ListKeysFunc: bots.ListApiKeys, RevokeKeyFunc: bots.RevokeApiKey}, Permission: handlers.PermissionManageBots})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "invite", Exec: &commands.InviteCommand{CreateInviteFunc: invites.CreateInvite, RevokeInviteFunc: invites.RevokeInvite,
+		ListInvitesFunc: invites.ListInvites, ListRedemptionsFunc: invites.ListRedemptions}, Permission: handlers.PermissionManageInvites})
+	commandHandler.RegisterCommand(&handlers.Command{Name: "whitelist", Exec: &commands.WhitelistCommand{FindPlayerFunc: playerHandler.FindPlayer, ListWhitelistFunc: invites.ListWhitelist,
+		AddFunc: invites.AddToWhitelist, RemoveFunc: invites.RemoveFromWhitelist}, Permission: handlers.PermissionManageInvites})
 
 	defer sqlite.CloseDb()
 
@@ -0,0 +1,67 @@
+package verify
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+// the size in bytes of a P-256 coordinate
+const p256CoordinateSize = 32
+
+// A JWKS is a JSON Web Key Set (RFC 7517), the document the fortress server publishes its verification keys in
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// A JWK is one public key in a JWKS. Fortress only signs with ES256, so only P-256 elliptic curve keys are used
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+// NewJWK describes the public key as a JWK with the given key id
+func NewJWK(kid string, key *ecdsa.PublicKey) JWK {
+	return JWK{
+		KeyType:   "EC",
+		Curve:     "P-256",
+		X:         base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, p256CoordinateSize))),
+		Y:         base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, p256CoordinateSize))),
+		KeyID:     kid,
+		Algorithm: "ES256",
+		Use:       "sig",
+	}
+}
+
+// PublicKey returns the key the JWK describes. It returns an error if the JWK is not a P-256 signing key or its point is not on the curve
+func (k JWK) PublicKey() (*ecdsa.PublicKey, error) {
+	if k.KeyType != "EC" || k.Curve != "P-256" {
+		return nil, fmt.Errorf("key %s is a %s %s key, not an EC P-256 key", k.KeyID, k.KeyType, k.Curve)
+	}
+	if k.Use != "" && k.Use != "sig" {
+		return nil, fmt.Errorf("key %s is not a signing key", k.KeyID)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil || len(x) != p256CoordinateSize {
+		return nil, fmt.Errorf("key %s has an invalid x coordinate", k.KeyID)
+	}
+	y, err := base64.RawURLEncoding.DecodeString(k.Y)
+	if err != nil || len(y) != p256CoordinateSize {
+		return nil, fmt.Errorf("key %s has an invalid y coordinate", k.KeyID)
+	}
+
+	// crypto/ecdh checks that the point is on the curve, so a bad key can't be used for an invalid curve attack
+	point := append(append([]byte{4}, x...), y...)
+	if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+		return nil, fmt.Errorf("key %s is not a valid P-256 key: %w", k.KeyID, err)
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+}
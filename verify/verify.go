@@ -0,0 +1,204 @@
+// Package verify lets other services check fortress session tokens. It fetches the server's verification keys from its JWKS
+// endpoint and caches them, so most tokens are verified without a request to the server:
+//
+//	verifier := verify.NewVerifier("https://fortress.example.com/.well-known/jwks.json", "https://fortress.example.com", "fortress")
+//	claims, err := verifier.Verify(ctx, token)
+//	if err != nil {
+//		// reject the request
+//	}
+//	playerId := claims.PlayerID
+//
+// A Verifier only checks the token's signature and claims. It can't know whether the player has since logged out or been
+// banned, so session tokens are kept short lived (a few minutes) by the server
+package verify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// how long fetched keys are used before they are fetched again
+	DefaultCacheTTL = 10 * time.Minute
+	// the least time between two fetches, so that tokens with made up key ids can't be used to flood the server
+	DefaultMinRefreshInterval = 30 * time.Second
+	// the largest JWKS document that will be read
+	maxJWKSSize = 1 << 20
+)
+
+// ErrUnknownKey is returned (wrapped) when a token is signed by a key that is not in the JWKS, even after fetching it again
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Claims are the claims on a fortress session token
+type Claims struct {
+	PlayerID  string `json:"player-id"`
+	SessionID string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// A Verifier verifies fortress session tokens against the keys in a JWKS. The fields may be changed before the Verifier is first used.
+// The methods of Verifier are thread-safe
+type Verifier struct {
+	// the url of the server's JWKS, usually its public url followed by /.well-known/jwks.json
+	JWKSURL string
+	// the issuer and audience that tokens must have, as configured on the server
+	Issuer   string
+	Audience string
+	// the client used to fetch the JWKS
+	HTTPClient *http.Client
+	// how long fetched keys are used before they are fetched again
+	CacheTTL time.Duration
+	// the least time between two fetches of the JWKS
+	MinRefreshInterval time.Duration
+
+	mu          sync.Mutex
+	keys        map[string]*ecdsa.PublicKey
+	fetchedAt   time.Time     // when the keys were last fetched successfully
+	attemptedAt time.Time     // when the keys were last fetched, successful or not
+	fetching    chan struct{} // closed when the fetch in progress is done, nil if there is none
+}
+
+// NewVerifier returns a Verifier for tokens issued by issuer for audience, using the keys published at jwksURL
+func NewVerifier(jwksURL string, issuer string, audience string) *Verifier {
+	return &Verifier{
+		JWKSURL:            jwksURL,
+		Issuer:             issuer,
+		Audience:           audience,
+		HTTPClient:         &http.Client{Timeout: 10 * time.Second},
+		CacheTTL:           DefaultCacheTTL,
+		MinRefreshInterval: DefaultMinRefreshInterval,
+	}
+}
+
+// Verify checks the token's signature, expiry, issuer and audience, and returns its claims
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	claims := &Claims{}
+	keyFunc := func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.key(ctx, kid)
+	}
+
+	_, err := jwt.ParseWithClaims(token, claims, keyFunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg()}),
+		jwt.WithIssuer(v.Issuer),
+		jwt.WithAudience(v.Audience),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if claims.PlayerID == "" || claims.SessionID == "" {
+		return nil, fmt.Errorf("token is not a fortress session token")
+	}
+	return claims, nil
+}
+
+// key returns the public key with the given id. The JWKS is fetched again if the cached keys are too old, or if the key is not
+// among them (the server may have rotated its keys), but no more often than MinRefreshInterval. Only one fetch runs at a time, and
+// the lock is not held during it. Callers that already have a key keep using it meanwhile, the others wait for the fetch
+func (v *Verifier) key(ctx context.Context, kid string) (*ecdsa.PublicKey, error) {
+	v.mu.Lock()
+	key, ok := v.keys[kid]
+	if ok && time.Since(v.fetchedAt) <= v.CacheTTL {
+		v.mu.Unlock()
+		return key, nil
+	}
+	fetching := v.fetching
+	if fetching == nil && time.Since(v.attemptedAt) >= v.MinRefreshInterval {
+		return v.refresh(ctx, kid, key) // unlocks
+	}
+	v.mu.Unlock()
+
+	if ok { // stale, but the keys were just fetched or are being fetched
+		return key, nil
+	}
+	if fetching != nil {
+		select {
+		case <-fetching:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		v.mu.Lock()
+		key, ok = v.keys[kid]
+		v.mu.Unlock()
+		if ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+}
+
+// refresh fetches the JWKS and replaces the cached keys, then returns the key with the given id. cached is the key the Verifier
+// had for it, which is returned if the fetch fails. It must be called with the lock held, and unlocks it while fetching
+func (v *Verifier) refresh(ctx context.Context, kid string, cached *ecdsa.PublicKey) (*ecdsa.PublicKey, error) {
+	startedAt := time.Now()
+	v.attemptedAt = startedAt
+	done := make(chan struct{})
+	v.fetching = done
+	v.mu.Unlock()
+
+	keys, err := v.fetch(ctx)
+
+	v.mu.Lock()
+	if err == nil {
+		v.keys = keys
+		v.fetchedAt = startedAt
+	}
+	v.fetching = nil
+	close(done)
+	key, ok := v.keys[kid]
+	v.mu.Unlock()
+
+	if err != nil {
+		if cached != nil { // the server could not be reached, but the key we have is still the best we know
+			return cached, nil
+		}
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+	}
+	return key, nil
+}
+
+// fetch downloads the JWKS and returns its keys by id. Keys that can't be used are skipped
+func (v *Verifier) fetch(ctx context.Context) (map[string]*ecdsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch keys from %s: %w", v.JWKSURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch keys from %s: %s", v.JWKSURL, resp.Status)
+	}
+
+	var jwks JWKS
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSSize)).Decode(&jwks); err != nil {
+		return nil, fmt.Errorf("could not read keys from %s: %w", v.JWKSURL, err)
+	}
+
+	keys := make(map[string]*ecdsa.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Algorithm != "" && jwk.Algorithm != jwt.SigningMethodES256.Alg() {
+			continue
+		}
+		if key, err := jwk.PublicKey(); err == nil {
+			keys[jwk.KeyID] = key
+		}
+	}
+	return keys, nil
+}
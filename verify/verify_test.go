@@ -0,0 +1,137 @@
+package verify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://fortress.example.com"
+	testAudience = "fortress"
+)
+
+// jwksServer serves a JWKS with one key, and counts how many times it was fetched. While block is not nil, requests wait on it
+type jwksServer struct {
+	*httptest.Server
+	key     *ecdsa.PrivateKey
+	kid     string
+	fetches atomic.Int32
+	block   chan struct{}
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &jwksServer{key: key, kid: "test-key"}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		if s.block != nil {
+			<-s.block
+		}
+		json.NewEncoder(w).Encode(JWKS{Keys: []JWK{NewJWK(s.kid, &s.key.PublicKey)}})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) verifier() *Verifier {
+	return NewVerifier(s.URL+"/.well-known/jwks.json", testIssuer, testAudience)
+}
+
+// sign returns a session token signed with the server's key, like the fortress server makes them
+func (s *jwksServer) sign(t *testing.T, kid string, audience string) string {
+	now := time.Now()
+	claims := &Claims{
+		PlayerID:  "player",
+		SessionID: "session",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(s.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestVerify(t *testing.T) {
+	s := newJWKSServer(t)
+	v := s.verifier()
+	ctx := context.Background()
+
+	claims, err := v.Verify(ctx, s.sign(t, s.kid, testAudience))
+	if err != nil {
+		t.Fatalf("a valid token was rejected: %v", err)
+	}
+	if claims.PlayerID != "player" || claims.SessionID != "session" {
+		t.Errorf("got player %q session %q", claims.PlayerID, claims.SessionID)
+	}
+	if _, err := v.Verify(ctx, s.sign(t, s.kid, testAudience)); err != nil {
+		t.Fatalf("a second valid token was rejected: %v", err)
+	}
+	if n := s.fetches.Load(); n != 1 {
+		t.Errorf("the JWKS was fetched %d times, the cached keys should have been used", n)
+	}
+
+	if _, err := v.Verify(ctx, s.sign(t, s.kid, "someone-else")); err == nil {
+		t.Errorf("a token for another audience was accepted")
+	}
+	if _, err := v.Verify(ctx, s.sign(t, "unknown-key", testAudience)); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("a token signed with an unknown key id returned %v, not ErrUnknownKey", err)
+	}
+}
+
+// TestVerifyDuringFetch checks that a slow JWKS fetch does not hold up tokens signed with a key the Verifier already has
+func TestVerifyDuringFetch(t *testing.T) {
+	s := newJWKSServer(t)
+	v := s.verifier()
+	ctx := context.Background()
+	token := s.sign(t, s.kid, testAudience)
+	if _, err := v.Verify(ctx, token); err != nil {
+		t.Fatalf("a valid token was rejected: %v", err)
+	}
+
+	// make the cached key stale, and the next fetch hang until the test is done
+	v.CacheTTL = 0
+	v.MinRefreshInterval = 0
+	s.block = make(chan struct{})
+	defer close(s.block)
+
+	go v.Verify(ctx, token) // starts the fetch
+	for s.fetches.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := v.Verify(ctx, token)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("a valid token was rejected while the keys were being fetched: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Verify waited for the JWKS fetch of another call")
+	}
+}